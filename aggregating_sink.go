@@ -0,0 +1,205 @@
+package health
+
+import (
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+)
+
+const defaultAggregatingSinkReservoirSize = 1024
+
+// AggregatingSink keeps percentiles of the timings emitted to it, per job and event, so that you can log a periodic
+// summary instead of every timing. Other emits are ignored.
+//
+// Each job+event keeps a uniform random sample of up to ReservoirSize timings, so percentiles are estimates once there
+// have been more timings than that. Counts, mins, and maxes are exact.
+type AggregatingSink struct {
+	// ReservoirSize is the most timings kept per job and event. Defaults to 1024.
+	ReservoirSize int
+
+	// Interval, if non-zero, is how long each window lasts. Once a window is over, the next emit, Snapshot, or LastWindow starts a new, empty one,
+	// and the one that's over is kept for LastWindow.
+	Interval time.Duration
+
+	mutex       sync.Mutex
+	timings     map[aggregatingSinkKey]*timingReservoir
+	lastTimings map[aggregatingSinkKey]*timingReservoir
+	windowStart time.Time
+	rand        *rand.Rand
+}
+
+// TimingSummary summarizes the timings of one job and event. Durations are in nanoseconds.
+type TimingSummary struct {
+	Job      string
+	Event    string
+	Count    int64
+	NanosMin int64
+	NanosMax int64
+	NanosP50 int64
+	NanosP95 int64
+	NanosP99 int64
+}
+
+type aggregatingSinkKey struct {
+	job, event string
+}
+
+type timingReservoir struct {
+	count    int64
+	min, max int64
+	samples  []int64
+}
+
+func NewAggregatingSink(interval time.Duration) *AggregatingSink {
+	return &AggregatingSink{Interval: interval}
+}
+
+func (s *AggregatingSink) EmitEvent(job string, event string, kvs map[string]string) {}
+
+func (s *AggregatingSink) EmitEventErr(job string, event string, inputErr error, kvs map[string]string) {
+}
+
+func (s *AggregatingSink) EmitTiming(job string, event string, nanos int64, kvs map[string]string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.rollWindow()
+
+	key := aggregatingSinkKey{job, event}
+	r := s.timings[key]
+	if r == nil {
+		r = &timingReservoir{min: nanos, max: nanos}
+		s.timings[key] = r
+	}
+	s.ingest(r, nanos)
+}
+
+func (s *AggregatingSink) EmitComplete(job string, status CompletionStatus, nanos int64, kvs map[string]string) {
+}
+
+func (s *AggregatingSink) EmitGauge(job string, event string, value float64, kvs map[string]string) {}
+
+func (s *AggregatingSink) EmitCount(job string, event string, delta int64, kvs map[string]string) {}
+
+// Snapshot summarizes the current window's timings, sorted by job and then event.
+func (s *AggregatingSink) Snapshot() []TimingSummary {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.rollWindow()
+	return summarizeTimings(s.timings)
+}
+
+// LastWindow summarizes the timings of the window before the current one, sorted by job and then event.
+// Use it with Interval to log a summary of each window once it's over:
+//
+//	sink := health.NewAggregatingSink(time.Minute)
+//	for range time.Tick(time.Minute) {
+//		for _, summary := range sink.LastWindow() {
+//			...
+//		}
+//	}
+//
+// It's empty until the first window is over, and if there were no timings in the window before the current one.
+func (s *AggregatingSink) LastWindow() []TimingSummary {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.rollWindow()
+	return summarizeTimings(s.lastTimings)
+}
+
+// summarizeTimings summarizes timings, sorted by job and then event.
+func summarizeTimings(timings map[aggregatingSinkKey]*timingReservoir) []TimingSummary {
+	summaries := make([]TimingSummary, 0, len(timings))
+	for key, r := range timings {
+		sorted := append([]int64(nil), r.samples...)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+		summaries = append(summaries, TimingSummary{
+			Job:      key.job,
+			Event:    key.event,
+			Count:    r.count,
+			NanosMin: r.min,
+			NanosMax: r.max,
+			NanosP50: percentile(sorted, 0.50),
+			NanosP95: percentile(sorted, 0.95),
+			NanosP99: percentile(sorted, 0.99),
+		})
+	}
+
+	sort.Slice(summaries, func(i, j int) bool {
+		if summaries[i].Job != summaries[j].Job {
+			return summaries[i].Job < summaries[j].Job
+		}
+		return summaries[i].Event < summaries[j].Event
+	})
+
+	return summaries
+}
+
+// Reset discards all timings, including the last window's, and starts a new window.
+func (s *AggregatingSink) Reset() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.timings = nil
+	s.rollWindow()
+}
+
+// rollWindow starts a new window if there isn't one or the current one is over, keeping the one that's over as the last window.
+// If a whole Interval or more has passed since it was over, the last window is empty instead. mutex must be held.
+func (s *AggregatingSink) rollWindow() {
+	t := now()
+	if s.timings != nil && (s.Interval <= 0 || t.Sub(s.windowStart) < s.Interval) {
+		return
+	}
+	s.lastTimings = nil
+	if s.timings != nil && t.Sub(s.windowStart) < 2*s.Interval {
+		s.lastTimings = s.timings
+	}
+	s.timings = make(map[aggregatingSinkKey]*timingReservoir)
+	s.windowStart = t
+}
+
+// ingest adds nanos to r, using reservoir sampling once r is full. mutex must be held.
+func (s *AggregatingSink) ingest(r *timingReservoir, nanos int64) {
+	r.count++
+	if nanos < r.min {
+		r.min = nanos
+	}
+	if nanos > r.max {
+		r.max = nanos
+	}
+
+	size := s.ReservoirSize
+	if size <= 0 {
+		size = defaultAggregatingSinkReservoirSize
+	}
+	if len(r.samples) < size {
+		r.samples = append(r.samples, nanos)
+		return
+	}
+
+	if s.rand == nil {
+		s.rand = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	if i := s.rand.Int63n(r.count); i < int64(size) {
+		r.samples[i] = nanos
+	}
+}
+
+// percentile returns the nearest-rank percentile p (0.0 to 1.0) of sorted, or 0 if it's empty.
+func percentile(sorted []int64, p float64) int64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := int(p*float64(len(sorted))+0.5) - 1
+	if i < 0 {
+		i = 0
+	}
+	if i >= len(sorted) {
+		i = len(sorted) - 1
+	}
+	return sorted[i]
+}
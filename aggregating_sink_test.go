@@ -0,0 +1,94 @@
+package health
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestAggregatingSink(t *testing.T) {
+	sink := NewAggregatingSink(0)
+
+	for i := int64(1); i <= 100; i++ {
+		sink.EmitTiming("myjob", "fetch", i, nil)
+	}
+	sink.EmitTiming("myjob", "save", 7, nil)
+	sink.EmitTiming("anotherjob", "fetch", 3, nil)
+	sink.EmitEvent("myjob", "fetch", nil)
+	sink.EmitComplete("myjob", Success, 1000, nil)
+
+	summaries := sink.Snapshot()
+	assert.Equal(t, 3, len(summaries))
+
+	assert.Equal(t, "anotherjob", summaries[0].Job)
+	assert.Equal(t, TimingSummary{Job: "myjob", Event: "fetch", Count: 100, NanosMin: 1, NanosMax: 100, NanosP50: 50, NanosP95: 95, NanosP99: 99}, summaries[1])
+	assert.Equal(t, TimingSummary{Job: "myjob", Event: "save", Count: 1, NanosMin: 7, NanosMax: 7, NanosP50: 7, NanosP95: 7, NanosP99: 7}, summaries[2])
+}
+
+func TestAggregatingSinkReservoir(t *testing.T) {
+	sink := &AggregatingSink{ReservoirSize: 10}
+
+	for i := int64(1); i <= 1000; i++ {
+		sink.EmitTiming("myjob", "fetch", i, nil)
+	}
+
+	summaries := sink.Snapshot()
+	assert.Equal(t, 1, len(summaries))
+	assert.Equal(t, int64(1000), summaries[0].Count)
+	assert.Equal(t, int64(1), summaries[0].NanosMin)
+	assert.Equal(t, int64(1000), summaries[0].NanosMax)
+	assert.Equal(t, 10, len(sink.timings[aggregatingSinkKey{"myjob", "fetch"}].samples))
+}
+
+func TestAggregatingSinkInterval(t *testing.T) {
+	defer resetNowMock()
+	setNowMock("2011-09-09T23:36:13Z")
+	sink := NewAggregatingSink(time.Minute)
+
+	sink.EmitTiming("myjob", "fetch", 5, nil)
+	setNowMock("2011-09-09T23:37:00Z")
+	sink.EmitTiming("myjob", "fetch", 7, nil)
+	assert.Equal(t, int64(2), sink.Snapshot()[0].Count)
+
+	setNowMock("2011-09-09T23:37:13Z")
+	assert.Equal(t, 0, len(sink.Snapshot()))
+
+	sink.EmitTiming("myjob", "fetch", 9, nil)
+	assert.Equal(t, int64(9), sink.Snapshot()[0].NanosP50)
+}
+
+func TestAggregatingSinkLastWindow(t *testing.T) {
+	defer resetNowMock()
+	setNowMock("2011-09-09T23:36:00Z")
+	sink := NewAggregatingSink(time.Minute)
+	assert.Equal(t, 0, len(sink.LastWindow()))
+
+	sink.EmitTiming("myjob", "fetch", 5, nil)
+	sink.EmitTiming("myjob", "fetch", 7, nil)
+	assert.Equal(t, 0, len(sink.LastWindow()))
+
+	// Just after the window is over, it's still there to be summarized.
+	setNowMock("2011-09-09T23:37:01Z")
+	assert.Equal(t, 0, len(sink.Snapshot()))
+	last := sink.LastWindow()
+	assert.Equal(t, 1, len(last))
+	assert.Equal(t, int64(2), last[0].Count)
+	assert.Equal(t, int64(7), last[0].NanosMax)
+
+	sink.EmitTiming("myjob", "fetch", 9, nil)
+	assert.Equal(t, int64(2), sink.LastWindow()[0].Count)
+
+	setNowMock("2011-09-09T23:38:02Z")
+	assert.Equal(t, int64(9), sink.LastWindow()[0].NanosP50)
+
+	// The window before the current one had no timings.
+	setNowMock("2011-09-09T23:40:00Z")
+	assert.Equal(t, 0, len(sink.LastWindow()))
+}
+
+func TestAggregatingSinkReset(t *testing.T) {
+	sink := NewAggregatingSink(0)
+	sink.EmitTiming("myjob", "fetch", 5, nil)
+	sink.Reset()
+	assert.Equal(t, 0, len(sink.Snapshot()))
+}
@@ -40,6 +40,8 @@ func startAggregator(intervalDuration time.Duration, retain time.Duration, sink
 				agg.EmitTiming(cmd.Job, cmd.Event, cmd.Nanos)
 			} else if cmd.Kind == cmdKindComplete {
 				agg.EmitComplete(cmd.Job, cmd.Status, cmd.Nanos)
+			} else if cmd.Kind == cmdKindCount {
+				agg.EmitCount(cmd.Job, cmd.Event, cmd.Delta)
 			}
 		case <-ticker:
 			agg.getIntervalAggregation() // this has the side effect of sliding the interval window if necessary.
@@ -82,6 +84,14 @@ func (a *aggregator) EmitEvent(job string, event string) {
 	intAgg.SerialNumber++
 }
 
+func (a *aggregator) EmitCount(job string, event string, delta int64) {
+	intAgg := a.getIntervalAggregation()
+	intAgg.Events[event] = intAgg.Events[event] + delta
+	jobAgg := intAgg.getJobAggregation(job)
+	jobAgg.Events[event] = jobAgg.Events[event] + delta
+	intAgg.SerialNumber++
+}
+
 func (a *aggregator) EmitEventErr(job string, event string, inputErr error) {
 	intAgg := a.getIntervalAggregation()
 	errc := intAgg.getCounterErrs(event)
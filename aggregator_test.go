@@ -192,6 +192,41 @@ func TestEmitComplete(t *testing.T) {
 	assert.Equal(t, 100, jobAgg.NanosMax)
 }
 
+func TestEmitCount(t *testing.T) {
+	setNowMock("2011-09-09T23:36:13Z")
+	defer resetNowMock()
+	a := newAggregator(time.Minute, time.Minute*5)
+	a.EmitEvent("foo", "bar")
+	a.EmitCount("foo", "bar", 4)
+	a.EmitCount("baz", "bar", 2)
+
+	intAgg := a.intervalAggregations[0]
+	assert.Equal(t, int64(7), intAgg.Events["bar"])
+	assert.Equal(t, int64(5), intAgg.Jobs["foo"].Events["bar"])
+	assert.Equal(t, int64(2), intAgg.Jobs["baz"].Events["bar"])
+}
+
+func TestEmitCompleteTimeoutCancelled(t *testing.T) {
+	setNowMock("2011-09-09T23:36:13Z")
+	defer resetNowMock()
+	a := newAggregator(time.Minute, time.Minute*5)
+	a.EmitComplete("foo", Timeout, 100)
+	a.EmitComplete("foo", Timeout, 5)
+	a.EmitComplete("foo", Cancelled, 9)
+
+	jobAgg := a.intervalAggregations[0].Jobs["foo"]
+	assert.NotNil(t, jobAgg)
+	assert.Equal(t, int64(3), jobAgg.Count)
+	assert.Equal(t, int64(2), jobAgg.CountTimeout)
+	assert.Equal(t, int64(1), jobAgg.CountCancelled)
+	assert.Equal(t, int64(0), jobAgg.CountError)
+
+	dup := jobAgg.Clone()
+	dup.merge(jobAgg)
+	assert.Equal(t, int64(4), dup.CountTimeout)
+	assert.Equal(t, int64(2), dup.CountCancelled)
+}
+
 func TestRotation(t *testing.T) {
 	defer resetNowMock()
 	a := newAggregator(time.Minute, time.Minute*5)
@@ -0,0 +1,233 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+type AsyncSinkOverflowPolicy int
+
+const (
+	// AsyncSinkBlock makes emits wait for room in the buffer.
+	AsyncSinkBlock AsyncSinkOverflowPolicy = iota
+
+	// AsyncSinkDropOldest makes room in a full buffer by discarding the oldest buffered emit.
+	AsyncSinkDropOldest
+)
+
+// AsyncSink wraps a Sink so that emits are buffered on a channel and handed to the wrapped Sink from a background goroutine.
+// This keeps a slow Sink (eg, a WriterSink writing to a network syslog) from blocking the caller.
+// Call Close to drain the buffer and stop the goroutine, or CloseContext to bound how long draining may take.
+type AsyncSink struct {
+	Sink Sink
+
+	// OverflowPolicy controls what happens when an emit finds the buffer full. Defaults to AsyncSinkBlock.
+	OverflowPolicy AsyncSinkOverflowPolicy
+
+	cmdChan  chan *emitCmd
+	doneChan chan int
+
+	// closedMutex guards against sending on cmdChan after it's been closed.
+	closedMutex sync.RWMutex
+	closed      bool
+
+	// enqueued and handled count emits put on and taken off of cmdChan (handled includes dropped ones).
+	// Flush waits on countCond for handled to catch up. Once abandoned is set by CloseContext, the rest of the buffer is dropped.
+	countMutex sync.Mutex
+	countCond  *sync.Cond
+	enqueued   uint64
+	handled    uint64
+	dropped    uint64
+	abandoned  bool
+}
+
+func NewAsyncSink(sink Sink, bufferSize int) *AsyncSink {
+	s := &AsyncSink{
+		Sink:     sink,
+		cmdChan:  make(chan *emitCmd, bufferSize),
+		doneChan: make(chan int),
+	}
+	s.countCond = sync.NewCond(&s.countMutex)
+
+	go asyncSinkProcessingLoop(s)
+
+	return s
+}
+
+func (s *AsyncSink) EmitEvent(job string, event string, kvs map[string]string) {
+	s.enqueue(&emitCmd{Kind: cmdKindEvent, Job: job, Event: event, Kvs: copyKvs(kvs)})
+}
+
+func (s *AsyncSink) EmitEventErr(job string, event string, inputErr error, kvs map[string]string) {
+	s.enqueue(&emitCmd{Kind: cmdKindEventErr, Job: job, Event: event, Err: inputErr, Kvs: copyKvs(kvs)})
+}
+
+func (s *AsyncSink) EmitTiming(job string, event string, nanos int64, kvs map[string]string) {
+	s.enqueue(&emitCmd{Kind: cmdKindTiming, Job: job, Event: event, Nanos: nanos, Kvs: copyKvs(kvs)})
+}
+
+func (s *AsyncSink) EmitComplete(job string, status CompletionStatus, nanos int64, kvs map[string]string) {
+	s.enqueue(&emitCmd{Kind: cmdKindComplete, Job: job, Status: status, Nanos: nanos, Kvs: copyKvs(kvs)})
+}
+
+func (s *AsyncSink) EmitGauge(job string, event string, value float64, kvs map[string]string) {
+	s.enqueue(&emitCmd{Kind: cmdKindGauge, Job: job, Event: event, Value: value, Kvs: copyKvs(kvs)})
+}
+
+func (s *AsyncSink) EmitCount(job string, event string, delta int64, kvs map[string]string) {
+	s.enqueue(&emitCmd{Kind: cmdKindCount, Job: job, Event: event, Delta: delta, Kvs: copyKvs(kvs)})
+}
+
+// Close stops accepting emits, waits for everything already buffered to reach the wrapped Sink, and stops the background goroutine.
+// Emits after Close are discarded. It's safe to call Close more than once.
+func (s *AsyncSink) Close() error {
+	s.closedMutex.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.cmdChan)
+	}
+	s.closedMutex.Unlock()
+
+	<-s.doneChan
+	return nil
+}
+
+// CloseContext is Close with a bound on draining, so a dead wrapped Sink can't hang shutdown.
+// If ctx is done before the buffer has drained, it gives up and returns an error saying how many emits hadn't been handled yet
+// (wrapping ctx.Err()). Those still in the buffer are dropped and counted by Dropped.
+// An emit the wrapped Sink is stuck on can't be interrupted, so the background goroutine exits only once that returns.
+func (s *AsyncSink) CloseContext(ctx context.Context) error {
+	closed := make(chan int)
+	go func() {
+		s.Close()
+		close(closed)
+	}()
+
+	select {
+	case <-closed:
+		return nil
+	case <-ctx.Done():
+	}
+
+	s.countMutex.Lock()
+	s.abandoned = true
+	left := s.enqueued - s.handled
+	s.countMutex.Unlock()
+
+	return fmt.Errorf("health.AsyncSink: gave up draining with %d emits left: %w", left, ctx.Err())
+}
+
+// Dropped returns the number of emits dropped so far, by AsyncSinkDropOldest or by CloseContext giving up.
+func (s *AsyncSink) Dropped() uint64 {
+	s.countMutex.Lock()
+	defer s.countMutex.Unlock()
+	return s.dropped
+}
+
+// Flush waits until everything emitted so far has been handed to the wrapped Sink, and then flushes it if it implements Flusher.
+func (s *AsyncSink) Flush() error {
+	s.countMutex.Lock()
+	target := s.enqueued
+	for s.handled < target {
+		s.countCond.Wait()
+	}
+	s.countMutex.Unlock()
+
+	return FlushAll(s.Sink)
+}
+
+func (s *AsyncSink) enqueue(cmd *emitCmd) {
+	s.closedMutex.RLock()
+	defer s.closedMutex.RUnlock()
+
+	if s.closed {
+		return
+	}
+
+	// Count the emit before it's on the channel so handled never gets ahead of enqueued.
+	s.addCount(&s.enqueued)
+
+	if s.OverflowPolicy == AsyncSinkBlock {
+		s.cmdChan <- cmd
+		return
+	}
+
+	for {
+		select {
+		case s.cmdChan <- cmd:
+			return
+		default:
+		}
+
+		// Full: drop the oldest and try again.
+		select {
+		case <-s.cmdChan:
+			s.drop()
+		default:
+		}
+	}
+}
+
+func (s *AsyncSink) addCount(count *uint64) {
+	s.countMutex.Lock()
+	*count++
+	s.countMutex.Unlock()
+	s.countCond.Broadcast()
+}
+
+// drop counts an emit taken off of cmdChan without being forwarded.
+func (s *AsyncSink) drop() {
+	s.countMutex.Lock()
+	s.dropped++
+	s.handled++
+	s.countMutex.Unlock()
+	s.countCond.Broadcast()
+}
+
+func (s *AsyncSink) isAbandoned() bool {
+	s.countMutex.Lock()
+	defer s.countMutex.Unlock()
+	return s.abandoned
+}
+
+func asyncSinkProcessingLoop(s *AsyncSink) {
+	for cmd := range s.cmdChan {
+		if s.isAbandoned() {
+			s.drop()
+			continue
+		}
+		forwardEmitCmd(s.Sink, cmd)
+		s.addCount(&s.handled)
+	}
+	close(s.doneChan)
+}
+
+func forwardEmitCmd(sink Sink, cmd *emitCmd) {
+	switch cmd.Kind {
+	case cmdKindEvent:
+		sink.EmitEvent(cmd.Job, cmd.Event, cmd.Kvs)
+	case cmdKindEventErr:
+		sink.EmitEventErr(cmd.Job, cmd.Event, cmd.Err, cmd.Kvs)
+	case cmdKindTiming:
+		sink.EmitTiming(cmd.Job, cmd.Event, cmd.Nanos, cmd.Kvs)
+	case cmdKindComplete:
+		sink.EmitComplete(cmd.Job, cmd.Status, cmd.Nanos, cmd.Kvs)
+	case cmdKindGauge:
+		sink.EmitGauge(cmd.Job, cmd.Event, cmd.Value, cmd.Kvs)
+	case cmdKindCount:
+		sink.EmitCount(cmd.Job, cmd.Event, cmd.Delta, cmd.Kvs)
+	}
+}
+
+// copyKvs returns a copy of kvs, since callers are free to modify their map once an emit returns.
+func copyKvs(kvs map[string]string) map[string]string {
+	if kvs == nil {
+		return nil
+	}
+	c := make(map[string]string, len(kvs))
+	for k, v := range kvs {
+		c[k] = v
+	}
+	return c
+}
@@ -0,0 +1,118 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+	"time"
+)
+
+// eventNameSink records the event names it receives.
+// If gate is set, each emit signals on entered and then waits for gate to be closed.
+type eventNameSink struct {
+	countingSink
+	entered chan int
+	gate    chan int
+	mutex   sync.Mutex
+	events  []string
+}
+
+func (s *eventNameSink) EmitEvent(job string, event string, kvs map[string]string) {
+	if s.gate != nil {
+		s.entered <- 1
+		<-s.gate
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.events = append(s.events, event)
+}
+
+func TestAsyncSink(t *testing.T) {
+	inner := &countingSink{}
+	sink := NewAsyncSink(inner, 10)
+	for i := 0; i < 100; i++ {
+		emitOneOfEach(sink)
+	}
+	assert.NoError(t, sink.Close())
+
+	assert.Equal(t, countingSink{100, 100, 100, 100, 100, 100}, *inner)
+
+	// Closing again is fine, and later emits are discarded.
+	assert.NoError(t, sink.Close())
+	emitOneOfEach(sink)
+	assert.Equal(t, countingSink{100, 100, 100, 100, 100, 100}, *inner)
+}
+
+func TestAsyncSinkCopiesKvs(t *testing.T) {
+	var gotKvs map[string]string
+	inner := &recordingKvsSink{kvs: &gotKvs}
+	sink := NewAsyncSink(inner, 10)
+
+	kvs := map[string]string{"foo": "bar"}
+	sink.EmitEvent("myjob", "myevent", kvs)
+	kvs["foo"] = "changed"
+	sink.Close()
+
+	assert.Equal(t, map[string]string{"foo": "bar"}, gotKvs)
+}
+
+type recordingKvsSink struct {
+	countingSink
+	kvs *map[string]string
+}
+
+func (s *recordingKvsSink) EmitEvent(job string, event string, kvs map[string]string) {
+	*s.kvs = kvs
+}
+
+func TestAsyncSinkDropOldest(t *testing.T) {
+	inner := &eventNameSink{entered: make(chan int, 10), gate: make(chan int)}
+	sink := NewAsyncSink(inner, 2)
+	sink.OverflowPolicy = AsyncSinkDropOldest
+
+	// The background goroutine takes "a" and then waits on the gate, so the buffer fills up behind it.
+	sink.EmitEvent("myjob", "a", nil)
+	<-inner.entered
+	for _, event := range []string{"b", "c", "d", "e"} {
+		sink.EmitEvent("myjob", event, nil)
+	}
+	close(inner.gate)
+	sink.Close()
+
+	assert.Equal(t, []string{"a", "d", "e"}, inner.events)
+	assert.Equal(t, uint64(2), sink.Dropped())
+}
+
+func TestAsyncSinkCloseContext(t *testing.T) {
+	inner := &countingSink{}
+	sink := NewAsyncSink(inner, 10)
+	emitOneOfEach(sink)
+	assert.NoError(t, sink.CloseContext(context.Background()))
+	assert.Equal(t, countingSink{1, 1, 1, 1, 1, 1}, *inner)
+	assert.Equal(t, uint64(0), sink.Dropped())
+}
+
+func TestAsyncSinkCloseContextDeadline(t *testing.T) {
+	inner := &eventNameSink{entered: make(chan int, 10), gate: make(chan int)}
+	sink := NewAsyncSink(inner, 10)
+
+	// The background goroutine gets stuck on "a", with "b" and "c" buffered behind it.
+	sink.EmitEvent("myjob", "a", nil)
+	<-inner.entered
+	sink.EmitEvent("myjob", "b", nil)
+	sink.EmitEvent("myjob", "c", nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := sink.CloseContext(ctx)
+	assert.EqualError(t, err, "health.AsyncSink: gave up draining with 3 emits left: context deadline exceeded")
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+
+	// Once "a" gets through, the rest are dropped rather than forwarded.
+	close(inner.gate)
+	sink.Close()
+	assert.Equal(t, []string{"a"}, inner.events)
+	assert.Equal(t, uint64(2), sink.Dropped())
+}
@@ -0,0 +1,63 @@
+package health
+
+import (
+	"bufio"
+	"io"
+	"sync"
+	"time"
+)
+
+// BufferedWriterSink is a WriterSink that buffers its writes, cutting a Write call per line down to one per buffer.
+// The buffer is flushed when it fills up, every flushInterval, and on Flush/Close, so a quiet log still shows up within flushInterval.
+// Call Close before exiting so the last lines aren't lost:
+//
+//	sink := health.NewBufferedWriterSink(f, 64*1024, time.Second)
+//	defer sink.Close()
+type BufferedWriterSink struct {
+	WriterSink
+
+	flushInterval time.Duration
+	doneChan      chan int
+	closeOnce     sync.Once
+}
+
+// NewBufferedWriterSink makes a BufferedWriterSink that writes to w through a buffer of size bytes, flushed every flushInterval.
+// If flushInterval is zero, the buffer is only flushed when full and on Flush/Close.
+func NewBufferedWriterSink(w io.Writer, size int, flushInterval time.Duration) *BufferedWriterSink {
+	s := &BufferedWriterSink{flushInterval: flushInterval}
+	s.Writer = bufio.NewWriterSize(w, size)
+
+	if flushInterval > 0 {
+		s.doneChan = make(chan int)
+		go bufferedWriterSinkFlushLoop(s)
+	}
+
+	return s
+}
+
+// Close stops the periodic flushes and flushes what's left in the buffer. It doesn't close the underlying writer.
+// Calling it more than once is fine.
+func (s *BufferedWriterSink) Close() error {
+	s.closeOnce.Do(func() {
+		if s.doneChan != nil {
+			s.doneChan <- 1
+		}
+	})
+	return s.Flush()
+}
+
+func bufferedWriterSinkFlushLoop(sink *BufferedWriterSink) {
+	ticker := time.NewTicker(sink.flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-sink.doneChan:
+			return
+		case <-ticker.C:
+			if err := sink.Flush(); err != nil && sink.ErrorHandler != nil {
+				sink.ErrorHandler(NameError(sink.Name, err))
+			}
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package health
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+	"time"
+)
+
+// lockedBuffer is a bytes.Buffer that's safe to read while a sink writes to it from another goroutine.
+type lockedBuffer struct {
+	mutex sync.Mutex
+	b     bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.b.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.b.String()
+}
+
+func TestBufferedWriterSink(t *testing.T) {
+	var b bytes.Buffer
+	sink := NewBufferedWriterSink(&b, 4096, 0)
+
+	sink.EmitEvent("myjob", "myevent", nil)
+	assert.Equal(t, 0, b.Len())
+
+	assert.NoError(t, sink.Close())
+	assert.Regexp(t, basicEventRegexp, b.String())
+
+	// Closing again is fine.
+	assert.NoError(t, sink.Close())
+}
+
+func TestBufferedWriterSinkFlushesWhenFull(t *testing.T) {
+	var b bytes.Buffer
+	sink := NewBufferedWriterSink(&b, 64, 0)
+	defer sink.Close()
+
+	sink.EmitEvent("myjob", "myevent", map[string]string{"padding": "xxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxx"})
+	sink.EmitEvent("myjob", "myevent", nil)
+	assert.Regexp(t, kvsEventRegexp, b.String())
+}
+
+func TestBufferedWriterSinkFlushInterval(t *testing.T) {
+	var b lockedBuffer
+	sink := NewBufferedWriterSink(&b, 4096, 10*time.Millisecond)
+	defer sink.Close()
+
+	sink.EmitEvent("myjob", "myevent", nil)
+
+	deadline := time.Now().Add(time.Second)
+	for b.String() == "" && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	assert.Regexp(t, basicEventRegexp, b.String())
+}
+
+func TestBufferedWriterSinkConcurrentWrites(t *testing.T) {
+	var b lockedBuffer
+	sink := NewBufferedWriterSink(&b, 128, time.Millisecond)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				sink.EmitEvent("myjob", "myevent", nil)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.NoError(t, sink.Close())
+
+	lines := bytes.Split(bytes.TrimSuffix([]byte(b.String()), []byte("\n")), []byte("\n"))
+	assert.Equal(t, 1000, len(lines))
+	for _, line := range lines {
+		assert.Regexp(t, basicEventRegexp, string(line))
+	}
+}
@@ -0,0 +1,136 @@
+package health
+
+import (
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// CompletionCounterSink tallies completions per job and status, so you can keep an eye on how jobs are doing
+// without reading every completion line. Other emits are ignored.
+//
+// If it's made with a Sink and an interval, it emits a summary event per job to Sink every interval, and then starts the tallies over:
+//
+//	[2016-01-02T15:04:05Z]: job:signup event:completions kvs:[error:2 interval:1m0s success:412]
+//
+// Statuses with no completions are left out of the summary.
+type CompletionCounterSink struct {
+	// Sink is where summaries go.
+	Sink Sink
+
+	// Interval is how often summaries are emitted.
+	Interval time.Duration
+
+	mutex     sync.Mutex
+	counts    map[string]map[CompletionStatus]int64
+	doneChan  chan int
+	closeOnce sync.Once
+}
+
+// NewCompletionCounterSink returns a CompletionCounterSink that summarizes to sink every interval until Close is called.
+// If sink is nil or interval isn't positive, there are no summaries, and the tallies only grow.
+func NewCompletionCounterSink(sink Sink, interval time.Duration) *CompletionCounterSink {
+	s := &CompletionCounterSink{
+		Sink:     sink,
+		Interval: interval,
+		counts:   make(map[string]map[CompletionStatus]int64),
+	}
+
+	if sink != nil && interval > 0 {
+		s.doneChan = make(chan int)
+		go completionCounterSinkSummaryLoop(s)
+	}
+
+	return s
+}
+
+func (s *CompletionCounterSink) EmitEvent(job string, event string, kvs map[string]string) {}
+
+func (s *CompletionCounterSink) EmitEventErr(job string, event string, inputErr error, kvs map[string]string) {
+}
+
+func (s *CompletionCounterSink) EmitTiming(job string, event string, nanos int64, kvs map[string]string) {
+}
+
+func (s *CompletionCounterSink) EmitComplete(job string, status CompletionStatus, nanos int64, kvs map[string]string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	jobCounts, ok := s.counts[job]
+	if !ok {
+		jobCounts = make(map[CompletionStatus]int64)
+		s.counts[job] = jobCounts
+	}
+	jobCounts[status]++
+}
+
+func (s *CompletionCounterSink) EmitGauge(job string, event string, value float64, kvs map[string]string) {
+}
+
+func (s *CompletionCounterSink) EmitCount(job string, event string, delta int64, kvs map[string]string) {
+}
+
+// Counts returns the tallies since the last summary (or since the sink was made), by job and then status.
+// The returned maps are copies.
+func (s *CompletionCounterSink) Counts() map[string]map[CompletionStatus]int64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	counts := make(map[string]map[CompletionStatus]int64, len(s.counts))
+	for job, jobCounts := range s.counts {
+		counts[job] = make(map[CompletionStatus]int64, len(jobCounts))
+		for status, n := range jobCounts {
+			counts[job][status] = n
+		}
+	}
+	return counts
+}
+
+// Close stops the summaries, emitting a last one for anything tallied since the previous one.
+// Calling it more than once is fine.
+func (s *CompletionCounterSink) Close() error {
+	s.closeOnce.Do(func() {
+		if s.doneChan != nil {
+			s.doneChan <- 1
+			s.summarize()
+		}
+	})
+	return nil
+}
+
+// summarize emits a summary event per job to Sink, in job order, and starts the tallies over.
+func (s *CompletionCounterSink) summarize() {
+	s.mutex.Lock()
+	counts := s.counts
+	s.counts = make(map[string]map[CompletionStatus]int64)
+	s.mutex.Unlock()
+
+	jobs := make([]string, 0, len(counts))
+	for job := range counts {
+		jobs = append(jobs, job)
+	}
+	sort.Strings(jobs)
+
+	for _, job := range jobs {
+		kvs := map[string]string{"interval": s.Interval.String()}
+		for status, n := range counts[job] {
+			kvs[status.String()] = strconv.FormatInt(n, 10)
+		}
+		s.Sink.EmitEvent(job, "completions", kvs)
+	}
+}
+
+func completionCounterSinkSummaryLoop(sink *CompletionCounterSink) {
+	ticker := time.NewTicker(sink.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-sink.doneChan:
+			return
+		case <-ticker.C:
+			sink.summarize()
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package health
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompletionCounterSink(t *testing.T) {
+	inner := &MemorySink{}
+	sink := NewCompletionCounterSink(inner, time.Hour)
+
+	sink.EmitComplete("signup", Success, 1204, nil)
+	sink.EmitComplete("signup", Success, 1204, nil)
+	sink.EmitComplete("signup", Error, 1204, nil)
+	sink.EmitComplete("billing", Panic, 1204, nil)
+	sink.EmitEvent("signup", "myevent", nil)
+
+	counts := sink.Counts()
+	assert.Equal(t, map[string]map[CompletionStatus]int64{
+		"signup":  {Success: 2, Error: 1},
+		"billing": {Panic: 1},
+	}, counts)
+
+	// Counts returns a copy.
+	counts["signup"][Success] = 100
+	assert.Equal(t, int64(2), sink.Counts()["signup"][Success])
+
+	sink.summarize()
+	assert.Equal(t, []MemorySinkEvent{
+		{Kind: KindEvent, Job: "billing", Event: "completions", Kvs: map[string]string{"panic": "1", "interval": "1h0m0s"}},
+		{Kind: KindEvent, Job: "signup", Event: "completions", Kvs: map[string]string{"success": "2", "error": "1", "interval": "1h0m0s"}},
+	}, inner.Events())
+
+	// Summarizing starts the tallies over.
+	assert.Equal(t, map[string]map[CompletionStatus]int64{}, sink.Counts())
+
+	sink.EmitComplete("signup", Timeout, 1204, nil)
+	assert.NoError(t, sink.Close())
+	events := inner.Events()
+	assert.Equal(t, 3, len(events))
+	assert.Equal(t, map[string]string{"timeout": "1", "interval": "1h0m0s"}, events[2].Kvs)
+
+	// Closing again doesn't block or summarize again.
+	assert.NoError(t, sink.Close())
+	assert.Equal(t, 3, len(inner.Events()))
+}
+
+func TestCompletionCounterSinkWithoutSummaries(t *testing.T) {
+	sink := NewCompletionCounterSink(nil, 0)
+	sink.EmitComplete("signup", Success, 1204, nil)
+	assert.NoError(t, sink.Close())
+	assert.Equal(t, map[string]map[CompletionStatus]int64{"signup": {Success: 1}}, sink.Counts())
+}
+
+func TestCompletionCounterSinkLoop(t *testing.T) {
+	inner := &MemorySink{}
+	sink := NewCompletionCounterSink(inner, time.Millisecond)
+	defer sink.Close()
+
+	sink.EmitComplete("signup", Success, 1204, nil)
+	for i := 0; i < 1000 && len(inner.Events()) == 0; i++ {
+		time.Sleep(time.Millisecond)
+	}
+	assert.Equal(t, "completions", inner.Events()[0].Event)
+}
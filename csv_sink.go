@@ -0,0 +1,100 @@
+package health
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// CSVSink writes one CSV row per emit, for loading into spreadsheets and other CSV tooling. The first row is a header:
+//
+//	time,kind,job,event,err,nanos,status,value,kvs
+//	2015-03-11T22:53:22.115855203Z,timing,myjob,fetch,,1204,,,"{""foo"":""bar""}"
+//
+// kind is one of KindEvent, KindEventErr, etc. Columns that don't apply to a kind are empty: nanos is set for timings and completions,
+// status for completions, and value for gauges and counts. kvs is JSON-encoded, and empty if there aren't any.
+// Fields are quoted and escaped by encoding/csv.
+type CSVSink struct {
+	Writer io.Writer
+
+	// ErrorHandler, if set, is called with any error returned by Writer.
+	ErrorHandler func(error)
+
+	// Name, if set, identifies this sink in errors passed to ErrorHandler (see SinkError).
+	Name string
+
+	mutex       sync.Mutex
+	csvWriter   *csv.Writer
+	wroteHeader bool
+}
+
+var csvSinkHeader = []string{"time", "kind", "job", "event", "err", "nanos", "status", "value", "kvs"}
+
+// NewCSVSink returns a CSVSink that writes to w.
+func NewCSVSink(w io.Writer) *CSVSink {
+	return &CSVSink{Writer: w}
+}
+
+func (s *CSVSink) EmitEvent(job string, event string, kvs map[string]string) {
+	s.write(KindEvent, job, event, "", "", "", "", kvs)
+}
+
+func (s *CSVSink) EmitEventErr(job string, event string, inputErr error, kvs map[string]string) {
+	s.write(KindEventErr, job, event, errorMessage(inputErr), "", "", "", kvs)
+}
+
+func (s *CSVSink) EmitTiming(job string, event string, nanos int64, kvs map[string]string) {
+	s.write(KindTiming, job, event, "", strconv.FormatInt(nanos, 10), "", "", kvs)
+}
+
+func (s *CSVSink) EmitComplete(job string, status CompletionStatus, nanos int64, kvs map[string]string) {
+	s.write(KindComplete, job, "", "", strconv.FormatInt(nanos, 10), status.String(), "", kvs)
+}
+
+func (s *CSVSink) EmitGauge(job string, event string, value float64, kvs map[string]string) {
+	s.write(KindGauge, job, event, "", "", "", strconv.FormatFloat(value, 'f', -1, 64), kvs)
+}
+
+func (s *CSVSink) EmitCount(job string, event string, delta int64, kvs map[string]string) {
+	s.write(KindCount, job, event, "", "", "", strconv.FormatInt(delta, 10), kvs)
+}
+
+func (s *CSVSink) write(kind, job, event, errMsg, nanos, status, value string, kvs map[string]string) {
+	var kvsJSON string
+	if len(kvs) > 0 {
+		b, err := json.Marshal(kvs)
+		if err != nil {
+			s.handleError(err)
+			return
+		}
+		kvsJSON = string(b)
+	}
+	row := []string{now().UTC().Format(time.RFC3339Nano), kind, job, event, errMsg, nanos, status, value, kvsJSON}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.csvWriter == nil {
+		s.csvWriter = csv.NewWriter(s.Writer)
+	}
+	if !s.wroteHeader {
+		s.csvWriter.Write(csvSinkHeader)
+		s.wroteHeader = true
+	}
+	s.csvWriter.Write(row)
+	s.csvWriter.Flush()
+	if err := s.csvWriter.Error(); err != nil {
+		s.handleError(err)
+		// csv.Writer keeps returning the first error it saw, so start over with a fresh one.
+		s.csvWriter = nil
+	}
+}
+
+func (s *CSVSink) handleError(err error) {
+	if s.ErrorHandler != nil {
+		s.ErrorHandler(NameError(s.Name, err))
+	}
+}
@@ -0,0 +1,46 @@
+package health
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCSVSink(t *testing.T) {
+	setNowMock("2011-09-09T23:36:13Z")
+	defer resetNowMock()
+
+	var b bytes.Buffer
+	sink := NewCSVSink(&b)
+	sink.EmitEvent("myjob", "started", nil)
+	sink.EmitEventErr("myjob", "fetch", errors.New("bad \"quote\", comma\nnewline"), map[string]string{"host": "a,b"})
+	sink.EmitTiming("myjob", "fetch", 1204, nil)
+	sink.EmitComplete("myjob", Error, 5678, nil)
+	sink.EmitGauge("myjob", "queue", 3.5, nil)
+	sink.EmitCount("myjob", "items", 7, nil)
+
+	rows, err := csv.NewReader(&b).ReadAll()
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{
+		{"time", "kind", "job", "event", "err", "nanos", "status", "value", "kvs"},
+		{"2011-09-09T23:36:13Z", "event", "myjob", "started", "", "", "", "", ""},
+		{"2011-09-09T23:36:13Z", "event_err", "myjob", "fetch", "bad \"quote\", comma\nnewline", "", "", "", `{"host":"a,b"}`},
+		{"2011-09-09T23:36:13Z", "timing", "myjob", "fetch", "", "1204", "", "", ""},
+		{"2011-09-09T23:36:13Z", "complete", "myjob", "", "", "5678", "error", "", ""},
+		{"2011-09-09T23:36:13Z", "gauge", "myjob", "queue", "", "", "", "3.5", ""},
+		{"2011-09-09T23:36:13Z", "count", "myjob", "items", "", "", "", "7", ""},
+	}, rows)
+}
+
+func TestCSVSinkErrorHandler(t *testing.T) {
+	var errs []error
+	sink := &CSVSink{Writer: erroringWriter{testErr}, Name: "csv", ErrorHandler: func(err error) { errs = append(errs, err) }}
+	sink.EmitEvent("myjob", "one", nil)
+	sink.EmitEvent("myjob", "two", nil)
+
+	assert.Equal(t, 2, len(errs))
+	assert.Equal(t, "csv: my test error", errs[0].Error())
+}
@@ -0,0 +1,177 @@
+package health
+
+import (
+	"bytes"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DedupSink collapses repeats of the same event or error into one line per Window.
+// The first occurrence of an event (same job, event, error message, and kvs) is forwarded to the wrapped Sink right away.
+// Repeats within Window of it are counted instead, and when the window is up, the event is forwarded once more with a "repeated" kv:
+//
+//	[2016-01-02T15:04:05Z]: job:sync event:fetch err:connection refused kvs:[host:db1 repeated:4211]
+//
+// Use it to keep a tight retry loop from flooding the logs during an outage.
+// Only events and errors are collapsed; timings, completions, gauges, and counts always go through.
+// Call Close when done to stop the background goroutine and forward the repeats still being counted.
+type DedupSink struct {
+	Sink   Sink
+	Window time.Duration
+
+	mutex     sync.Mutex
+	windows   map[string]*dedupWindow
+	doneChan  chan int
+	closeOnce sync.Once
+}
+
+// dedupWindow is an event that's been forwarded, and how many times it's been repeated since.
+type dedupWindow struct {
+	job     string
+	event   string
+	isErr   bool
+	err     error
+	kvs     map[string]string
+	start   time.Time
+	repeats int64
+}
+
+// NewDedupSink returns a DedupSink that collapses repeats within window. If window isn't positive, it's 10s.
+func NewDedupSink(sink Sink, window time.Duration) *DedupSink {
+	if window <= 0 {
+		window = 10 * time.Second
+	}
+
+	s := &DedupSink{
+		Sink:     sink,
+		Window:   window,
+		windows:  make(map[string]*dedupWindow),
+		doneChan: make(chan int),
+	}
+
+	go dedupSinkExpiryLoop(s)
+
+	return s
+}
+
+func (s *DedupSink) EmitEvent(job string, event string, kvs map[string]string) {
+	if s.first(job, event, false, nil, kvs) {
+		s.Sink.EmitEvent(job, event, kvs)
+	}
+}
+
+func (s *DedupSink) EmitEventErr(job string, event string, inputErr error, kvs map[string]string) {
+	if s.first(job, event, true, inputErr, kvs) {
+		s.Sink.EmitEventErr(job, event, inputErr, kvs)
+	}
+}
+
+func (s *DedupSink) EmitTiming(job string, event string, nanos int64, kvs map[string]string) {
+	s.Sink.EmitTiming(job, event, nanos, kvs)
+}
+
+func (s *DedupSink) EmitComplete(job string, status CompletionStatus, nanos int64, kvs map[string]string) {
+	s.Sink.EmitComplete(job, status, nanos, kvs)
+}
+
+func (s *DedupSink) EmitGauge(job string, event string, value float64, kvs map[string]string) {
+	s.Sink.EmitGauge(job, event, value, kvs)
+}
+
+func (s *DedupSink) EmitCount(job string, event string, delta int64, kvs map[string]string) {
+	s.Sink.EmitCount(job, event, delta, kvs)
+}
+
+// Flush flushes the wrapped Sink if it implements Flusher. Repeats still being counted aren't forwarded until their window is up.
+func (s *DedupSink) Flush() error {
+	return FlushAll(s.Sink)
+}
+
+// Close stops the background goroutine and forwards every repeat still being counted, without waiting for its window to be up.
+// Calling it more than once is fine.
+func (s *DedupSink) Close() error {
+	s.closeOnce.Do(func() {
+		s.doneChan <- 1
+		s.expire(time.Time{})
+	})
+	return nil
+}
+
+// first reports whether the event is the first of its window. If it isn't, it's counted as a repeat.
+// The window keeps a copy of kvs, since it's forwarded again after the caller may have changed it.
+func (s *DedupSink) first(job, event string, isErr bool, err error, kvs map[string]string) bool {
+	key := dedupKey(job, event, isErr, err, kvs)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if w, ok := s.windows[key]; ok {
+		w.repeats++
+		return false
+	}
+	s.windows[key] = &dedupWindow{job: job, event: event, isErr: isErr, err: err, kvs: copyKvs(kvs), start: now()}
+	return true
+}
+
+// expire ends the windows that started before cutoff (or all of them, if cutoff is zero), forwarding their repeats.
+func (s *DedupSink) expire(cutoff time.Time) {
+	var ended []*dedupWindow
+
+	s.mutex.Lock()
+	for key, w := range s.windows {
+		if cutoff.IsZero() || w.start.Before(cutoff) {
+			delete(s.windows, key)
+			if w.repeats > 0 {
+				ended = append(ended, w)
+			}
+		}
+	}
+	s.mutex.Unlock()
+
+	for _, w := range ended {
+		kvs := withKv(w.kvs, "repeated", strconv.FormatInt(w.repeats, 10))
+		if w.isErr {
+			s.Sink.EmitEventErr(w.job, w.event, w.err, kvs)
+		} else {
+			s.Sink.EmitEvent(w.job, w.event, kvs)
+		}
+	}
+}
+
+// dedupKey identifies an event by its job, event, error message, and kvs.
+func dedupKey(job, event string, isErr bool, err error, kvs map[string]string) string {
+	var b bytes.Buffer
+	b.WriteString(strconv.Quote(job))
+	b.WriteString(strconv.Quote(event))
+	if isErr {
+		b.WriteString(strconv.Quote(errorMessage(err)))
+	} else {
+		b.WriteRune('-')
+	}
+	for _, k := range sortedKeys(kvs, nil) {
+		b.WriteString(strconv.Quote(k))
+		b.WriteString(strconv.Quote(kvs[k]))
+	}
+	return b.String()
+}
+
+func dedupSinkExpiryLoop(sink *DedupSink) {
+	// Check twice per window, so a window is never forwarded more than half a window late,
+	// but no more than once a millisecond, however short the window.
+	tick := sink.Window / 2
+	if tick < time.Millisecond {
+		tick = time.Millisecond
+	}
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-sink.doneChan:
+			return
+		case <-ticker.C:
+			sink.expire(now().Add(-sink.Window))
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package health
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDedupSink(t *testing.T) {
+	setNowMock("2016-01-02T15:04:05Z")
+	defer resetNowMock()
+
+	inner := &MemorySink{}
+	sink := NewDedupSink(inner, time.Minute)
+	defer sink.Close()
+
+	for i := 0; i < 5; i++ {
+		sink.EmitEventErr("sync", "fetch", errors.New("connection refused"), map[string]string{"host": "db1"})
+	}
+	sink.EmitEventErr("sync", "fetch", errors.New("connection refused"), map[string]string{"host": "db2"})
+	sink.EmitEventErr("sync", "fetch", errors.New("timeout"), map[string]string{"host": "db1"})
+	sink.EmitEvent("sync", "retry", nil)
+	sink.EmitEvent("sync", "retry", nil)
+
+	// First occurrences go straight through.
+	events := inner.Events()
+	assert.Equal(t, 4, len(events))
+	assert.Equal(t, map[string]string{"host": "db1"}, events[0].Kvs)
+	assert.Equal(t, map[string]string{"host": "db2"}, events[1].Kvs)
+	assert.Equal(t, "timeout", events[2].Err.Error())
+	assert.Equal(t, "retry", events[3].Event)
+
+	// Windows that haven't ended yet are left alone.
+	sink.expire(now().Add(-time.Minute))
+	assert.Equal(t, 4, len(inner.Events()))
+
+	setNowMock("2016-01-02T15:05:06Z")
+	sink.expire(now().Add(-time.Minute))
+	events = inner.Events()
+	assert.Equal(t, 6, len(events))
+	repeats := map[string]string{}
+	for _, e := range events[4:] {
+		repeats[e.Event] = e.Kvs["repeated"]
+	}
+	assert.Equal(t, map[string]string{"fetch": "4", "retry": "1"}, repeats)
+
+	// After its window, an event goes straight through again.
+	sink.EmitEvent("sync", "retry", nil)
+	assert.Equal(t, 7, len(inner.Events()))
+}
+
+func TestDedupSinkNilError(t *testing.T) {
+	inner := &MemorySink{}
+	sink := NewDedupSink(inner, time.Hour)
+
+	sink.EmitEventErr("myjob", "myevent", nil, nil)
+	sink.EmitEventErr("myjob", "myevent", nil, nil)
+	sink.EmitEvent("myjob", "myevent", nil)
+	assert.NoError(t, sink.Close())
+
+	events := inner.Events()
+	assert.Equal(t, 3, len(events))
+	assert.Equal(t, KindEventErr, events[0].Kind)
+	assert.Equal(t, KindEvent, events[1].Kind)
+	assert.Equal(t, KindEventErr, events[2].Kind)
+	assert.Equal(t, "1", events[2].Kvs["repeated"])
+}
+
+func TestDedupSinkPassesThroughValues(t *testing.T) {
+	inner := &MemorySink{}
+	sink := NewDedupSink(inner, time.Minute)
+	defer sink.Close()
+
+	for i := 0; i < 2; i++ {
+		sink.EmitTiming("myjob", "mytiming", 1204, nil)
+		sink.EmitComplete("myjob", Success, 1204, nil)
+		sink.EmitGauge("myjob", "mygauge", 1, nil)
+		sink.EmitCount("myjob", "mycount", 1, nil)
+	}
+	assert.Equal(t, 8, len(inner.Events()))
+}
+
+func TestDedupSinkClose(t *testing.T) {
+	inner := &MemorySink{}
+	sink := NewDedupSink(inner, time.Hour)
+
+	sink.EmitEvent("myjob", "myevent", map[string]string{"a": "1"})
+	sink.EmitEvent("myjob", "myevent", map[string]string{"a": "1"})
+	sink.EmitEvent("myjob", "myevent", map[string]string{"a": "1"})
+	assert.Equal(t, 1, len(inner.Events()))
+
+	assert.NoError(t, sink.Close())
+	events := inner.Events()
+	assert.Equal(t, 2, len(events))
+	assert.Equal(t, map[string]string{"a": "1", "repeated": "2"}, events[1].Kvs)
+
+	// Closing again doesn't block or forward anything.
+	assert.NoError(t, sink.Close())
+	assert.Equal(t, 2, len(inner.Events()))
+}
+
+func TestDedupSinkCopiesKvs(t *testing.T) {
+	inner := &MemorySink{}
+	sink := NewDedupSink(inner, time.Hour)
+
+	kvs := map[string]string{"a": "1"}
+	sink.EmitEvent("myjob", "myevent", kvs)
+	sink.EmitEvent("myjob", "myevent", kvs)
+	kvs["a"] = "reused"
+
+	assert.NoError(t, sink.Close())
+	events := inner.Events()
+	assert.Equal(t, 2, len(events))
+	assert.Equal(t, map[string]string{"a": "1", "repeated": "1"}, events[1].Kvs)
+}
+
+func TestDedupSinkTinyWindow(t *testing.T) {
+	inner := &MemorySink{}
+	sink := NewDedupSink(inner, time.Nanosecond)
+
+	sink.EmitEvent("myjob", "myevent", nil)
+	time.Sleep(5 * time.Millisecond)
+	sink.EmitEvent("myjob", "myevent", nil)
+	assert.NoError(t, sink.Close())
+
+	// The second emit is forwarded either as a first, if the window expired in between, or as a repeat on Close.
+	assert.Equal(t, 2, len(inner.Events()))
+}
@@ -0,0 +1,88 @@
+package health
+
+import (
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// dropCounter counts the emits a wrapper sink drops and can summarize them to a Sink periodically.
+// It's embedded first in its sink so that its counters stay 64-bit aligned.
+type dropCounter struct {
+	// dropped and summarized are accessed atomically.
+	dropped    uint64
+	summarized uint64
+
+	summaryMutex    sync.Mutex
+	summarySink     Sink
+	summaryEvent    string
+	summaryInterval time.Duration
+	doneChan        chan int
+}
+
+// Dropped returns the number of emits dropped so far.
+func (c *dropCounter) Dropped() uint64 {
+	return atomic.LoadUint64(&c.dropped)
+}
+
+// Close stops the summaries started by SummarizeDrops, emitting a last one for anything dropped since the previous one.
+// It does nothing if there are no summaries.
+func (c *dropCounter) Close() error {
+	c.summaryMutex.Lock()
+	defer c.summaryMutex.Unlock()
+
+	if c.doneChan != nil {
+		c.doneChan <- 1
+		c.doneChan = nil
+		c.summarize()
+	}
+	return nil
+}
+
+func (c *dropCounter) drop() {
+	atomic.AddUint64(&c.dropped, 1)
+}
+
+// startSummaries emits event to sink every interval in which something was dropped, until Close. It does nothing if they're already started.
+func (c *dropCounter) startSummaries(sink Sink, event string, interval time.Duration) {
+	c.summaryMutex.Lock()
+	defer c.summaryMutex.Unlock()
+
+	if c.doneChan != nil || interval <= 0 {
+		return
+	}
+	c.summarySink = sink
+	c.summaryEvent = event
+	c.summaryInterval = interval
+	c.doneChan = make(chan int)
+	go dropCounterSummaryLoop(c, c.doneChan)
+}
+
+// summarize emits the number of emits dropped since the last summary, if there were any.
+func (c *dropCounter) summarize() {
+	dropped := atomic.LoadUint64(&c.dropped)
+	n := dropped - atomic.SwapUint64(&c.summarized, dropped)
+	if n == 0 {
+		return
+	}
+
+	c.summarySink.EmitEvent("health", c.summaryEvent, map[string]string{
+		"dropped":  strconv.FormatUint(n, 10),
+		"interval": c.summaryInterval.String(),
+	})
+}
+
+func dropCounterSummaryLoop(c *dropCounter, doneChan chan int) {
+	ticker := time.NewTicker(c.summaryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-doneChan:
+			return
+		case <-ticker.C:
+			c.summarize()
+		}
+	}
+}
@@ -0,0 +1,14 @@
+package health
+
+import (
+	"time"
+)
+
+// EmitSince emits a timing of how long it's been since start, saving the time.Since(start).Nanoseconds() dance:
+//
+//	start := time.Now()
+//	rows, err := db.Query(q)
+//	health.EmitSince(sink, "myjob", "query", start, nil)
+func EmitSince(sink Sink, job, event string, start time.Time, kvs map[string]string) {
+	sink.EmitTiming(job, event, now().Sub(start).Nanoseconds(), kvs)
+}
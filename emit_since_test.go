@@ -0,0 +1,25 @@
+package health
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEmitSince(t *testing.T) {
+	setNowMock("2011-09-09T23:36:13Z")
+	defer resetNowMock()
+
+	sink := &MemorySink{}
+	start := now().Add(-1500 * time.Millisecond)
+	EmitSince(sink, "myjob", "query", start, map[string]string{"table": "users"})
+
+	events := sink.Events()
+	assert.Equal(t, 1, len(events))
+	assert.Equal(t, KindTiming, events[0].Kind)
+	assert.Equal(t, "myjob", events[0].Job)
+	assert.Equal(t, "query", events[0].Event)
+	assert.Equal(t, int64(1500*time.Millisecond), events[0].Nanos)
+	assert.Equal(t, map[string]string{"table": "users"}, events[0].Kvs)
+}
@@ -15,17 +15,33 @@ type UnmutedError struct {
 }
 
 func (e *MutedError) Error() string {
-	return e.Err.Error()
+	return errorMessage(e.Err)
 }
 
 func (e *UnmutedError) Error() string {
-	return e.Err.Error()
+	return errorMessage(e.Err)
+}
+
+func (e *MutedError) Unwrap() error {
+	return e.Err
+}
+
+func (e *UnmutedError) Unwrap() error {
+	return e.Err
 }
 
 func Mute(err error) *MutedError {
 	return &MutedError{Err: err}
 }
 
+// errorMessage returns err.Error(), or "<nil>" (as fmt prints it) if err is nil, so that a nil error passed to EmitEventErr doesn't panic.
+func errorMessage(err error) string {
+	if err == nil {
+		return "<nil>"
+	}
+	return err.Error()
+}
+
 func wrapErr(err error) error {
 	switch err := err.(type) {
 	case *MutedError, *UnmutedError:
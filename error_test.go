@@ -1,6 +1,7 @@
 package health
 
 import (
+	"bytes"
 	"fmt"
 	"github.com/stretchr/testify/assert"
 	"testing"
@@ -35,6 +36,8 @@ func (s *testSink) EmitEventErr(job string, event string, inputErr error, kvs ma
 func (s *testSink) EmitTiming(job string, event string, nanos int64, kvs map[string]string) {}
 func (s *testSink) EmitComplete(job string, status CompletionStatus, nanos int64, kvs map[string]string) {
 }
+func (s *testSink) EmitGauge(job string, event string, value float64, kvs map[string]string) {}
+func (s *testSink) EmitCount(job string, event string, delta int64, kvs map[string]string)   {}
 
 func TestUnmutedErrors(t *testing.T) {
 	stream := NewStream()
@@ -110,3 +113,15 @@ func TestMutedErrors(t *testing.T) {
 	assert.NotNil(t, sink.LastErr)
 	assert.True(t, sink.LastErr.WasMuted)
 }
+
+func TestNilErrors(t *testing.T) {
+	var b bytes.Buffer
+	stream := NewStream()
+	stream.AddSink(&WriterSink{Writer: &b, NoTimestamp: true})
+	job := stream.NewJob("myjob")
+
+	err := job.EventErr("myevent", nil)
+	assert.Equal(t, "<nil>", err.Error())
+	assert.Equal(t, "<nil>", Mute(nil).Error())
+	assert.Equal(t, "job:myjob event:myevent err:<nil>\n", b.String())
+}
@@ -0,0 +1,95 @@
+package health
+
+import (
+	"sync"
+)
+
+// ExitStatusSink forwards every emit to the wrapped Sink unchanged, and keeps track of the most severe level it's seen,
+// so a command-line tool can exit non-zero if anything went wrong:
+//
+//	status := health.NewExitStatusSink(&health.WriterSink{Writer: os.Stderr})
+//	stream.AddSink(status)
+//	...
+//	os.Exit(status.SuggestedExitCode())
+//
+// An emit's level is its kvs["level"] if that's one of trace, debug, info, warn, error, or fatal (or err or warning),
+// and otherwise depends on the emit: EmitEventErr is error, EmitComplete is fatal for Panic and error for Error and Timeout,
+// and everything else is info.
+type ExitStatusSink struct {
+	Sink Sink
+
+	mutex   sync.Mutex
+	highest Level
+}
+
+// NewExitStatusSink returns an ExitStatusSink that forwards to sink.
+func NewExitStatusSink(sink Sink) *ExitStatusSink {
+	return &ExitStatusSink{Sink: sink}
+}
+
+func (s *ExitStatusSink) EmitEvent(job string, event string, kvs map[string]string) {
+	s.see(kvs, LevelInfo)
+	s.Sink.EmitEvent(job, event, kvs)
+}
+
+func (s *ExitStatusSink) EmitEventErr(job string, event string, inputErr error, kvs map[string]string) {
+	s.see(kvs, LevelError)
+	s.Sink.EmitEventErr(job, event, inputErr, kvs)
+}
+
+func (s *ExitStatusSink) EmitTiming(job string, event string, nanos int64, kvs map[string]string) {
+	s.see(kvs, LevelInfo)
+	s.Sink.EmitTiming(job, event, nanos, kvs)
+}
+
+func (s *ExitStatusSink) EmitComplete(job string, status CompletionStatus, nanos int64, kvs map[string]string) {
+	s.see(kvs, CompletionLevel(status))
+	s.Sink.EmitComplete(job, status, nanos, kvs)
+}
+
+func (s *ExitStatusSink) EmitGauge(job string, event string, value float64, kvs map[string]string) {
+	s.see(kvs, LevelInfo)
+	s.Sink.EmitGauge(job, event, value, kvs)
+}
+
+func (s *ExitStatusSink) EmitCount(job string, event string, delta int64, kvs map[string]string) {
+	s.see(kvs, LevelInfo)
+	s.Sink.EmitCount(job, event, delta, kvs)
+}
+
+// Flush flushes the wrapped Sink if it implements Flusher.
+func (s *ExitStatusSink) Flush() error {
+	return FlushAll(s.Sink)
+}
+
+// HighestLevel returns the most severe level seen so far: one of trace, debug, info, warn, error, or fatal.
+// It returns "" if nothing has been emitted.
+func (s *ExitStatusSink) HighestLevel() string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.highest.String()
+}
+
+// SuggestedExitCode returns 0 if nothing at error or above has been emitted, 1 if the highest level seen is error, and 2 if it's fatal.
+func (s *ExitStatusSink) SuggestedExitCode() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	switch {
+	case s.highest >= LevelFatal:
+		return 2
+	case s.highest >= LevelError:
+		return 1
+	}
+	return 0
+}
+
+// see records the level of an emit: kvs["level"] if it's a known level, or def.
+func (s *ExitStatusSink) see(kvs map[string]string, def Level) {
+	level := EmitLevel(kvs, def)
+
+	s.mutex.Lock()
+	if level > s.highest {
+		s.highest = level
+	}
+	s.mutex.Unlock()
+}
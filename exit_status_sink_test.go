@@ -0,0 +1,52 @@
+package health
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExitStatusSink(t *testing.T) {
+	mem := &MemorySink{}
+	sink := NewExitStatusSink(mem)
+	assert.Equal(t, "", sink.HighestLevel())
+	assert.Equal(t, 0, sink.SuggestedExitCode())
+
+	sink.EmitEvent("myjob", "started", nil)
+	sink.EmitTiming("myjob", "fetch", 1204, nil)
+	assert.Equal(t, "info", sink.HighestLevel())
+	assert.Equal(t, 0, sink.SuggestedExitCode())
+
+	sink.EmitEvent("myjob", "slow", map[string]string{"level": "WARNING"})
+	assert.Equal(t, "warn", sink.HighestLevel())
+	assert.Equal(t, 0, sink.SuggestedExitCode())
+
+	sink.EmitEventErr("myjob", "fetch", testErr, nil)
+	assert.Equal(t, "error", sink.HighestLevel())
+	assert.Equal(t, 1, sink.SuggestedExitCode())
+
+	// Less severe emits don't lower it.
+	sink.EmitComplete("myjob", Success, 1204, nil)
+	assert.Equal(t, "error", sink.HighestLevel())
+
+	sink.EmitComplete("myjob", Panic, 1204, nil)
+	assert.Equal(t, "fatal", sink.HighestLevel())
+	assert.Equal(t, 2, sink.SuggestedExitCode())
+
+	assert.Equal(t, 6, len(mem.Events()))
+}
+
+func TestExitStatusSinkLevelKv(t *testing.T) {
+	sink := NewExitStatusSink(&MemorySink{})
+
+	// A known level in kvs wins over the emit's default.
+	sink.EmitEventErr("myjob", "retry", testErr, map[string]string{"level": "warn"})
+	assert.Equal(t, "warn", sink.HighestLevel())
+	assert.Equal(t, 0, sink.SuggestedExitCode())
+
+	sink.EmitGauge("myjob", "queue", 3, map[string]string{"level": "err"})
+	assert.Equal(t, "error", sink.HighestLevel())
+
+	sink.EmitComplete("myjob", Timeout, 1204, nil)
+	assert.Equal(t, 1, sink.SuggestedExitCode())
+}
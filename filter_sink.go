@@ -0,0 +1,102 @@
+package health
+
+import (
+	"time"
+)
+
+// FilterSink forwards an emit to the wrapped Sink only if Keep returns true for it.
+// Use it to silence noisy jobs or events, eg from a library you don't control. Dropped counts the emits Keep turned away.
+type FilterSink struct {
+	dropCounter
+
+	Sink Sink
+
+	// Keep is called with the kind of emit (KindEvent, KindEventErr, KindTiming, KindComplete, KindGauge, or KindCount), the job, and the event.
+	// Completions don't have an event, so it's passed as "" for them.
+	Keep func(kind, job, event string) bool
+}
+
+func NewFilterSink(sink Sink, keep func(kind, job, event string) bool) *FilterSink {
+	return &FilterSink{Sink: sink, Keep: keep}
+}
+
+// NewJobAllowListSink returns a FilterSink that only forwards emits from the given jobs.
+func NewJobAllowListSink(sink Sink, jobs ...string) *FilterSink {
+	set := stringSet(jobs)
+	return NewFilterSink(sink, func(kind, job, event string) bool {
+		return set[job]
+	})
+}
+
+// NewJobDenyListSink returns a FilterSink that forwards emits from every job except the given ones.
+func NewJobDenyListSink(sink Sink, jobs ...string) *FilterSink {
+	set := stringSet(jobs)
+	return NewFilterSink(sink, func(kind, job, event string) bool {
+		return !set[job]
+	})
+}
+
+func (s *FilterSink) EmitEvent(job string, event string, kvs map[string]string) {
+	if s.keep(KindEvent, job, event) {
+		s.Sink.EmitEvent(job, event, kvs)
+	}
+}
+
+func (s *FilterSink) EmitEventErr(job string, event string, inputErr error, kvs map[string]string) {
+	if s.keep(KindEventErr, job, event) {
+		s.Sink.EmitEventErr(job, event, inputErr, kvs)
+	}
+}
+
+func (s *FilterSink) EmitTiming(job string, event string, nanos int64, kvs map[string]string) {
+	if s.keep(KindTiming, job, event) {
+		s.Sink.EmitTiming(job, event, nanos, kvs)
+	}
+}
+
+func (s *FilterSink) EmitComplete(job string, status CompletionStatus, nanos int64, kvs map[string]string) {
+	if s.keep(KindComplete, job, "") {
+		s.Sink.EmitComplete(job, status, nanos, kvs)
+	}
+}
+
+func (s *FilterSink) EmitGauge(job string, event string, value float64, kvs map[string]string) {
+	if s.keep(KindGauge, job, event) {
+		s.Sink.EmitGauge(job, event, value, kvs)
+	}
+}
+
+func (s *FilterSink) EmitCount(job string, event string, delta int64, kvs map[string]string) {
+	if s.keep(KindCount, job, event) {
+		s.Sink.EmitCount(job, event, delta, kvs)
+	}
+}
+
+// SummarizeDrops emits an event to the wrapped Sink every interval in which something was dropped, until Close is called:
+//
+//	[2016-01-02T15:04:05Z]: job:health event:filter_sink.dropped kvs:[dropped:412 interval:10s]
+func (s *FilterSink) SummarizeDrops(interval time.Duration) {
+	s.startSummaries(s.Sink, "filter_sink.dropped", interval)
+}
+
+// Flush flushes the wrapped Sink if it implements Flusher.
+func (s *FilterSink) Flush() error {
+	return FlushAll(s.Sink)
+}
+
+// keep calls Keep, counting the emit as dropped if it returns false.
+func (s *FilterSink) keep(kind, job, event string) bool {
+	if s.Keep(kind, job, event) {
+		return true
+	}
+	s.drop()
+	return false
+}
+
+func stringSet(strs []string) map[string]bool {
+	set := make(map[string]bool, len(strs))
+	for _, s := range strs {
+		set[s] = true
+	}
+	return set
+}
@@ -0,0 +1,87 @@
+package health
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestFilterSink(t *testing.T) {
+	inner := &MemorySink{}
+	sink := NewFilterSink(inner, func(kind, job, event string) bool {
+		return kind != KindTiming && event != "noisy"
+	})
+
+	sink.EmitEvent("myjob", "myevent", nil)
+	sink.EmitEvent("myjob", "noisy", nil)
+	sink.EmitEventErr("myjob", "noisy", testErr, nil)
+	sink.EmitTiming("myjob", "myevent", 34567890, nil)
+	sink.EmitGauge("myjob", "myevent", 3.14, nil)
+	sink.EmitComplete("myjob", Success, 34567890, nil)
+
+	var kinds []string
+	for _, e := range inner.Events() {
+		kinds = append(kinds, e.Kind)
+	}
+	assert.Equal(t, []string{KindEvent, KindGauge, KindComplete}, kinds)
+	assert.Equal(t, uint64(3), sink.Dropped())
+}
+
+func TestFilterSinkSummarizeDrops(t *testing.T) {
+	inner := &MemorySink{}
+	sink := NewJobDenyListSink(inner, "noisy")
+	sink.SummarizeDrops(10 * time.Second)
+	defer sink.Close()
+
+	sink.EmitEvent("noisy", "myevent", nil)
+	sink.EmitEvent("noisy", "myevent", nil)
+	sink.summarize()
+
+	// Nothing more was dropped, so there's nothing to summarize.
+	sink.EmitEvent("myjob", "myevent", nil)
+	sink.summarize()
+
+	events := inner.Events()
+	assert.Equal(t, 2, len(events))
+	assert.Equal(t, "filter_sink.dropped", events[0].Event)
+	assert.Equal(t, map[string]string{"dropped": "2", "interval": "10s"}, events[0].Kvs)
+	assert.Equal(t, "myjob", events[1].Job)
+}
+
+func TestFilterSinkCompletionEvent(t *testing.T) {
+	var gotEvent *string
+	sink := NewFilterSink(&countingSink{}, func(kind, job, event string) bool {
+		gotEvent = &event
+		return true
+	})
+
+	sink.EmitComplete("myjob", Success, 34567890, nil)
+	assert.Equal(t, "", *gotEvent)
+}
+
+func TestJobAllowListSink(t *testing.T) {
+	inner := &countingSink{}
+	sink := NewJobAllowListSink(inner, "a", "b")
+
+	sink.EmitEvent("a", "myevent", nil)
+	sink.EmitEvent("b", "myevent", nil)
+	sink.EmitEvent("c", "myevent", nil)
+	sink.EmitComplete("c", Success, 1, nil)
+
+	assert.Equal(t, 2, inner.Events)
+	assert.Equal(t, 0, inner.Completions)
+}
+
+func TestJobDenyListSink(t *testing.T) {
+	inner := &countingSink{}
+	sink := NewJobDenyListSink(inner, "noisylib")
+
+	sink.EmitEvent("myjob", "myevent", nil)
+	sink.EmitEvent("noisylib", "myevent", nil)
+	sink.EmitEventErr("noisylib", "myevent", testErr, nil)
+	sink.EmitTiming("myjob", "myevent", 1, nil)
+
+	assert.Equal(t, 1, inner.Events)
+	assert.Equal(t, 0, inner.EventErrs)
+	assert.Equal(t, 1, inner.Timings)
+}
@@ -0,0 +1,24 @@
+package health
+
+// Flusher is implemented by sinks that buffer what's emitted to them (eg, AsyncSink, GzipWriterSink, and batching StatsDSinks).
+// Flush blocks until everything emitted so far has been written out.
+type Flusher interface {
+	Flush() error
+}
+
+// FlushAll flushes each of sinks that implements Flusher, and skips the rest.
+// Every sink is flushed even if some fail; the first error is returned.
+// Call it before exiting to make sure nothing buffered is lost:
+//
+//	health.FlushAll(stream.Sinks...)
+func FlushAll(sinks ...Sink) error {
+	var firstErr error
+	for _, sink := range sinks {
+		if f, ok := sink.(Flusher); ok {
+			if err := f.Flush(); err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,71 @@
+package health
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// flushingSink is a countingSink that counts flushes, and fails them with err.
+type flushingSink struct {
+	countingSink
+	Flushes int
+	err     error
+}
+
+func (s *flushingSink) Flush() error {
+	s.Flushes++
+	return s.err
+}
+
+func TestFlushAll(t *testing.T) {
+	failing := &flushingSink{err: errors.New("flush failed")}
+	ok := &flushingSink{}
+
+	err := FlushAll(&countingSink{}, failing, ok)
+
+	assert.Equal(t, "flush failed", err.Error())
+	assert.Equal(t, 1, failing.Flushes)
+	assert.Equal(t, 1, ok.Flushes)
+	assert.NoError(t, FlushAll())
+}
+
+func TestWriterSinkFlush(t *testing.T) {
+	var b bytes.Buffer
+	w := bufio.NewWriter(&b)
+	sink := WriterSink{Writer: w}
+
+	sink.EmitEvent("myjob", "myevent", nil)
+	assert.Equal(t, 0, b.Len())
+
+	assert.NoError(t, sink.Flush())
+	assert.Regexp(t, basicEventRegexp, b.String())
+
+	// Unbuffered writers are left alone.
+	assert.NoError(t, (&WriterSink{Writer: &b}).Flush())
+}
+
+func TestAsyncSinkFlush(t *testing.T) {
+	inner := &flushingSink{}
+	sink := NewAsyncSink(inner, 100)
+	defer sink.Close()
+
+	for i := 0; i < 50; i++ {
+		sink.EmitEvent("myjob", "myevent", nil)
+	}
+	assert.NoError(t, sink.Flush())
+
+	assert.Equal(t, 50, inner.Events)
+	assert.Equal(t, 1, inner.Flushes)
+}
+
+func TestMultiSinkFlush(t *testing.T) {
+	a, b := &flushingSink{}, &flushingSink{}
+	sink := NewMultiSink(a, &countingSink{}, NewSamplingSink(b, 1))
+
+	assert.NoError(t, sink.Flush())
+	assert.Equal(t, 1, a.Flushes)
+	assert.Equal(t, 1, b.Flushes)
+}
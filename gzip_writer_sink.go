@@ -0,0 +1,41 @@
+package health
+
+import (
+	"compress/gzip"
+	"io"
+)
+
+// GzipWriterSink is a WriterSink that gzip-compresses everything before it reaches the underlying writer.
+// All of WriterSink's options are available on the embedded WriterSink.
+// Call Close when done to write the gzip footer. Close does not close the underlying writer.
+type GzipWriterSink struct {
+	WriterSink
+
+	gzipWriter *gzip.Writer
+}
+
+// NewGzipWriterSink makes a GzipWriterSink that writes to w. level is a compress/gzip level, eg gzip.DefaultCompression or gzip.BestSpeed.
+func NewGzipWriterSink(w io.Writer, level int) (*GzipWriterSink, error) {
+	gz, err := gzip.NewWriterLevel(w, level)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &GzipWriterSink{gzipWriter: gz}
+	s.Writer = gz
+	return s, nil
+}
+
+// Flush pushes everything written so far through to the underlying writer, so that it can be decompressed without waiting for Close.
+func (s *GzipWriterSink) Flush() error {
+	s.writeMutex.Lock()
+	defer s.writeMutex.Unlock()
+	return s.gzipWriter.Flush()
+}
+
+// Close flushes and writes the gzip footer. Emits after Close are reported to ErrorHandler, if set.
+func (s *GzipWriterSink) Close() error {
+	s.writeMutex.Lock()
+	defer s.writeMutex.Unlock()
+	return s.gzipWriter.Close()
+}
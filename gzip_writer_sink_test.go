@@ -0,0 +1,56 @@
+package health
+
+import (
+	"bytes"
+	"compress/gzip"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestGzipWriterSink(t *testing.T) {
+	var b bytes.Buffer
+	sink, err := NewGzipWriterSink(&b, gzip.BestSpeed)
+	assert.NoError(t, err)
+
+	sink.EmitEvent("myjob", "myevent", map[string]string{"wat": "ok", "another": "thing"})
+	sink.EmitTiming("myjob", "myevent", 34567890, nil)
+	assert.NoError(t, sink.Close())
+
+	r, err := gzip.NewReader(&b)
+	assert.NoError(t, err)
+	out, err := ioutil.ReadAll(r)
+	assert.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSuffix(string(out), "\n"), "\n")
+	assert.Equal(t, 2, len(lines))
+	assert.Equal(t, "another:thing wat:ok", kvsEventRegexp.FindStringSubmatch(lines[0])[3])
+	assert.Equal(t, "34 ms", basicTimingRegexp.FindStringSubmatch(lines[1])[3])
+}
+
+func TestGzipWriterSinkFlush(t *testing.T) {
+	var b bytes.Buffer
+	sink, err := NewGzipWriterSink(&b, gzip.DefaultCompression)
+	assert.NoError(t, err)
+
+	sink.EmitEvent("myjob", "first", nil)
+	assert.NoError(t, sink.Flush())
+
+	// Everything before the flush can be read back, even though the stream isn't finished:
+	r, err := gzip.NewReader(bytes.NewReader(b.Bytes()))
+	assert.NoError(t, err)
+	out, err := ioutil.ReadAll(r)
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+	assert.Equal(t, "first", basicEventRegexp.FindStringSubmatch(string(out))[2])
+
+	// And the stream is still valid after more writes and a Close:
+	sink.EmitEvent("myjob", "second", nil)
+	assert.NoError(t, sink.Close())
+	r, err = gzip.NewReader(&b)
+	assert.NoError(t, err)
+	out, err = ioutil.ReadAll(r)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, strings.Count(string(out), "\n"))
+}
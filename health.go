@@ -1,6 +1,7 @@
 package health
 
 import (
+	"fmt"
 	"time"
 )
 
@@ -38,6 +39,8 @@ const (
 	Panic
 	Error
 	Junk
+	Timeout
+	Cancelled
 )
 
 var completionStatusToString = map[CompletionStatus]string{
@@ -46,19 +49,71 @@ var completionStatusToString = map[CompletionStatus]string{
 	Panic:           "panic",
 	Error:           "error",
 	Junk:            "junk",
+	Timeout:         "timeout",
+	Cancelled:       "cancelled",
+}
+
+var stringToCompletionStatus = map[string]CompletionStatus{
+	"success":          Success,
+	"validation_error": ValidationError,
+	"panic":            Panic,
+	"error":            Error,
+	"junk":             Junk,
+	"timeout":          Timeout,
+	"cancelled":        Cancelled,
 }
 
 func (cs CompletionStatus) String() string {
 	return completionStatusToString[cs]
 }
 
+// ParseCompletionStatus returns the CompletionStatus whose String() is s, eg "validation_error" for ValidationError.
+func ParseCompletionStatus(s string) (CompletionStatus, error) {
+	cs, ok := stringToCompletionStatus[s]
+	if !ok {
+		return 0, fmt.Errorf("health: invalid completion status %q", s)
+	}
+	return cs, nil
+}
+
+// MarshalText implements encoding.TextMarshaler, so statuses are written as strings in JSON and config files.
+func (cs CompletionStatus) MarshalText() ([]byte, error) {
+	s, ok := completionStatusToString[cs]
+	if !ok {
+		return nil, fmt.Errorf("health: invalid completion status %d", int(cs))
+	}
+	return []byte(s), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler. See ParseCompletionStatus.
+func (cs *CompletionStatus) UnmarshalText(text []byte) error {
+	parsed, err := ParseCompletionStatus(string(text))
+	if err != nil {
+		return err
+	}
+	*cs = parsed
+	return nil
+}
+
 type Sink interface {
 	EmitEvent(job string, event string, kvs map[string]string)
 	EmitEventErr(job string, event string, err error, kvs map[string]string)
 	EmitTiming(job string, event string, nanoseconds int64, kvs map[string]string)
 	EmitComplete(job string, status CompletionStatus, nanoseconds int64, kvs map[string]string)
+	EmitGauge(job string, event string, value float64, kvs map[string]string)
+	EmitCount(job string, event string, delta int64, kvs map[string]string)
 }
 
+// Names for each kind of emit in the Sink interface, as recorded by MemorySink.
+const (
+	KindEvent    = "event"
+	KindEventErr = "event_err"
+	KindTiming   = "timing"
+	KindComplete = "complete"
+	KindGauge    = "gauge"
+	KindCount    = "count"
+)
+
 func NewStream() *Stream {
 	s := &Stream{}
 	s.Job = s.NewJob("general")
@@ -146,6 +201,35 @@ func (j *Job) TimingKv(eventName string, nanoseconds int64, kvs map[string]strin
 	}
 }
 
+func (j *Job) Gauge(eventName string, value float64) {
+	allKvs := j.mergedKeyValues(nil)
+	for _, sink := range j.Stream.Sinks {
+		sink.EmitGauge(j.JobName, eventName, value, allKvs)
+	}
+}
+
+func (j *Job) GaugeKv(eventName string, value float64, kvs map[string]string) {
+	allKvs := j.mergedKeyValues(kvs)
+	for _, sink := range j.Stream.Sinks {
+		sink.EmitGauge(j.JobName, eventName, value, allKvs)
+	}
+}
+
+// Count adds delta to the counter named eventName, eg job.Count("cache.miss", 1).
+func (j *Job) Count(eventName string, delta int64) {
+	allKvs := j.mergedKeyValues(nil)
+	for _, sink := range j.Stream.Sinks {
+		sink.EmitCount(j.JobName, eventName, delta, allKvs)
+	}
+}
+
+func (j *Job) CountKv(eventName string, delta int64, kvs map[string]string) {
+	allKvs := j.mergedKeyValues(kvs)
+	for _, sink := range j.Stream.Sinks {
+		sink.EmitCount(j.JobName, eventName, delta, allKvs)
+	}
+}
+
 func (j *Job) Complete(status CompletionStatus) {
 	allKvs := j.mergedKeyValues(nil)
 	for _, sink := range j.Stream.Sinks {
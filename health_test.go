@@ -0,0 +1,39 @@
+package health
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParseCompletionStatus(t *testing.T) {
+	for cs, s := range completionStatusToString {
+		parsed, err := ParseCompletionStatus(s)
+		assert.NoError(t, err)
+		assert.Equal(t, cs, parsed)
+	}
+
+	_, err := ParseCompletionStatus("Success")
+	assert.Error(t, err)
+	_, err = ParseCompletionStatus("")
+	assert.Error(t, err)
+}
+
+func TestCompletionStatusJSON(t *testing.T) {
+	type config struct {
+		Statuses []CompletionStatus `json:"statuses"`
+	}
+
+	b, err := json.Marshal(config{Statuses: []CompletionStatus{Success, Junk}})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"statuses":["success","junk"]}`, string(b))
+
+	var c config
+	assert.NoError(t, json.Unmarshal([]byte(`{"statuses":["panic","validation_error"]}`), &c))
+	assert.Equal(t, []CompletionStatus{Panic, ValidationError}, c.Statuses)
+
+	assert.Error(t, json.Unmarshal([]byte(`{"statuses":["nope"]}`), &c))
+
+	_, err = json.Marshal(CompletionStatus(42))
+	assert.Error(t, err)
+}
@@ -22,6 +22,8 @@ type Job struct {
 	CountPanic           int64  `json:"count_panic"`
 	CountError           int64  `json:"count_error"`
 	CountJunk            int64  `json:"count_junk"`
+	CountTimeout         int64  `json:"count_timeout"`
+	CountCancelled       int64  `json:"count_cancelled"`
 
 	NanosSum        int64   `json:"nanos_sum"`
 	NanosSumSquares float64 `json:"nanos_sum_squares"`
@@ -278,6 +280,12 @@ var jobSorters = map[string]By{
 	"count_junk": func(j1, j2 *Job) bool {
 		return j1.CountJunk > j2.CountJunk
 	},
+	"count_timeout": func(j1, j2 *Job) bool {
+		return j1.CountTimeout > j2.CountTimeout
+	},
+	"count_cancelled": func(j1, j2 *Job) bool {
+		return j1.CountCancelled > j2.CountCancelled
+	},
 	"total_time": func(j1, j2 *Job) bool {
 		return j1.NanosSum > j2.NanosSum
 	},
@@ -330,6 +338,8 @@ func filterJobs(overall *health.IntervalAggregation, sort string, limit int) []*
 			CountPanic:           j.CountPanic,
 			CountError:           j.CountError,
 			CountJunk:            j.CountJunk,
+			CountTimeout:         j.CountTimeout,
+			CountCancelled:       j.CountCancelled,
 			NanosSum:             j.NanosSum,
 			NanosSumSquares:      j.NanosSumSquares,
 			NanosMin:             j.NanosMin,
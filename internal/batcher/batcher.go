@@ -0,0 +1,111 @@
+// Package batcher runs the background loop shared by the sinks that send emits in batches.
+package batcher
+
+import (
+	"sync"
+	"time"
+)
+
+// Batcher collects items on a background goroutine and sends them in batches: when a batch is full, every interval, and on Flush and Close.
+// The batch itself belongs to the sink: add puts an item in it, and send sends it and empties it.
+// Both are only ever called from the Batcher's goroutine, so they don't need to lock anything.
+type Batcher struct {
+	add         func(item interface{}) bool
+	send        func() error
+	handleError func(error)
+
+	itemChan  chan interface{}
+	flushChan chan chan error
+	doneChan  chan int
+	closeOnce sync.Once
+}
+
+// New starts a Batcher that queues up to queueSize items before Add blocks.
+// add puts an item in the batch and reports whether the batch is now full. send sends the batch and empties it;
+// its errors are passed to handleError, except when it's called by Flush or Close, which return them instead.
+func New(queueSize int, interval time.Duration, add func(item interface{}) bool, send func() error, handleError func(error)) *Batcher {
+	b := &Batcher{
+		add:         add,
+		send:        send,
+		handleError: handleError,
+		itemChan:    make(chan interface{}, queueSize),
+		flushChan:   make(chan chan error),
+		doneChan:    make(chan int),
+	}
+
+	go batchLoop(b, interval)
+
+	return b
+}
+
+// Add queues item to be added to the batch.
+func (b *Batcher) Add(item interface{}) {
+	b.itemChan <- item
+}
+
+// Flush sends everything added so far, and returns the error from sending it. After Close, it does nothing.
+func (b *Batcher) Flush() error {
+	errChan := make(chan error)
+	select {
+	case b.flushChan <- errChan:
+		return <-errChan
+	case <-b.doneChan:
+		return nil
+	}
+}
+
+// Close sends everything added so far and stops the background goroutine. Nothing can be added afterwards.
+// Calling it more than once is fine.
+func (b *Batcher) Close() error {
+	var err error
+	b.closeOnce.Do(func() {
+		err = b.Flush()
+		close(b.doneChan)
+	})
+	return err
+}
+
+// addItem adds item to the batch, and if that fills it, sends it and returns the error from that.
+func (b *Batcher) addItem(item interface{}) error {
+	if b.add(item) {
+		return b.send()
+	}
+	return nil
+}
+
+func batchLoop(b *Batcher, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-b.doneChan:
+			return
+		case item := <-b.itemChan:
+			if err := b.addItem(item); err != nil {
+				b.handleError(err)
+			}
+		case <-ticker.C:
+			if err := b.send(); err != nil {
+				b.handleError(err)
+			}
+		case errChan := <-b.flushChan:
+			var firstErr error
+		drain:
+			for {
+				select {
+				case item := <-b.itemChan:
+					if err := b.addItem(item); err != nil && firstErr == nil {
+						firstErr = err
+					}
+				default:
+					break drain
+				}
+			}
+			if err := b.send(); err != nil && firstErr == nil {
+				firstErr = err
+			}
+			errChan <- firstErr
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package batcher
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testSink batches ints, BatchSize at a time, returning err from each send.
+type testSink struct {
+	BatchSize int
+
+	mutex   sync.Mutex
+	batch   []int
+	sent    [][]int
+	err     error
+	handled []error
+}
+
+func (s *testSink) add(item interface{}) bool {
+	s.batch = append(s.batch, item.(int))
+	return len(s.batch) >= s.BatchSize
+}
+
+func (s *testSink) send() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if len(s.batch) > 0 {
+		s.sent = append(s.sent, s.batch)
+	}
+	s.batch = nil
+	return s.err
+}
+
+func (s *testSink) handleError(err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.handled = append(s.handled, err)
+}
+
+func (s *testSink) Sent() [][]int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([][]int(nil), s.sent...)
+}
+
+func (s *testSink) Handled() []error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]error(nil), s.handled...)
+}
+
+func TestBatcher(t *testing.T) {
+	sink := &testSink{BatchSize: 2}
+	b := New(10, time.Hour, sink.add, sink.send, sink.handleError)
+
+	for i := 1; i <= 5; i++ {
+		b.Add(i)
+	}
+	assert.NoError(t, b.Flush())
+	assert.Equal(t, [][]int{{1, 2}, {3, 4}, {5}}, sink.Sent())
+
+	b.Add(6)
+	assert.NoError(t, b.Close())
+	assert.Equal(t, [][]int{{1, 2}, {3, 4}, {5}, {6}}, sink.Sent())
+
+	// Closing again, or flushing after closing, doesn't block.
+	assert.NoError(t, b.Close())
+	assert.NoError(t, b.Flush())
+}
+
+func TestBatcherInterval(t *testing.T) {
+	sink := &testSink{BatchSize: 100}
+	b := New(10, time.Millisecond, sink.add, sink.send, sink.handleError)
+	defer b.Close()
+
+	b.Add(1)
+	for i := 0; i < 100 && len(sink.Sent()) == 0; i++ {
+		time.Sleep(time.Millisecond)
+	}
+	assert.Equal(t, [][]int{{1}}, sink.Sent())
+}
+
+func TestBatcherErrors(t *testing.T) {
+	sink := &testSink{BatchSize: 1, err: errors.New("send failed")}
+	b := New(10, time.Hour, sink.add, sink.send, sink.handleError)
+
+	// A full batch is sent in the background, so its error is handled.
+	b.Add(1)
+	for i := 0; i < 100 && len(sink.Handled()) == 0; i++ {
+		time.Sleep(time.Millisecond)
+	}
+	assert.Equal(t, []error{sink.err}, sink.Handled())
+
+	// Flush and Close return their error instead.
+	assert.EqualError(t, b.Flush(), "send failed")
+	assert.EqualError(t, b.Close(), "send failed")
+	assert.Equal(t, 1, len(sink.Handled()))
+}
@@ -37,6 +37,8 @@ type JobAggregation struct {
 	CountPanic           int64 `json:"count_panic"`
 	CountError           int64 `json:"count_error"`
 	CountJunk            int64 `json:"count_junk"`
+	CountTimeout         int64 `json:"count_timeout"`
+	CountCancelled       int64 `json:"count_cancelled"`
 }
 
 type TimerAggregation struct {
@@ -159,5 +161,9 @@ func (a *JobAggregation) ingest(status CompletionStatus, nanos int64) {
 		a.CountError++
 	} else if status == Junk {
 		a.CountJunk++
+	} else if status == Timeout {
+		a.CountTimeout++
+	} else if status == Cancelled {
+		a.CountCancelled++
 	}
 }
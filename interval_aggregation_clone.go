@@ -52,6 +52,8 @@ func (ja *JobAggregation) Clone() *JobAggregation {
 		CountPanic:           ja.CountPanic,
 		CountError:           ja.CountError,
 		CountJunk:            ja.CountJunk,
+		CountTimeout:         ja.CountTimeout,
+		CountCancelled:       ja.CountCancelled,
 	}
 
 	dup.aggregationMaps = *ja.aggregationMaps.Clone()
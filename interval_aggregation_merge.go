@@ -24,6 +24,8 @@ func (intoJob *JobAggregation) merge(fromJob *JobAggregation) {
 	intoJob.CountPanic += fromJob.CountPanic
 	intoJob.CountError += fromJob.CountError
 	intoJob.CountJunk += fromJob.CountJunk
+	intoJob.CountTimeout += fromJob.CountTimeout
+	intoJob.CountCancelled += fromJob.CountCancelled
 }
 
 func (intoTa *TimerAggregation) merge(fromTa *TimerAggregation) {
@@ -18,6 +18,8 @@ const (
 	cmdKindEventErr
 	cmdKindTiming
 	cmdKindComplete
+	cmdKindGauge
+	cmdKindCount
 )
 
 type emitCmd struct {
@@ -27,6 +29,9 @@ type emitCmd struct {
 	Err    error
 	Nanos  int64
 	Status CompletionStatus
+	Value  float64
+	Delta  int64
+	Kvs    map[string]string
 }
 
 func NewJsonPollingSink(intervalDuration time.Duration, retain time.Duration) *JsonPollingSink {
@@ -64,6 +69,15 @@ func (s *JsonPollingSink) EmitComplete(job string, status CompletionStatus, nano
 	s.cmdChan <- &emitCmd{Kind: cmdKindComplete, Job: job, Status: status, Nanos: nanos}
 }
 
+func (s *JsonPollingSink) EmitGauge(job string, event string, value float64, kvs map[string]string) {
+	// no-op: gauges aren't aggregated yet.
+}
+
+// EmitCount adds delta to the event's count, as if it had been emitted delta times.
+func (s *JsonPollingSink) EmitCount(job string, event string, delta int64, kvs map[string]string) {
+	s.cmdChan <- &emitCmd{Kind: cmdKindCount, Job: job, Event: event, Delta: delta}
+}
+
 func (s *JsonPollingSink) GetMetrics() []*IntervalAggregation {
 	intervalsChan := make(chan []*IntervalAggregation)
 	s.intervalsChanChan <- intervalsChan
@@ -0,0 +1,186 @@
+package health
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// This sink writes one JSON object per line, which is handy for log aggregators.
+// Each line looks like:
+//
+//	{"time":"2015-03-11T22:53:22.115855203Z","job":"myjob","event":"myevent","kvs":{"foo":"bar"}}
+//
+// Timings and completions carry a "nanos" field, completions a "status" field, gauges a "gauge" field, counts a "count" field, and errors an "err" field.
+// Fields are always in the order above, and kvs keys are sorted, so the same emit renders to the same bytes in every process.
+// Gauges that aren't finite, which JSON has no number for, are written as the strings "NaN", "+Inf", and "-Inf".
+// Set FieldNames to match another schema, eg {"@timestamp":"...","message":"myevent",...}.
+type JsonSink struct {
+	io.Writer
+
+	// FieldNames renames the fields of each line. Its zero value keeps the names above.
+	FieldNames JsonSinkFieldNames
+
+	// writeMutex makes sure each line is written to Writer atomically with respect to other goroutines.
+	writeMutex sync.Mutex
+}
+
+// JsonSinkFieldNames are the keys a JsonSink writes each field under. An empty name keeps the field's usual name, and "-" leaves the field out.
+// Make sure the names are distinct; fields sharing a name are all written, and most JSON decoders keep only the last.
+type JsonSinkFieldNames struct {
+	Time   string
+	Job    string
+	Event  string
+	Err    string
+	Nanos  string
+	Status string
+	Gauge  string
+	Count  string
+	Kvs    string
+}
+
+// jsonSinkLine is one line. TypedKvs, if set, is written in place of Kvs.
+type jsonSinkLine struct {
+	Time   string            `json:"time"`
+	Job    string            `json:"job"`
+	Event  string            `json:"event,omitempty"`
+	Err    string            `json:"err,omitempty"`
+	Nanos  *int64            `json:"nanos,omitempty"`
+	Status string            `json:"status,omitempty"`
+	Gauge  *jsonFloat        `json:"gauge,omitempty"`
+	Count  *int64            `json:"count,omitempty"`
+	Kvs    map[string]string `json:"kvs,omitempty"`
+
+	TypedKvs map[string]interface{} `json:"-"`
+}
+
+// jsonFloat is a float64 that marshals to a string if it isn't finite, rather than failing to marshal.
+type jsonFloat float64
+
+func (f jsonFloat) MarshalJSON() ([]byte, error) {
+	v := float64(f)
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return json.Marshal(kvString(v))
+	}
+	return json.Marshal(v)
+}
+
+// jsonSinkTypedLine is a jsonSinkLine with typed kvs in place of its string ones.
+type jsonSinkTypedLine struct {
+	*jsonSinkLine
+	Kvs map[string]interface{} `json:"kvs,omitempty"`
+}
+
+func (s *JsonSink) EmitEvent(job string, event string, kvs map[string]string) {
+	s.write(&jsonSinkLine{Job: job, Event: event, Kvs: kvs})
+}
+
+// EmitEventKV is EmitEvent with typed kvs values, which are written as JSON strings, bools, numbers, or null.
+// Values of any other type are written as strings, rendered with fmt.Sprint.
+func (s *JsonSink) EmitEventKV(job string, event string, kvs map[string]interface{}) {
+	s.write(&jsonSinkLine{Job: job, Event: event, TypedKvs: jsonKvs(kvs)})
+}
+
+func (s *JsonSink) EmitEventErr(job string, event string, inputErr error, kvs map[string]string) {
+	s.write(&jsonSinkLine{Job: job, Event: event, Err: errorMessage(inputErr), Kvs: kvs})
+}
+
+func (s *JsonSink) EmitTiming(job string, event string, nanos int64, kvs map[string]string) {
+	s.write(&jsonSinkLine{Job: job, Event: event, Nanos: &nanos, Kvs: kvs})
+}
+
+func (s *JsonSink) EmitComplete(job string, status CompletionStatus, nanos int64, kvs map[string]string) {
+	s.write(&jsonSinkLine{Job: job, Status: status.String(), Nanos: &nanos, Kvs: kvs})
+}
+
+func (s *JsonSink) EmitGauge(job string, event string, value float64, kvs map[string]string) {
+	gauge := jsonFloat(value)
+	s.write(&jsonSinkLine{Job: job, Event: event, Gauge: &gauge, Kvs: kvs})
+}
+
+func (s *JsonSink) EmitCount(job string, event string, delta int64, kvs map[string]string) {
+	s.write(&jsonSinkLine{Job: job, Event: event, Count: &delta, Kvs: kvs})
+}
+
+func (s *JsonSink) write(line *jsonSinkLine) {
+	line.Time = now().UTC().Format(time.RFC3339Nano)
+
+	var b []byte
+	var err error
+	if s.FieldNames != (JsonSinkFieldNames{}) {
+		b, err = s.marshalRenamed(line)
+	} else if line.TypedKvs != nil {
+		b, err = json.Marshal(&jsonSinkTypedLine{jsonSinkLine: line, Kvs: line.TypedKvs})
+	} else {
+		b, err = json.Marshal(line)
+	}
+	if err != nil {
+		return
+	}
+	b = append(b, '\n')
+
+	s.writeMutex.Lock()
+	defer s.writeMutex.Unlock()
+	s.Writer.Write(b)
+}
+
+// marshalRenamed marshals line like json.Marshal would, but with the keys in FieldNames.
+func (s *JsonSink) marshalRenamed(line *jsonSinkLine) ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	var err error
+	field := func(name, def string, v interface{}) {
+		if err != nil || name == "-" {
+			return
+		}
+		if name == "" {
+			name = def
+		}
+		if b.Len() > 1 {
+			b.WriteByte(',')
+		}
+		var key, value []byte
+		if key, err = json.Marshal(name); err != nil {
+			return
+		}
+		if value, err = json.Marshal(v); err != nil {
+			return
+		}
+		b.Write(key)
+		b.WriteByte(':')
+		b.Write(value)
+	}
+
+	names := &s.FieldNames
+	field(names.Time, "time", line.Time)
+	field(names.Job, "job", line.Job)
+	if line.Event != "" {
+		field(names.Event, "event", line.Event)
+	}
+	if line.Err != "" {
+		field(names.Err, "err", line.Err)
+	}
+	if line.Nanos != nil {
+		field(names.Nanos, "nanos", *line.Nanos)
+	}
+	if line.Status != "" {
+		field(names.Status, "status", line.Status)
+	}
+	if line.Gauge != nil {
+		field(names.Gauge, "gauge", *line.Gauge)
+	}
+	if line.Count != nil {
+		field(names.Count, "count", *line.Count)
+	}
+	if line.TypedKvs != nil {
+		field(names.Kvs, "kvs", line.TypedKvs)
+	} else if len(line.Kvs) > 0 {
+		field(names.Kvs, "kvs", line.Kvs)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), err
+}
@@ -0,0 +1,254 @@
+package health
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"math"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func decodeJsonSinkLine(t *testing.T, b *bytes.Buffer) map[string]interface{} {
+	var m map[string]interface{}
+	assert.NoError(t, json.Unmarshal(b.Bytes(), &m))
+	assert.Equal(t, byte('\n'), b.Bytes()[b.Len()-1])
+	return m
+}
+
+func BenchmarkJsonSinkEmitEvent(b *testing.B) {
+	var by bytes.Buffer
+	someKvs := map[string]string{"foo": "bar", "qux": "dog"}
+	sink := JsonSink{Writer: &by}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		by.Reset()
+		sink.EmitEvent("myjob", "myevent", someKvs)
+	}
+}
+
+func TestJsonSinkEmitEvent(t *testing.T) {
+	var b bytes.Buffer
+	sink := JsonSink{Writer: &b}
+	sink.EmitEvent("myjob", "myevent", map[string]string{"wat": "ok", "another": "thing"})
+
+	m := decodeJsonSinkLine(t, &b)
+	assert.NotEmpty(t, m["time"])
+	assert.Equal(t, "myjob", m["job"])
+	assert.Equal(t, "myevent", m["event"])
+	assert.Equal(t, map[string]interface{}{"wat": "ok", "another": "thing"}, m["kvs"])
+	assert.NotContains(t, m, "nanos")
+	assert.NotContains(t, m, "err")
+}
+
+func TestJsonSinkEmitEventErr(t *testing.T) {
+	var b bytes.Buffer
+	sink := JsonSink{Writer: &b}
+	sink.EmitEventErr("myjob", "myevent", testErr, nil)
+
+	m := decodeJsonSinkLine(t, &b)
+	assert.Equal(t, "myjob", m["job"])
+	assert.Equal(t, "myevent", m["event"])
+	assert.Equal(t, testErr.Error(), m["err"])
+	assert.NotContains(t, m, "kvs")
+}
+
+func TestJsonSinkEmitEventErrNil(t *testing.T) {
+	var b bytes.Buffer
+	sink := JsonSink{Writer: &b}
+	sink.EmitEventErr("myjob", "myevent", nil, nil)
+
+	m := decodeJsonSinkLine(t, &b)
+	assert.Equal(t, "<nil>", m["err"])
+}
+
+func TestJsonSinkEmitTiming(t *testing.T) {
+	var b bytes.Buffer
+	sink := JsonSink{Writer: &b}
+	sink.EmitTiming("myjob", "myevent", 34567890, nil)
+
+	m := decodeJsonSinkLine(t, &b)
+	assert.Equal(t, "myevent", m["event"])
+	assert.Equal(t, float64(34567890), m["nanos"])
+}
+
+func TestJsonSinkEmitCount(t *testing.T) {
+	var b bytes.Buffer
+	sink := JsonSink{Writer: &b}
+	sink.EmitCount("myjob", "cache.miss", 3, nil)
+
+	m := decodeJsonSinkLine(t, &b)
+	assert.Equal(t, "cache.miss", m["event"])
+	assert.Equal(t, float64(3), m["count"])
+	assert.NotContains(t, m, "gauge")
+}
+
+func TestJsonSinkEmitGauge(t *testing.T) {
+	var b bytes.Buffer
+	sink := JsonSink{Writer: &b}
+	sink.EmitGauge("myjob", "myevent", 3.5, nil)
+
+	m := decodeJsonSinkLine(t, &b)
+	assert.Equal(t, "myevent", m["event"])
+	assert.Equal(t, 3.5, m["gauge"])
+	assert.NotContains(t, m, "nanos")
+}
+
+func TestJsonSinkEmitGaugeNonFinite(t *testing.T) {
+	var b bytes.Buffer
+	sink := JsonSink{Writer: &b}
+	sink.EmitGauge("myjob", "myevent", math.NaN(), nil)
+	assert.Equal(t, "NaN", decodeJsonSinkLine(t, &b)["gauge"])
+
+	b.Reset()
+	sink.EmitGauge("myjob", "myevent", math.Inf(-1), nil)
+	assert.Equal(t, "-Inf", decodeJsonSinkLine(t, &b)["gauge"])
+
+	b.Reset()
+	sink.FieldNames.Gauge = "value"
+	sink.EmitGauge("myjob", "myevent", math.Inf(1), nil)
+	assert.Equal(t, "+Inf", decodeJsonSinkLine(t, &b)["value"])
+
+	b.Reset()
+	sink.FieldNames = JsonSinkFieldNames{}
+	sink.EmitEventKV("myjob", "myevent", map[string]interface{}{"ratio": math.NaN(), "small": float32(0.5)})
+	kvs := decodeJsonSinkLine(t, &b)["kvs"].(map[string]interface{})
+	assert.Equal(t, "NaN", kvs["ratio"])
+	assert.Equal(t, 0.5, kvs["small"])
+}
+
+func TestJsonSinkEmitComplete(t *testing.T) {
+	for kind, kindStr := range completionStatusToString {
+		var b bytes.Buffer
+		sink := JsonSink{Writer: &b}
+		sink.EmitComplete("myjob", kind, 0, nil)
+
+		m := decodeJsonSinkLine(t, &b)
+		assert.Equal(t, "myjob", m["job"])
+		assert.Equal(t, kindStr, m["status"])
+		assert.Equal(t, float64(0), m["nanos"])
+		assert.NotContains(t, m, "event")
+	}
+}
+
+func TestJsonSinkEmitEventKV(t *testing.T) {
+	var b bytes.Buffer
+	sink := JsonSink{Writer: &b}
+	sink.EmitEventKV("myjob", "myevent", map[string]interface{}{
+		"count": 3, "ratio": 0.25, "ok": true, "name": "bob", "none": nil, "err": testErr, "ch": make(chan int),
+	})
+
+	m := decodeJsonSinkLine(t, &b)
+	assert.Equal(t, "myjob", m["job"])
+	assert.Equal(t, "myevent", m["event"])
+	kvs := m["kvs"].(map[string]interface{})
+	assert.Equal(t, float64(3), kvs["count"])
+	assert.Equal(t, 0.25, kvs["ratio"])
+	assert.Equal(t, true, kvs["ok"])
+	assert.Equal(t, "bob", kvs["name"])
+	assert.Contains(t, kvs, "none")
+	assert.Nil(t, kvs["none"])
+	assert.Equal(t, "my test error", kvs["err"])
+	assert.IsType(t, "", kvs["ch"])
+
+	b.Reset()
+	sink.EmitEventKV("myjob", "myevent", nil)
+	m = decodeJsonSinkLine(t, &b)
+	assert.NotContains(t, m, "kvs")
+}
+
+func TestJsonSinkFieldNames(t *testing.T) {
+	var b bytes.Buffer
+	sink := JsonSink{Writer: &b, FieldNames: JsonSinkFieldNames{Time: "@timestamp", Event: "message", Nanos: "-", Kvs: "labels"}}
+
+	sink.EmitTiming("myjob", "fetch", 1204, map[string]string{"wat": "ok"})
+	assert.Regexp(t, `^\{"@timestamp":"[^"]+","job":"myjob","message":"fetch","labels":\{"wat":"ok"\}\}\n$`, b.String())
+
+	b.Reset()
+	sink.EmitComplete("myjob", Error, 1204, nil)
+	m := decodeJsonSinkLine(t, &b)
+	assert.Equal(t, "myjob", m["job"])
+	assert.Equal(t, "error", m["status"])
+	assert.NotContains(t, m, "message")
+	assert.NotContains(t, m, "labels")
+	assert.NotContains(t, m, "nanos")
+
+	b.Reset()
+	sink.EmitEventErr("myjob", "fetch", testErr, nil)
+	sink.EmitGauge("myjob", "queue", 3.5, nil)
+	sink.EmitCount("myjob", "items", 2, nil)
+	sink.EmitEventKV("myjob", "typed", map[string]interface{}{"n": 1})
+	lines := bytes.Split(bytes.TrimSpace(b.Bytes()), []byte("\n"))
+	assert.Equal(t, 4, len(lines))
+	assert.Contains(t, string(lines[0]), `"err":"my test error"`)
+	assert.Contains(t, string(lines[1]), `"gauge":3.5`)
+	assert.Contains(t, string(lines[2]), `"count":2`)
+	assert.Contains(t, string(lines[3]), `"labels":{"n":1}`)
+}
+
+func TestJsonSinkStableOrder(t *testing.T) {
+	setNowMock("2011-09-09T23:36:13Z")
+	defer resetNowMock()
+
+	kvs := map[string]string{}
+	typedKvs := map[string]interface{}{}
+	for _, k := range []string{"zeta", "alpha", "mike", "bravo", "yankee", "charlie", "x-ray", "delta", "kilo", "echo"} {
+		kvs[k] = k + "_value"
+		typedKvs[k] = len(k)
+	}
+
+	sinks := []*JsonSink{
+		{},
+		{FieldNames: JsonSinkFieldNames{Time: "@timestamp", Kvs: "labels"}},
+	}
+	for _, sink := range sinks {
+		var first string
+		for i := 0; i < 100; i++ {
+			var b bytes.Buffer
+			sink.Writer = &b
+			sink.EmitEventErr("myjob", "myevent", testErr, kvs)
+			sink.EmitTiming("myjob", "myevent", 1204, kvs)
+			sink.EmitEventKV("myjob", "myevent", typedKvs)
+			if i == 0 {
+				first = b.String()
+				continue
+			}
+			assert.Equal(t, first, b.String())
+		}
+		assert.Contains(t, first, `{"alpha":"alpha_value","bravo":"bravo_value","charlie":"charlie_value","delta":"delta_value",`)
+		assert.Contains(t, first, `{"alpha":5,"bravo":5,"charlie":7,"delta":5,"echo":4,`)
+	}
+
+	// Fields come in a fixed order too.
+	var b bytes.Buffer
+	sink := JsonSink{Writer: &b}
+	sink.EmitEventErr("myjob", "myevent", testErr, map[string]string{"bravo": "b", "alpha": "a"})
+	assert.Equal(t, `{"time":"2011-09-09T23:36:13Z","job":"myjob","event":"myevent","err":"my test error","kvs":{"alpha":"a","bravo":"b"}}`+"\n", b.String())
+}
+
+func TestJsonSinkConcurrentWrites(t *testing.T) {
+	var w byteAtATimeWriter
+	sink := JsonSink{Writer: &w}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				sink.EmitEvent("myjob", "myevent", map[string]string{"wat": "ok"})
+			}
+		}()
+	}
+	wg.Wait()
+
+	lines := strings.Split(strings.TrimSuffix(string(w.buf), "\n"), "\n")
+	assert.Equal(t, 400, len(lines))
+	for _, line := range lines {
+		var m map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &m); err != nil {
+			t.Fatalf("garbled line: %q", line)
+		}
+	}
+}
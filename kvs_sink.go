@@ -0,0 +1,62 @@
+package health
+
+// KvsSink adds Kvs to every emit before forwarding it to the wrapped Sink. If a key is in both, the emitted kvs win.
+// Make one with WithKvs.
+type KvsSink struct {
+	Sink Sink
+	Kvs  map[string]string
+}
+
+// WithKvs returns a Sink that merges kvs into every emit to sink, eg to tag everything going to one sink with where it came from:
+//
+//	stream.AddSink(health.WithKvs(sink, map[string]string{"region": "us-east"}))
+//
+// kvs is copied, so changing it afterwards has no effect. Every emit gets a fresh map, so neither kvs nor the emitted kvs are modified.
+func WithKvs(sink Sink, kvs map[string]string) Sink {
+	copied := make(map[string]string, len(kvs))
+	for k, v := range kvs {
+		copied[k] = v
+	}
+	return &KvsSink{Sink: sink, Kvs: copied}
+}
+
+func (s *KvsSink) EmitEvent(job string, event string, kvs map[string]string) {
+	s.Sink.EmitEvent(job, event, s.merged(kvs))
+}
+
+func (s *KvsSink) EmitEventErr(job string, event string, inputErr error, kvs map[string]string) {
+	s.Sink.EmitEventErr(job, event, inputErr, s.merged(kvs))
+}
+
+func (s *KvsSink) EmitTiming(job string, event string, nanos int64, kvs map[string]string) {
+	s.Sink.EmitTiming(job, event, nanos, s.merged(kvs))
+}
+
+func (s *KvsSink) EmitComplete(job string, status CompletionStatus, nanos int64, kvs map[string]string) {
+	s.Sink.EmitComplete(job, status, nanos, s.merged(kvs))
+}
+
+func (s *KvsSink) EmitGauge(job string, event string, value float64, kvs map[string]string) {
+	s.Sink.EmitGauge(job, event, value, s.merged(kvs))
+}
+
+func (s *KvsSink) EmitCount(job string, event string, delta int64, kvs map[string]string) {
+	s.Sink.EmitCount(job, event, delta, s.merged(kvs))
+}
+
+// Flush flushes the wrapped Sink if it implements Flusher.
+func (s *KvsSink) Flush() error {
+	return FlushAll(s.Sink)
+}
+
+// merged returns a new map with Kvs and then kvs in it.
+func (s *KvsSink) merged(kvs map[string]string) map[string]string {
+	allKvs := make(map[string]string, len(s.Kvs)+len(kvs))
+	for k, v := range s.Kvs {
+		allKvs[k] = v
+	}
+	for k, v := range kvs {
+		allKvs[k] = v
+	}
+	return allKvs
+}
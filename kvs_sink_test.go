@@ -0,0 +1,46 @@
+package health
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithKvs(t *testing.T) {
+	inner := &MemorySink{}
+	shared := map[string]string{"region": "us-east", "wat": "shared"}
+	sink := WithKvs(inner, shared)
+
+	emitted := map[string]string{"wat": "emitted"}
+	sink.EmitEvent("myjob", "myevent", emitted)
+	sink.EmitEventErr("myjob", "myevent", testErr, nil)
+	sink.EmitTiming("myjob", "myevent", 1204, nil)
+	sink.EmitComplete("myjob", Success, 1204, nil)
+	sink.EmitGauge("myjob", "myevent", 1, nil)
+	sink.EmitCount("myjob", "myevent", 1, nil)
+
+	events := inner.Events()
+	assert.Equal(t, 6, len(events))
+
+	// The emitted kvs win.
+	assert.Equal(t, map[string]string{"region": "us-east", "wat": "emitted"}, events[0].Kvs)
+	for _, e := range events[1:] {
+		assert.Equal(t, map[string]string{"region": "us-east", "wat": "shared"}, e.Kvs)
+	}
+
+	// Neither map is modified, and changing the shared map afterwards has no effect.
+	assert.Equal(t, map[string]string{"wat": "emitted"}, emitted)
+	assert.Equal(t, map[string]string{"region": "us-east", "wat": "shared"}, shared)
+	shared["region"] = "eu-west"
+	events[1].Kvs["region"] = "mutated downstream"
+	sink.EmitEvent("myjob", "myevent", nil)
+	assert.Equal(t, "us-east", inner.Events()[6].Kvs["region"])
+}
+
+func TestWithKvsComposes(t *testing.T) {
+	inner := &MemorySink{}
+	sink := WithKvs(&MultiSink{Sinks: []Sink{WithKvs(inner, map[string]string{"a": "inner", "b": "inner"})}}, map[string]string{"a": "outer"})
+
+	sink.EmitEvent("myjob", "myevent", map[string]string{"c": "emitted"})
+	assert.Equal(t, map[string]string{"a": "outer", "b": "inner", "c": "emitted"}, inner.Events()[0].Kvs)
+}
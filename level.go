@@ -0,0 +1,68 @@
+package health
+
+import (
+	"strings"
+)
+
+// Level is how severe an emit is, for sinks that treat emits differently by severity, eg to pick a syslog severity or filter out noise.
+// Levels compare in order of severity, least to most. An emit's level is its kvs["level"] if that names one (see ParseLevel),
+// and otherwise depends on the emit: EmitEventErr is LevelError, EmitComplete is CompletionLevel of its status, and everything else is LevelInfo.
+type Level int
+
+const (
+	// LevelNone is below all of the other levels. No emit has it.
+	LevelNone Level = iota
+	LevelTrace
+	LevelDebug
+	LevelInfo
+	LevelWarn
+	LevelError
+	LevelFatal
+)
+
+var levelNames = []string{"", "trace", "debug", "info", "warn", "error", "fatal"}
+
+var levelsByName = map[string]Level{
+	"trace":   LevelTrace,
+	"debug":   LevelDebug,
+	"info":    LevelInfo,
+	"warn":    LevelWarn,
+	"warning": LevelWarn,
+	"error":   LevelError,
+	"err":     LevelError,
+	"fatal":   LevelFatal,
+}
+
+// String returns the level's name, eg "warn", or "" for LevelNone.
+func (l Level) String() string {
+	if l < LevelNone || int(l) >= len(levelNames) {
+		return ""
+	}
+	return levelNames[l]
+}
+
+// ParseLevel returns the level named name, ignoring case: one of trace, debug, info, warn (or warning), error (or err), or fatal.
+// ok is false if name isn't one of them.
+func ParseLevel(name string) (level Level, ok bool) {
+	level, ok = levelsByName[strings.ToLower(name)]
+	return level, ok
+}
+
+// EmitLevel returns the level named by kvs["level"], or def if it isn't a known level.
+func EmitLevel(kvs map[string]string, def Level) Level {
+	if level, ok := ParseLevel(kvs["level"]); ok {
+		return level
+	}
+	return def
+}
+
+// CompletionLevel returns the default level of a completion with status: fatal for Panic, error for Error and Timeout, and info otherwise.
+func CompletionLevel(status CompletionStatus) Level {
+	switch status {
+	case Panic:
+		return LevelFatal
+	case Error, Timeout:
+		return LevelError
+	}
+	return LevelInfo
+}
@@ -0,0 +1,90 @@
+package health
+
+// LevelRoute sends emits at or above MinLevel to Sink. See LevelRoutingSink.
+type LevelRoute struct {
+	MinLevel Level
+	Sink     Sink
+}
+
+// LevelRoutingSink forwards each emit to the Routes whose MinLevel its level meets, eg to send errors to stderr and everything to a file:
+//
+//	sink := health.NewLevelRoutingSink(
+//		health.LevelRoute{MinLevel: health.LevelError, Sink: &health.WriterSink{Writer: os.Stderr}},
+//		health.LevelRoute{MinLevel: health.LevelTrace, Sink: fileSink},
+//	)
+//	sink.All = true
+//
+// An emit's level is as described on Level, unless DefaultLevel is set. Emits that don't meet any route's MinLevel are dropped.
+type LevelRoutingSink struct {
+	Routes []LevelRoute
+
+	// All, if true, forwards each emit to every route it meets. Otherwise it only goes to the route with the highest MinLevel it meets,
+	// or the first of them if several have that MinLevel.
+	All bool
+
+	// DefaultLevel, if it isn't LevelNone, is the level of every emit whose kvs["level"] doesn't name one, whatever kind of emit it is.
+	DefaultLevel Level
+}
+
+func NewLevelRoutingSink(routes ...LevelRoute) *LevelRoutingSink {
+	return &LevelRoutingSink{Routes: routes}
+}
+
+func (s *LevelRoutingSink) EmitEvent(job string, event string, kvs map[string]string) {
+	s.route(s.level(kvs, LevelInfo), func(sink Sink) { sink.EmitEvent(job, event, kvs) })
+}
+
+func (s *LevelRoutingSink) EmitEventErr(job string, event string, inputErr error, kvs map[string]string) {
+	s.route(s.level(kvs, LevelError), func(sink Sink) { sink.EmitEventErr(job, event, inputErr, kvs) })
+}
+
+func (s *LevelRoutingSink) EmitTiming(job string, event string, nanos int64, kvs map[string]string) {
+	s.route(s.level(kvs, LevelInfo), func(sink Sink) { sink.EmitTiming(job, event, nanos, kvs) })
+}
+
+func (s *LevelRoutingSink) EmitComplete(job string, status CompletionStatus, nanos int64, kvs map[string]string) {
+	s.route(s.level(kvs, CompletionLevel(status)), func(sink Sink) { sink.EmitComplete(job, status, nanos, kvs) })
+}
+
+func (s *LevelRoutingSink) EmitGauge(job string, event string, value float64, kvs map[string]string) {
+	s.route(s.level(kvs, LevelInfo), func(sink Sink) { sink.EmitGauge(job, event, value, kvs) })
+}
+
+func (s *LevelRoutingSink) EmitCount(job string, event string, delta int64, kvs map[string]string) {
+	s.route(s.level(kvs, LevelInfo), func(sink Sink) { sink.EmitCount(job, event, delta, kvs) })
+}
+
+// Flush flushes each route's Sink that implements Flusher. See FlushAll.
+func (s *LevelRoutingSink) Flush() error {
+	sinks := make([]Sink, 0, len(s.Routes))
+	for _, r := range s.Routes {
+		sinks = append(sinks, r.Sink)
+	}
+	return FlushAll(sinks...)
+}
+
+// level returns the emit's level, with def being its level if kvs doesn't have one and DefaultLevel isn't set.
+func (s *LevelRoutingSink) level(kvs map[string]string, def Level) Level {
+	if s.DefaultLevel != LevelNone {
+		def = s.DefaultLevel
+	}
+	return EmitLevel(kvs, def)
+}
+
+func (s *LevelRoutingSink) route(level Level, emit func(sink Sink)) {
+	var best *LevelRoute
+	for i := range s.Routes {
+		r := &s.Routes[i]
+		if level < r.MinLevel {
+			continue
+		}
+		if s.All {
+			emit(r.Sink)
+		} else if best == nil || r.MinLevel > best.MinLevel {
+			best = r
+		}
+	}
+	if best != nil {
+		emit(best.Sink)
+	}
+}
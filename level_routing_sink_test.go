@@ -0,0 +1,64 @@
+package health
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func memorySinkEvents(s *MemorySink) []string {
+	var events []string
+	for _, e := range s.Events() {
+		events = append(events, e.Kind+":"+e.Event)
+	}
+	return events
+}
+
+func TestLevelRoutingSinkHighest(t *testing.T) {
+	errs, all := &MemorySink{}, &MemorySink{}
+	sink := NewLevelRoutingSink(LevelRoute{MinLevel: LevelError, Sink: errs}, LevelRoute{MinLevel: LevelTrace, Sink: all})
+
+	sink.EmitEvent("myjob", "leveled_debug", map[string]string{"level": "debug"})
+	sink.EmitEvent("myjob", "leveled_fatal", map[string]string{"level": "fatal"})
+	sink.EmitEvent("myjob", "unleveled", nil)
+	sink.EmitEventErr("myjob", "unleveled_err", testErr, nil)
+	sink.EmitEventErr("myjob", "leveled_warn_err", testErr, map[string]string{"level": "warn"})
+	sink.EmitComplete("myjob", Panic, 1, nil)
+	sink.EmitGauge("myjob", "gauge", 3.14, nil)
+
+	assert.Equal(t, []string{"event:leveled_fatal", "event_err:unleveled_err", "complete:"}, memorySinkEvents(errs))
+	assert.Equal(t, []string{"event:leveled_debug", "event:unleveled", "event_err:leveled_warn_err", "gauge:gauge"}, memorySinkEvents(all))
+}
+
+func TestLevelRoutingSinkAll(t *testing.T) {
+	errs, all := &MemorySink{}, &MemorySink{}
+	sink := NewLevelRoutingSink(LevelRoute{MinLevel: LevelError, Sink: errs}, LevelRoute{MinLevel: LevelInfo, Sink: all})
+	sink.All = true
+
+	sink.EmitEvent("myjob", "leveled_debug", map[string]string{"level": "debug"})
+	sink.EmitEvent("myjob", "leveled_error", map[string]string{"level": "error"})
+	sink.EmitEvent("myjob", "unleveled", nil)
+	sink.EmitEventErr("myjob", "unleveled_err", testErr, nil)
+
+	assert.Equal(t, []string{"event:leveled_error", "event_err:unleveled_err"}, memorySinkEvents(errs))
+	assert.Equal(t, []string{"event:leveled_error", "event:unleveled", "event_err:unleveled_err"}, memorySinkEvents(all))
+}
+
+func TestLevelRoutingSinkDefaultLevel(t *testing.T) {
+	errs, all := &MemorySink{}, &MemorySink{}
+	sink := NewLevelRoutingSink(LevelRoute{MinLevel: LevelError, Sink: errs}, LevelRoute{MinLevel: LevelWarn, Sink: all})
+	sink.DefaultLevel = LevelDebug
+
+	sink.EmitEventErr("myjob", "unleveled_err", testErr, nil)
+	sink.EmitEvent("myjob", "unleveled", nil)
+	sink.EmitEvent("myjob", "leveled_error", map[string]string{"level": "error"})
+
+	assert.Equal(t, []string{"event:leveled_error"}, memorySinkEvents(errs))
+	assert.Equal(t, 0, len(all.Events()))
+}
+
+func TestLevelRoutingSinkFlush(t *testing.T) {
+	flushed := &flushingSink{}
+	sink := NewLevelRoutingSink(LevelRoute{MinLevel: LevelTrace, Sink: &MemorySink{}}, LevelRoute{MinLevel: LevelError, Sink: flushed})
+	assert.NoError(t, sink.Flush())
+	assert.Equal(t, 1, flushed.Flushes)
+}
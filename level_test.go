@@ -0,0 +1,42 @@
+package health
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseLevel(t *testing.T) {
+	for name, want := range map[string]Level{"trace": LevelTrace, "DEBUG": LevelDebug, "info": LevelInfo, "Warning": LevelWarn, "err": LevelError, "fatal": LevelFatal} {
+		level, ok := ParseLevel(name)
+		assert.True(t, ok, name)
+		assert.Equal(t, want, level, name)
+	}
+
+	_, ok := ParseLevel("loud")
+	assert.False(t, ok)
+	_, ok = ParseLevel("")
+	assert.False(t, ok)
+}
+
+func TestLevelOrder(t *testing.T) {
+	assert.True(t, LevelFatal > LevelError)
+	assert.True(t, LevelError > LevelWarn)
+	assert.True(t, LevelTrace > LevelNone)
+}
+
+func TestLevelString(t *testing.T) {
+	assert.Equal(t, "warn", LevelWarn.String())
+	assert.Equal(t, "", LevelNone.String())
+	assert.Equal(t, "", Level(42).String())
+}
+
+func TestEmitLevel(t *testing.T) {
+	assert.Equal(t, LevelWarn, EmitLevel(map[string]string{"level": "WARN"}, LevelInfo))
+	assert.Equal(t, LevelInfo, EmitLevel(map[string]string{"level": "loud"}, LevelInfo))
+	assert.Equal(t, LevelError, EmitLevel(nil, LevelError))
+
+	assert.Equal(t, LevelFatal, CompletionLevel(Panic))
+	assert.Equal(t, LevelError, CompletionLevel(Timeout))
+	assert.Equal(t, LevelInfo, CompletionLevel(Success))
+}
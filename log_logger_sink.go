@@ -0,0 +1,40 @@
+package health
+
+import (
+	"bytes"
+	"log"
+)
+
+// LogLoggerSink is a WriterSink that writes each line through a *log.Logger, so lines share the logger's prefix, flags, and output.
+// All of WriterSink's options are available on the embedded WriterSink.
+//
+//	logger := log.New(os.Stderr, "myapp ", log.LstdFlags)
+//	stream.AddSink(health.NewLogLoggerSink(logger))
+//
+// The logger's Lshortfile and Llongfile flags would report a file in this package; set Caller instead.
+type LogLoggerSink struct {
+	WriterSink
+
+	Logger *log.Logger
+}
+
+// NewLogLoggerSink makes a LogLoggerSink that writes to logger.
+// If logger's flags already add a date or time, NoTimestamp is set so lines aren't timestamped twice. Set it back to false to keep both.
+func NewLogLoggerSink(logger *log.Logger) *LogLoggerSink {
+	s := &LogLoggerSink{Logger: logger}
+	s.Writer = logLoggerWriter{logger}
+	s.NoTimestamp = logger.Flags()&(log.Ldate|log.Ltime|log.Lmicroseconds) != 0
+	return s
+}
+
+// logLoggerWriter passes each line written to it to Logger.Output, minus the trailing newline that Output adds back.
+type logLoggerWriter struct {
+	logger *log.Logger
+}
+
+func (w logLoggerWriter) Write(p []byte) (int, error) {
+	if err := w.logger.Output(2, string(bytes.TrimSuffix(p, []byte("\n")))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
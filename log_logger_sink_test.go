@@ -0,0 +1,40 @@
+package health
+
+import (
+	"bytes"
+	"log"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogLoggerSink(t *testing.T) {
+	var b bytes.Buffer
+	sink := NewLogLoggerSink(log.New(&b, "myapp ", log.Ldate|log.Ltime))
+	assert.True(t, sink.NoTimestamp)
+
+	sink.EmitEvent("myjob", "myevent", map[string]string{"wat": "ok"})
+	sink.EmitComplete("myjob", Success, 1204, nil)
+	assert.Regexp(t, regexp.MustCompile(`^myapp \d{4}/\d\d/\d\d \d\d:\d\d:\d\d job:myjob event:myevent kvs:\[wat:ok\]\n`+
+		`myapp \d{4}/\d\d/\d\d \d\d:\d\d:\d\d job:myjob status:success time:1204 ns\n$`), b.String())
+}
+
+func TestLogLoggerSinkTimestamp(t *testing.T) {
+	var b bytes.Buffer
+	sink := NewLogLoggerSink(log.New(&b, "", 0))
+	assert.False(t, sink.NoTimestamp)
+
+	sink.EmitEvent("myjob", "myevent", nil)
+	result := basicEventRegexp.FindStringSubmatch(b.String())
+	assert.Equal(t, 3, len(result))
+	assert.Equal(t, "myevent", result[2])
+
+	// Keeping both timestamps is up to the caller.
+	b.Reset()
+	sink = NewLogLoggerSink(log.New(&b, "", log.LstdFlags))
+	sink.NoTimestamp = false
+	sink.Format = Logfmt
+	sink.EmitEvent("myjob", "myevent", nil)
+	assert.Regexp(t, regexp.MustCompile(`^\d{4}/\d\d/\d\d \d\d:\d\d:\d\d ts=\S+ job=myjob event=myevent\n$`), b.String())
+}
@@ -0,0 +1,88 @@
+package health
+
+import (
+	"sync"
+)
+
+// MemorySink records everything emitted to it. It's meant for tests that want to assert on what code emitted:
+//
+//	sink := &health.MemorySink{}
+//	stream.AddSink(sink)
+//	...
+//	assert.Equal(t, "my_event", sink.Events()[0].Event)
+//
+// It's safe for concurrent use.
+type MemorySink struct {
+	mutex  sync.Mutex
+	events []MemorySinkEvent
+}
+
+// MemorySinkEvent is one recorded call. Only the fields that apply to its Kind are set.
+type MemorySinkEvent struct {
+	Kind   string // One of KindEvent, KindEventErr, etc.
+	Job    string
+	Event  string
+	Err    error
+	Nanos  int64
+	Status CompletionStatus
+	Value  float64
+	Delta  int64
+	Kvs    map[string]string
+}
+
+func (s *MemorySink) EmitEvent(job string, event string, kvs map[string]string) {
+	s.record(MemorySinkEvent{Kind: KindEvent, Job: job, Event: event, Kvs: copyKvs(kvs)})
+}
+
+func (s *MemorySink) EmitEventErr(job string, event string, inputErr error, kvs map[string]string) {
+	s.record(MemorySinkEvent{Kind: KindEventErr, Job: job, Event: event, Err: inputErr, Kvs: copyKvs(kvs)})
+}
+
+func (s *MemorySink) EmitTiming(job string, event string, nanos int64, kvs map[string]string) {
+	s.record(MemorySinkEvent{Kind: KindTiming, Job: job, Event: event, Nanos: nanos, Kvs: copyKvs(kvs)})
+}
+
+func (s *MemorySink) EmitComplete(job string, status CompletionStatus, nanos int64, kvs map[string]string) {
+	s.record(MemorySinkEvent{Kind: KindComplete, Job: job, Status: status, Nanos: nanos, Kvs: copyKvs(kvs)})
+}
+
+func (s *MemorySink) EmitGauge(job string, event string, value float64, kvs map[string]string) {
+	s.record(MemorySinkEvent{Kind: KindGauge, Job: job, Event: event, Value: value, Kvs: copyKvs(kvs)})
+}
+
+func (s *MemorySink) EmitCount(job string, event string, delta int64, kvs map[string]string) {
+	s.record(MemorySinkEvent{Kind: KindCount, Job: job, Event: event, Delta: delta, Kvs: copyKvs(kvs)})
+}
+
+// Events returns a copy of everything recorded so far, oldest first.
+func (s *MemorySink) Events() []MemorySinkEvent {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]MemorySinkEvent(nil), s.events...)
+}
+
+// EventsOfKind is like Events, but only returns calls of the given kind, eg KindTiming.
+func (s *MemorySink) EventsOfKind(kind string) []MemorySinkEvent {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	var events []MemorySinkEvent
+	for _, e := range s.events {
+		if e.Kind == kind {
+			events = append(events, e)
+		}
+	}
+	return events
+}
+
+// Reset forgets everything recorded so far.
+func (s *MemorySink) Reset() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.events = nil
+}
+
+func (s *MemorySink) record(e MemorySinkEvent) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.events = append(s.events, e)
+}
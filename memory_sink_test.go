@@ -0,0 +1,58 @@
+package health
+
+import (
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+)
+
+func TestMemorySink(t *testing.T) {
+	sink := &MemorySink{}
+	stream := NewStream().AddSink(sink)
+	job := stream.NewJob("myjob")
+
+	kvs := map[string]string{"wat": "ok"}
+	job.EventKv("myevent", kvs)
+	kvs["wat"] = "changed"
+	job.Timing("mytiming", 1204000)
+	job.Gauge("mygauge", 3.5)
+
+	events := sink.Events()
+	assert.Equal(t, 3, len(events))
+	assert.Equal(t, MemorySinkEvent{Kind: KindEvent, Job: "myjob", Event: "myevent", Kvs: map[string]string{"wat": "ok"}}, events[0])
+	assert.Equal(t, MemorySinkEvent{Kind: KindTiming, Job: "myjob", Event: "mytiming", Nanos: 1204000}, events[1])
+	assert.Equal(t, MemorySinkEvent{Kind: KindGauge, Job: "myjob", Event: "mygauge", Value: 3.5}, events[2])
+
+	job.EventErr("myerr", testErr)
+	job.Complete(Error)
+
+	errs := sink.EventsOfKind(KindEventErr)
+	assert.Equal(t, 1, len(errs))
+	assert.Equal(t, "myerr", errs[0].Event)
+	assert.Equal(t, testErr, errs[0].Err.(*UnmutedError).Err)
+
+	completions := sink.EventsOfKind(KindComplete)
+	assert.Equal(t, 1, len(completions))
+	assert.Equal(t, Error, completions[0].Status)
+
+	sink.Reset()
+	assert.Equal(t, 0, len(sink.Events()))
+}
+
+func TestMemorySinkConcurrent(t *testing.T) {
+	sink := &MemorySink{}
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				emitOneOfEach(sink)
+				sink.Events()
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 600, len(sink.Events()))
+	assert.Equal(t, 100, len(sink.EventsOfKind(KindGauge)))
+}
@@ -0,0 +1,68 @@
+package health
+
+import (
+	"strings"
+	"unicode"
+)
+
+// SanitizeMetricName turns a job or event name into something every metrics backend accepts as (part of) a metric name:
+//   - it's lowercased,
+//   - every character other than a-z, 0-9, and '_' (including '.', '-', spaces, and non-ASCII letters) becomes '_',
+//   - runs of '_' are collapsed into one, and leading and trailing ones are trimmed,
+//   - a name that would start with a digit gets a leading '_', and an empty name becomes "_".
+//
+// For example, "API v2.Signup--Email" becomes "api_v2_signup_email". The result is the same for the same input, so it's safe to use in keys.
+// It can be used as a StatsDSink's SanitizationFunc.
+func SanitizeMetricName(s string) string {
+	var b strings.Builder
+	b.Grow(len(s) + 1)
+
+	pendingUnderscore := false
+	for _, r := range strings.ToLower(s) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			if pendingUnderscore && b.Len() > 0 {
+				b.WriteByte('_')
+			}
+			pendingUnderscore = false
+			if b.Len() == 0 && r <= '9' {
+				b.WriteByte('_')
+			}
+			b.WriteRune(r)
+		} else {
+			pendingUnderscore = true
+		}
+	}
+
+	if b.Len() == 0 {
+		return "_"
+	}
+	return b.String()
+}
+
+// SanitizeDottedMetricName is SanitizeMetricName for names made of '.'-separated parts, eg "signup.email_sent", for backends
+// that use '.' to namespace metrics, eg DogStatsD. Each part is sanitized on its own and empty parts are left out,
+// so "Signup..Email Sent" becomes "signup.email_sent".
+func SanitizeDottedMetricName(s string) string {
+	parts := strings.Split(s, ".")
+	kept := parts[:0]
+	for _, part := range parts {
+		if part != "" {
+			kept = append(kept, SanitizeMetricName(part))
+		}
+	}
+	if len(kept) == 0 {
+		return "_"
+	}
+	return strings.Join(kept, ".")
+}
+
+// SanitizeMetricTag replaces the characters that delimit DogStatsD packets ('|', '@', ',', '#', whitespace, and control characters) with '_',
+// so s can be used as a tag key or value. Unlike SanitizeMetricName, it keeps case, ':', and everything else.
+func SanitizeMetricTag(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '|' || r == '@' || r == ',' || r == '#' || unicode.IsSpace(r) || unicode.IsControl(r) {
+			return '_'
+		}
+		return r
+	}, s)
+}
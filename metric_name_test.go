@@ -0,0 +1,58 @@
+package health
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSanitizeMetricName(t *testing.T) {
+	cases := []struct {
+		in, out string
+	}{
+		{"signup", "signup"},
+		{"email_sent", "email_sent"},
+		{"EmailSent", "emailsent"},
+		{"API v2.Signup--Email", "api_v2_signup_email"},
+		{"db.query", "db_query"},
+		{"a__b", "a_b"},
+		{"__a__", "a"},
+		{"  spaced out  ", "spaced_out"},
+		{"stats|d:key", "stats_d_key"},
+		{"café", "caf"},
+		{"naïve name", "na_ve_name"},
+		{"404 errors", "_404_errors"},
+		{"...", "_"},
+		{"", "_"},
+		{"_", "_"},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.out, SanitizeMetricName(c.in), "input: %q", c.in)
+		// It's idempotent.
+		assert.Equal(t, c.out, SanitizeMetricName(c.out), "input: %q", c.out)
+	}
+}
+
+func TestSanitizeDottedMetricName(t *testing.T) {
+	cases := []struct {
+		in, out string
+	}{
+		{"signup.email_sent", "signup.email_sent"},
+		{"Signup..Email Sent", "signup.email_sent"},
+		{"completion.time", "completion.time"},
+		{"fetch|all", "fetch_all"},
+		{".404.", "_404"},
+		{"...", "_"},
+		{"", "_"},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.out, SanitizeDottedMetricName(c.in), "input: %q", c.in)
+		assert.Equal(t, c.out, SanitizeDottedMetricName(c.out), "input: %q", c.out)
+	}
+}
+
+func TestSanitizeMetricTag(t *testing.T) {
+	assert.Equal(t, "region:US_East", SanitizeMetricTag("region:US East"))
+	assert.Equal(t, "a_b_c_d_e_f", SanitizeMetricTag("a|b@c,d#e\tf"))
+	assert.Equal(t, "café", SanitizeMetricTag("café"))
+}
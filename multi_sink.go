@@ -0,0 +1,66 @@
+package health
+
+import (
+	"fmt"
+	"os"
+)
+
+// MultiSink forwards everything it receives to each of its Sinks, in order.
+// If one of the Sinks panics, the panic is reported to stderr and the remaining Sinks still receive the call.
+type MultiSink struct {
+	Sinks []Sink
+}
+
+func NewMultiSink(sinks ...Sink) *MultiSink {
+	return &MultiSink{Sinks: sinks}
+}
+
+func (s *MultiSink) EmitEvent(job string, event string, kvs map[string]string) {
+	for _, sink := range s.Sinks {
+		s.forward(func() { sink.EmitEvent(job, event, kvs) })
+	}
+}
+
+func (s *MultiSink) EmitEventErr(job string, event string, inputErr error, kvs map[string]string) {
+	for _, sink := range s.Sinks {
+		s.forward(func() { sink.EmitEventErr(job, event, inputErr, kvs) })
+	}
+}
+
+func (s *MultiSink) EmitTiming(job string, event string, nanos int64, kvs map[string]string) {
+	for _, sink := range s.Sinks {
+		s.forward(func() { sink.EmitTiming(job, event, nanos, kvs) })
+	}
+}
+
+func (s *MultiSink) EmitComplete(job string, status CompletionStatus, nanos int64, kvs map[string]string) {
+	for _, sink := range s.Sinks {
+		s.forward(func() { sink.EmitComplete(job, status, nanos, kvs) })
+	}
+}
+
+func (s *MultiSink) EmitGauge(job string, event string, value float64, kvs map[string]string) {
+	for _, sink := range s.Sinks {
+		s.forward(func() { sink.EmitGauge(job, event, value, kvs) })
+	}
+}
+
+func (s *MultiSink) EmitCount(job string, event string, delta int64, kvs map[string]string) {
+	for _, sink := range s.Sinks {
+		s.forward(func() { sink.EmitCount(job, event, delta, kvs) })
+	}
+}
+
+// Flush flushes each of the Sinks that implements Flusher. See FlushAll.
+func (s *MultiSink) Flush() error {
+	return FlushAll(s.Sinks...)
+}
+
+func (s *MultiSink) forward(emit func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			fmt.Fprintf(os.Stderr, "health.MultiSink: sink panicked: %v\n", r)
+		}
+	}()
+	emit()
+}
@@ -0,0 +1,76 @@
+package health
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// countingSink counts the calls it receives for each kind of emit.
+type countingSink struct {
+	Events, EventErrs, Timings, Completions, Gauges, Counts int
+}
+
+func (s *countingSink) EmitEvent(job string, event string, kvs map[string]string) { s.Events++ }
+func (s *countingSink) EmitEventErr(job string, event string, inputErr error, kvs map[string]string) {
+	s.EventErrs++
+}
+func (s *countingSink) EmitTiming(job string, event string, nanos int64, kvs map[string]string) {
+	s.Timings++
+}
+func (s *countingSink) EmitComplete(job string, status CompletionStatus, nanos int64, kvs map[string]string) {
+	s.Completions++
+}
+func (s *countingSink) EmitGauge(job string, event string, value float64, kvs map[string]string) {
+	s.Gauges++
+}
+func (s *countingSink) EmitCount(job string, event string, delta int64, kvs map[string]string) {
+	s.Counts++
+}
+
+type panickingSink struct{}
+
+func (s *panickingSink) EmitEvent(job string, event string, kvs map[string]string) { panic("event") }
+func (s *panickingSink) EmitEventErr(job string, event string, inputErr error, kvs map[string]string) {
+	panic("event_err")
+}
+func (s *panickingSink) EmitTiming(job string, event string, nanos int64, kvs map[string]string) {
+	panic("timing")
+}
+func (s *panickingSink) EmitComplete(job string, status CompletionStatus, nanos int64, kvs map[string]string) {
+	panic("complete")
+}
+func (s *panickingSink) EmitGauge(job string, event string, value float64, kvs map[string]string) {
+	panic("gauge")
+}
+func (s *panickingSink) EmitCount(job string, event string, delta int64, kvs map[string]string) {
+	panic("count")
+}
+
+func emitOneOfEach(sink Sink) {
+	sink.EmitEvent("myjob", "myevent", nil)
+	sink.EmitEventErr("myjob", "myevent", testErr, nil)
+	sink.EmitTiming("myjob", "myevent", 1204000, nil)
+	sink.EmitComplete("myjob", Success, 1204000, nil)
+	sink.EmitGauge("myjob", "myevent", 3.14, nil)
+	sink.EmitCount("myjob", "myevent", 2, nil)
+}
+
+func TestMultiSink(t *testing.T) {
+	a, b := &countingSink{}, &countingSink{}
+	sink := NewMultiSink(a, b)
+	emitOneOfEach(sink)
+
+	for _, child := range []*countingSink{a, b} {
+		assert.Equal(t, countingSink{1, 1, 1, 1, 1, 1}, *child)
+	}
+}
+
+func TestMultiSinkPanickingChild(t *testing.T) {
+	a, b := &countingSink{}, &countingSink{}
+	sink := NewMultiSink(a, &panickingSink{}, b)
+	emitOneOfEach(sink)
+
+	for _, child := range []*countingSink{a, b} {
+		assert.Equal(t, countingSink{1, 1, 1, 1, 1, 1}, *child)
+	}
+}
@@ -0,0 +1,14 @@
+package health
+
+// NopSink discards everything. It's handy for turning off instrumentation in tests and benchmarks.
+type NopSink struct{}
+
+var _ Sink = NopSink{}
+
+func (s NopSink) EmitEvent(job string, event string, kvs map[string]string)                    {}
+func (s NopSink) EmitEventErr(job string, event string, inputErr error, kvs map[string]string) {}
+func (s NopSink) EmitTiming(job string, event string, nanos int64, kvs map[string]string)      {}
+func (s NopSink) EmitComplete(job string, status CompletionStatus, nanos int64, kvs map[string]string) {
+}
+func (s NopSink) EmitGauge(job string, event string, value float64, kvs map[string]string) {}
+func (s NopSink) EmitCount(job string, event string, delta int64, kvs map[string]string)   {}
@@ -0,0 +1,130 @@
+package health
+
+import (
+	"sync"
+	"time"
+)
+
+// RateLimiter decides whether each emit may go through.
+type RateLimiter interface {
+	Allow() bool
+}
+
+// TokenBucket is a RateLimiter that allows Rate emits per second on average, with bursts of up to Burst.
+// The bucket starts full.
+type TokenBucket struct {
+	Rate  float64
+	Burst int
+
+	// Now is the bucket's clock. If nil, the current time is used. Set it to a fake clock in tests.
+	Now func() time.Time
+
+	mutex  sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+func NewTokenBucket(rate float64, burst int) *TokenBucket {
+	return &TokenBucket{Rate: rate, Burst: burst}
+}
+
+func (b *TokenBucket) Allow() bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	var t time.Time
+	if b.Now != nil {
+		t = b.Now()
+	} else {
+		t = now()
+	}
+
+	if b.last.IsZero() {
+		b.tokens = float64(b.Burst)
+	} else if elapsed := t.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens += elapsed * b.Rate
+		if b.tokens > float64(b.Burst) {
+			b.tokens = float64(b.Burst)
+		}
+	}
+	b.last = t
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// RateLimitSink forwards emits to the wrapped Sink only as fast as Limiter allows, and drops the rest.
+// Use it to keep an error storm from flooding a downstream like syslog.
+//
+// If it's made with a summaryInterval, it emits an event to the wrapped Sink every interval in which something was dropped:
+//
+//	[2016-01-02T15:04:05Z]: job:health event:rate_limit_sink.dropped kvs:[dropped:412 interval:10s]
+type RateLimitSink struct {
+	dropCounter
+
+	Sink    Sink
+	Limiter RateLimiter
+}
+
+// NewRateLimitSink returns a RateLimitSink that allows eventsPerSecond emits per second, with bursts of up to burst.
+// If summaryInterval is non-zero, drops are summarized every summaryInterval until Close is called.
+func NewRateLimitSink(sink Sink, eventsPerSecond float64, burst int, summaryInterval time.Duration) *RateLimitSink {
+	s := &RateLimitSink{
+		Sink:    sink,
+		Limiter: NewTokenBucket(eventsPerSecond, burst),
+	}
+	s.startSummaries(sink, "rate_limit_sink.dropped", summaryInterval)
+	return s
+}
+
+func (s *RateLimitSink) EmitEvent(job string, event string, kvs map[string]string) {
+	if s.allow() {
+		s.Sink.EmitEvent(job, event, kvs)
+	}
+}
+
+func (s *RateLimitSink) EmitEventErr(job string, event string, inputErr error, kvs map[string]string) {
+	if s.allow() {
+		s.Sink.EmitEventErr(job, event, inputErr, kvs)
+	}
+}
+
+func (s *RateLimitSink) EmitTiming(job string, event string, nanos int64, kvs map[string]string) {
+	if s.allow() {
+		s.Sink.EmitTiming(job, event, nanos, kvs)
+	}
+}
+
+func (s *RateLimitSink) EmitComplete(job string, status CompletionStatus, nanos int64, kvs map[string]string) {
+	if s.allow() {
+		s.Sink.EmitComplete(job, status, nanos, kvs)
+	}
+}
+
+func (s *RateLimitSink) EmitGauge(job string, event string, value float64, kvs map[string]string) {
+	if s.allow() {
+		s.Sink.EmitGauge(job, event, value, kvs)
+	}
+}
+
+func (s *RateLimitSink) EmitCount(job string, event string, delta int64, kvs map[string]string) {
+	if s.allow() {
+		s.Sink.EmitCount(job, event, delta, kvs)
+	}
+}
+
+// Flush flushes the wrapped Sink if it implements Flusher.
+func (s *RateLimitSink) Flush() error {
+	return FlushAll(s.Sink)
+}
+
+func (s *RateLimitSink) allow() bool {
+	if s.Limiter.Allow() {
+		return true
+	}
+	s.drop()
+	return false
+}
@@ -0,0 +1,92 @@
+package health
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+// fakeClock is a clock for TokenBucket.Now that only moves when told to.
+type fakeClock struct {
+	t time.Time
+}
+
+func (c *fakeClock) Now() time.Time          { return c.t }
+func (c *fakeClock) Advance(d time.Duration) { c.t = c.t.Add(d) }
+func newFakeClock() *fakeClock               { return &fakeClock{t: time.Date(2016, 1, 2, 15, 4, 5, 0, time.UTC)} }
+
+func TestTokenBucket(t *testing.T) {
+	clock := newFakeClock()
+	bucket := &TokenBucket{Rate: 2, Burst: 3, Now: clock.Now}
+
+	// Starts full.
+	assert.True(t, bucket.Allow())
+	assert.True(t, bucket.Allow())
+	assert.True(t, bucket.Allow())
+	assert.False(t, bucket.Allow())
+
+	// Refills at Rate per second.
+	clock.Advance(500 * time.Millisecond)
+	assert.True(t, bucket.Allow())
+	assert.False(t, bucket.Allow())
+
+	// Never holds more than Burst.
+	clock.Advance(time.Hour)
+	assert.True(t, bucket.Allow())
+	assert.True(t, bucket.Allow())
+	assert.True(t, bucket.Allow())
+	assert.False(t, bucket.Allow())
+}
+
+func TestRateLimitSink(t *testing.T) {
+	clock := newFakeClock()
+	inner := &countingSink{}
+	sink := NewRateLimitSink(inner, 10, 6, 0)
+	sink.Limiter = &TokenBucket{Rate: 10, Burst: 6, Now: clock.Now}
+
+	for i := 0; i < 4; i++ {
+		emitOneOfEach(sink)
+	}
+
+	assert.Equal(t, 6, inner.Events+inner.EventErrs+inner.Timings+inner.Completions+inner.Gauges+inner.Counts)
+	assert.Equal(t, uint64(18), sink.Dropped())
+
+	clock.Advance(time.Second)
+	emitOneOfEach(sink)
+	assert.Equal(t, 12, inner.Events+inner.EventErrs+inner.Timings+inner.Completions+inner.Gauges+inner.Counts)
+	assert.Equal(t, uint64(18), sink.Dropped())
+}
+
+func TestRateLimitSinkSummary(t *testing.T) {
+	inner := &MemorySink{}
+	sink := NewRateLimitSink(inner, 0, 1, 10*time.Second)
+	defer sink.Close()
+
+	for i := 0; i < 413; i++ {
+		sink.EmitEvent("myjob", "myevent", nil)
+	}
+	sink.summarize()
+
+	// Nothing more was dropped, so there's nothing to summarize.
+	sink.summarize()
+
+	events := inner.Events()
+	assert.Equal(t, 2, len(events))
+	assert.Equal(t, "myevent", events[0].Event)
+	assert.Equal(t, "health", events[1].Job)
+	assert.Equal(t, "rate_limit_sink.dropped", events[1].Event)
+	assert.Equal(t, map[string]string{"dropped": "412", "interval": "10s"}, events[1].Kvs)
+}
+
+func TestRateLimitSinkClose(t *testing.T) {
+	inner := &MemorySink{}
+	sink := NewRateLimitSink(inner, 0, 0, time.Hour)
+
+	sink.EmitEvent("myjob", "myevent", nil)
+	assert.NoError(t, sink.Close())
+	assert.NoError(t, sink.Close())
+
+	events := inner.Events()
+	assert.Equal(t, 1, len(events))
+	assert.Equal(t, "1", events[0].Kvs["dropped"])
+}
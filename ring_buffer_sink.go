@@ -0,0 +1,148 @@
+package health
+
+import (
+	"io"
+	"strings"
+	"sync"
+)
+
+// RingBufferSink is a WriterSink that keeps the last N lines in memory instead of writing them anywhere, like a flight recorder:
+// emit everything to it, and Dump it when something goes wrong.
+// All of WriterSink's options are available on the embedded WriterSink. Each emit is O(1), and memory is bounded by N lines.
+//
+//	recorder := health.NewRingBufferSink(1000)
+//	stream.AddSink(recorder)
+//	...
+//	if err != nil {
+//		recorder.Dump(os.Stderr)
+//	}
+//
+// Each line is kept with its job and level (see Handler), which are only known for lines emitted through the Sink methods.
+// Lines written with WriterSink's other methods, eg EmitGaugeUnit, have neither.
+type RingBufferSink struct {
+	WriterSink
+
+	ring *lineRing
+
+	// emitMutex keeps the job and level set by an emit paired with the line it writes.
+	emitMutex sync.Mutex
+}
+
+// NewRingBufferSink makes a RingBufferSink that keeps the last n lines. If n isn't positive, it keeps 1000.
+func NewRingBufferSink(n int) *RingBufferSink {
+	if n <= 0 {
+		n = 1000
+	}
+	ring := &lineRing{entries: make([]ringEntry, n)}
+	s := &RingBufferSink{ring: ring}
+	s.Writer = ring
+	return s
+}
+
+func (s *RingBufferSink) EmitEvent(job string, event string, kvs map[string]string) {
+	s.emit(job, EmitLevel(kvs, LevelInfo), func() { s.WriterSink.EmitEvent(job, event, kvs) })
+}
+
+func (s *RingBufferSink) EmitEventErr(job string, event string, inputErr error, kvs map[string]string) {
+	s.emit(job, EmitLevel(kvs, LevelError), func() { s.WriterSink.EmitEventErr(job, event, inputErr, kvs) })
+}
+
+func (s *RingBufferSink) EmitTiming(job string, event string, nanos int64, kvs map[string]string) {
+	s.emit(job, EmitLevel(kvs, LevelInfo), func() { s.WriterSink.EmitTiming(job, event, nanos, kvs) })
+}
+
+func (s *RingBufferSink) EmitComplete(job string, status CompletionStatus, nanos int64, kvs map[string]string) {
+	s.emit(job, EmitLevel(kvs, CompletionLevel(status)), func() { s.WriterSink.EmitComplete(job, status, nanos, kvs) })
+}
+
+func (s *RingBufferSink) EmitGauge(job string, event string, value float64, kvs map[string]string) {
+	s.emit(job, EmitLevel(kvs, LevelInfo), func() { s.WriterSink.EmitGauge(job, event, value, kvs) })
+}
+
+func (s *RingBufferSink) EmitCount(job string, event string, delta int64, kvs map[string]string) {
+	s.emit(job, EmitLevel(kvs, LevelInfo), func() { s.WriterSink.EmitCount(job, event, delta, kvs) })
+}
+
+// Lines returns the lines being kept, oldest first, without their line endings.
+func (s *RingBufferSink) Lines() []string {
+	entries := s.ring.snapshot()
+	lines := make([]string, len(entries))
+	for i, e := range entries {
+		lines[i] = strings.TrimRight(e.line, "\r\n")
+	}
+	return lines
+}
+
+// Dump writes the lines being kept to w, oldest first, as they were rendered.
+func (s *RingBufferSink) Dump(w io.Writer) error {
+	for _, e := range s.ring.snapshot() {
+		if _, err := io.WriteString(w, e.line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// emit tags the line written by write with job and level.
+func (s *RingBufferSink) emit(job string, level Level, write func()) {
+	s.emitMutex.Lock()
+	defer s.emitMutex.Unlock()
+
+	s.ring.tag(job, level)
+	write()
+	s.ring.tag("", LevelNone)
+}
+
+// ringEntry is a kept line and what it's about. job is "" and level is LevelNone if they aren't known.
+type ringEntry struct {
+	line  string
+	job   string
+	level Level
+}
+
+// lineRing is an io.Writer that keeps the last len(entries) writes, each tagged with the job and level set by tag.
+// WriterSink writes one line per Write.
+type lineRing struct {
+	mutex   sync.Mutex
+	entries []ringEntry
+	next    int
+	full    bool
+
+	job   string
+	level Level
+}
+
+func (r *lineRing) tag(job string, level Level) {
+	r.mutex.Lock()
+	r.job = job
+	r.level = level
+	r.mutex.Unlock()
+}
+
+func (r *lineRing) Write(p []byte) (int, error) {
+	line := string(p)
+
+	r.mutex.Lock()
+	r.entries[r.next] = ringEntry{line: line, job: r.job, level: r.level}
+	r.next++
+	if r.next == len(r.entries) {
+		r.next = 0
+		r.full = true
+	}
+	r.mutex.Unlock()
+
+	return len(p), nil
+}
+
+// snapshot returns a copy of the entries, oldest first.
+func (r *lineRing) snapshot() []ringEntry {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if !r.full {
+		return append([]ringEntry(nil), r.entries[:r.next]...)
+	}
+	entries := make([]ringEntry, 0, len(r.entries))
+	entries = append(entries, r.entries[r.next:]...)
+	return append(entries, r.entries[:r.next]...)
+}
@@ -0,0 +1,69 @@
+package health
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// ringBufferSinkHTTPLine is one line of Handler's JSON output.
+type ringBufferSinkHTTPLine struct {
+	Job   string `json:"job,omitempty"`
+	Level string `json:"level,omitempty"`
+	Line  string `json:"line"`
+}
+
+// Handler returns an http.Handler that dumps the lines being kept, oldest first, for a quick look at recent events:
+//
+//	http.Handle("/debug/recent", recorder.Handler())
+//
+// By default lines are written as text/plain, as they were rendered. These query parameters are supported:
+//   - job: only lines for this job
+//   - level: only lines at or above this level (trace, debug, info, warn, error, or fatal)
+//   - format=json: a JSON array of {"job", "level", "line"} objects, with line endings trimmed
+//
+// Lines without a known job or level (see RingBufferSink) are left out when filtering by them.
+func (s *RingBufferSink) Handler() http.Handler {
+	return http.HandlerFunc(s.serveHTTP)
+}
+
+func (s *RingBufferSink) serveHTTP(rw http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	job := query.Get("job")
+	minLevel := LevelNone
+	if l := query.Get("level"); l != "" {
+		var ok bool
+		if minLevel, ok = ParseLevel(l); !ok {
+			http.Error(rw, "unknown level: "+l, http.StatusBadRequest)
+			return
+		}
+	}
+
+	var entries []ringEntry
+	for _, e := range s.ring.snapshot() {
+		if job != "" && e.job != job {
+			continue
+		}
+		if e.level < minLevel {
+			continue
+		}
+		entries = append(entries, e)
+	}
+
+	if query.Get("format") == "json" {
+		lines := make([]ringBufferSinkHTTPLine, len(entries))
+		for i, e := range entries {
+			lines[i] = ringBufferSinkHTTPLine{Job: e.job, Level: e.level.String(), Line: strings.TrimRight(e.line, "\r\n")}
+		}
+		rw.Header().Set("Content-Type", "application/json; charset=utf-8")
+		json.NewEncoder(rw).Encode(lines)
+		return
+	}
+
+	rw.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	for _, e := range entries {
+		if _, err := rw.Write([]byte(e.line)); err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package health
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func getRingBufferSink(t *testing.T, sink *RingBufferSink, url string) *httptest.ResponseRecorder {
+	rw := httptest.NewRecorder()
+	req, err := http.NewRequest("GET", url, nil)
+	assert.NoError(t, err)
+	sink.Handler().ServeHTTP(rw, req)
+	return rw
+}
+
+func TestRingBufferSinkHandler(t *testing.T) {
+	sink := NewRingBufferSink(10)
+	sink.NoTimestamp = true
+	sink.EmitEvent("signup", "started", nil)
+	sink.EmitEventErr("signup", "email", testErr, nil)
+	sink.EmitEvent("billing", "slow", map[string]string{"level": "warn"})
+	sink.EmitComplete("billing", Panic, 1204, nil)
+	sink.EmitGaugeUnit("billing", "heap", 512, "bytes", nil)
+
+	rw := getRingBufferSink(t, sink, "/debug/recent")
+	assert.Equal(t, 200, rw.Code)
+	assert.Equal(t, "text/plain; charset=utf-8", rw.Header().Get("Content-Type"))
+	assert.Equal(t, "job:signup event:started\n"+
+		"job:signup event:email err:my test error\n"+
+		"job:billing event:slow kvs:[level:warn]\n"+
+		"job:billing status:panic time:1204 ns\n"+
+		"job:billing event:heap gauge:512bytes\n", rw.Body.String())
+
+	rw = getRingBufferSink(t, sink, "/debug/recent?job=billing")
+	assert.Equal(t, "job:billing event:slow kvs:[level:warn]\njob:billing status:panic time:1204 ns\n", rw.Body.String())
+
+	rw = getRingBufferSink(t, sink, "/debug/recent?level=WARN")
+	assert.Equal(t, "job:signup event:email err:my test error\n"+
+		"job:billing event:slow kvs:[level:warn]\n"+
+		"job:billing status:panic time:1204 ns\n", rw.Body.String())
+
+	rw = getRingBufferSink(t, sink, "/debug/recent?level=error&job=signup&format=json")
+	assert.Equal(t, "application/json; charset=utf-8", rw.Header().Get("Content-Type"))
+	var lines []map[string]string
+	assert.NoError(t, json.Unmarshal(rw.Body.Bytes(), &lines))
+	assert.Equal(t, []map[string]string{{"job": "signup", "level": "error", "line": "job:signup event:email err:my test error"}}, lines)
+
+	rw = getRingBufferSink(t, sink, "/debug/recent?level=loud")
+	assert.Equal(t, 400, rw.Code)
+}
+
+func TestRingBufferSinkHandlerEmpty(t *testing.T) {
+	sink := NewRingBufferSink(10)
+	rw := getRingBufferSink(t, sink, "/?format=json")
+	assert.Equal(t, "[]\n", rw.Body.String())
+}
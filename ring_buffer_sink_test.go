@@ -0,0 +1,62 @@
+package health
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRingBufferSink(t *testing.T) {
+	sink := NewRingBufferSink(3)
+	sink.NoTimestamp = true
+	assert.Empty(t, sink.Lines())
+
+	sink.EmitEvent("myjob", "one", nil)
+	sink.EmitEvent("myjob", "two", nil)
+	assert.Equal(t, []string{"job:myjob event:one", "job:myjob event:two"}, sink.Lines())
+
+	sink.EmitEvent("myjob", "three", nil)
+	sink.EmitEventErr("myjob", "four", testErr, nil)
+	sink.EmitComplete("myjob", Success, 1204, nil)
+	assert.Equal(t, []string{
+		"job:myjob event:three",
+		"job:myjob event:four err:my test error",
+		"job:myjob status:success time:1204 ns",
+	}, sink.Lines())
+
+	var b bytes.Buffer
+	assert.NoError(t, sink.Dump(&b))
+	assert.Equal(t, "job:myjob event:three\njob:myjob event:four err:my test error\njob:myjob status:success time:1204 ns\n", b.String())
+}
+
+func TestRingBufferSinkLineEnding(t *testing.T) {
+	sink := NewRingBufferSink(2)
+	sink.NoTimestamp = true
+	sink.LineEnding = "\r\n"
+	sink.EmitEvent("myjob", "one", nil)
+
+	assert.Equal(t, []string{"job:myjob event:one"}, sink.Lines())
+	var b bytes.Buffer
+	sink.Dump(&b)
+	assert.Equal(t, "job:myjob event:one\r\n", b.String())
+}
+
+func TestRingBufferSinkConcurrent(t *testing.T) {
+	sink := NewRingBufferSink(100)
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				sink.EmitEvent("myjob", fmt.Sprintf("event%d", i), nil)
+				sink.Lines()
+			}
+		}(i)
+	}
+	wg.Wait()
+	assert.Equal(t, 100, len(sink.Lines()))
+}
@@ -0,0 +1,222 @@
+package health
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// RotatingFileSink is a WriterSink that writes to a file, and rotates that file once it gets too big or too old.
+// Rotated files are renamed to Filename plus a timestamp and gzipped, eg "app.log.2015-03-11T22-53-22.115855203.gz".
+// All of WriterSink's options are available on the embedded WriterSink.
+//
+// If the file or its directory is removed out from under the sink, it's recreated within a second.
+type RotatingFileSink struct {
+	WriterSink
+
+	Filename string
+
+	// MaxBytes is the size the file may grow to before it's rotated. Zero means no limit.
+	MaxBytes int64
+
+	// MaxAge is how long a file is written to before it's rotated. Zero means no limit.
+	MaxAge time.Duration
+
+	// MaxBackups is the number of rotated files to keep. Zero means keep them all.
+	MaxBackups int
+
+	fileMutex sync.Mutex
+	file      *os.File
+	size      int64
+	openedAt  time.Time
+	checkedAt time.Time
+}
+
+// How often we check that the file we're writing to is still there.
+const rotatingFileSinkCheckInterval = time.Second
+
+const rotatingFileSinkTimeFormat = "2006-01-02T15-04-05.000000000"
+
+func NewRotatingFileSink(filename string) *RotatingFileSink {
+	s := &RotatingFileSink{Filename: filename}
+	s.Writer = rotatingFileSinkWriter{s}
+	return s
+}
+
+// Close closes the current file. A later emit will reopen it.
+func (s *RotatingFileSink) Close() error {
+	s.fileMutex.Lock()
+	defer s.fileMutex.Unlock()
+	return s.closeFile()
+}
+
+type rotatingFileSinkWriter struct {
+	sink *RotatingFileSink
+}
+
+func (w rotatingFileSinkWriter) Write(p []byte) (int, error) {
+	return w.sink.writeFile(p)
+}
+
+func (s *RotatingFileSink) writeFile(p []byte) (int, error) {
+	s.fileMutex.Lock()
+	defer s.fileMutex.Unlock()
+
+	if s.file != nil && now().Sub(s.checkedAt) >= rotatingFileSinkCheckInterval {
+		s.checkedAt = now()
+		if _, err := os.Stat(s.Filename); err != nil {
+			s.closeFile()
+		}
+	}
+
+	if s.file == nil {
+		if err := s.openFile(); err != nil {
+			return 0, err
+		}
+	}
+
+	if s.shouldRotate(len(p)) {
+		if err := s.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := s.file.Write(p)
+	s.size += int64(n)
+	return n, err
+}
+
+func (s *RotatingFileSink) shouldRotate(writeLen int) bool {
+	if s.size == 0 {
+		return false
+	}
+	if s.MaxBytes > 0 && s.size+int64(writeLen) > s.MaxBytes {
+		return true
+	}
+	if s.MaxAge > 0 && now().Sub(s.openedAt) >= s.MaxAge {
+		return true
+	}
+	return false
+}
+
+func (s *RotatingFileSink) openFile() error {
+	if err := os.MkdirAll(filepath.Dir(s.Filename), 0755); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(s.Filename, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	s.file = f
+	s.size = fi.Size()
+	s.openedAt = now()
+	s.checkedAt = now()
+	return nil
+}
+
+func (s *RotatingFileSink) closeFile() error {
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
+
+// rotate moves the current file to a gzipped backup, prunes old backups, and opens a fresh file.
+func (s *RotatingFileSink) rotate() error {
+	if err := s.closeFile(); err != nil {
+		return err
+	}
+
+	backup := s.Filename + "." + now().UTC().Format(rotatingFileSinkTimeFormat)
+	for i := 1; fileExists(backup + ".gz"); i++ {
+		backup = fmt.Sprintf("%s.%s-%d", s.Filename, now().UTC().Format(rotatingFileSinkTimeFormat), i)
+	}
+
+	if err := os.Rename(s.Filename, backup); err == nil {
+		if err := gzipFile(backup); err != nil {
+			return err
+		}
+		if err := s.pruneBackups(); err != nil {
+			return err
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	return s.openFile()
+}
+
+func (s *RotatingFileSink) backups() ([]string, error) {
+	matches, err := filepath.Glob(s.Filename + ".*.gz")
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(matches)
+	return matches, nil
+}
+
+func (s *RotatingFileSink) pruneBackups() error {
+	if s.MaxBackups <= 0 {
+		return nil
+	}
+
+	backups, err := s.backups()
+	if err != nil {
+		return err
+	}
+	for len(backups) > s.MaxBackups {
+		if err := os.Remove(backups[0]); err != nil {
+			return err
+		}
+		backups = backups[1:]
+	}
+	return nil
+}
+
+// gzipFile compresses fname to fname + ".gz" and removes fname.
+func gzipFile(fname string) error {
+	in, err := os.Open(fname)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(fname+".gz", os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+
+	gz := gzip.NewWriter(out)
+	if _, err := io.Copy(gz, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+
+	return os.Remove(fname)
+}
+
+func fileExists(fname string) bool {
+	_, err := os.Stat(fname)
+	return !os.IsNotExist(err)
+}
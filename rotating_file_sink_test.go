@@ -0,0 +1,125 @@
+package health
+
+import (
+	"compress/gzip"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func readGzipFile(t *testing.T, fname string) string {
+	f, err := os.Open(fname)
+	assert.NoError(t, err)
+	defer f.Close()
+	r, err := gzip.NewReader(f)
+	assert.NoError(t, err)
+	b, err := ioutil.ReadAll(r)
+	assert.NoError(t, err)
+	return string(b)
+}
+
+func readFile(t *testing.T, fname string) string {
+	b, err := ioutil.ReadFile(fname)
+	assert.NoError(t, err)
+	return string(b)
+}
+
+func TestRotatingFileSinkMaxBytes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "health")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	sink := NewRotatingFileSink(filepath.Join(dir, "app.log"))
+	sink.MaxBytes = 150
+	defer sink.Close()
+
+	// Each line is about 70 bytes, so two fit in a file.
+	for _, event := range []string{"one", "two", "three", "four", "five"} {
+		sink.EmitEvent("myjob", event, nil)
+	}
+
+	backups, err := sink.backups()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(backups))
+
+	assert.Equal(t, 2, strings.Count(readGzipFile(t, backups[0]), "\n"))
+	assert.Contains(t, readGzipFile(t, backups[0]), "event:one\n")
+	assert.Contains(t, readGzipFile(t, backups[1]), "event:four\n")
+	assert.Contains(t, readFile(t, sink.Filename), "event:five\n")
+	assert.Equal(t, 1, strings.Count(readFile(t, sink.Filename), "\n"))
+}
+
+func TestRotatingFileSinkMaxBackups(t *testing.T) {
+	dir, err := ioutil.TempDir("", "health")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	sink := NewRotatingFileSink(filepath.Join(dir, "app.log"))
+	sink.MaxBytes = 1
+	sink.MaxBackups = 3
+	defer sink.Close()
+
+	for i := 0; i < 10; i++ {
+		sink.EmitEvent("myjob", "myevent", nil)
+	}
+
+	backups, err := sink.backups()
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(backups))
+}
+
+func TestRotatingFileSinkMaxAge(t *testing.T) {
+	setNowMock("2011-09-09T23:36:13Z")
+	defer resetNowMock()
+
+	dir, err := ioutil.TempDir("", "health")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	sink := NewRotatingFileSink(filepath.Join(dir, "app.log"))
+	sink.MaxAge = time.Hour
+	defer sink.Close()
+
+	sink.EmitEvent("myjob", "one", nil)
+	setNowMock("2011-09-09T23:59:13Z")
+	sink.EmitEvent("myjob", "two", nil)
+
+	backups, err := sink.backups()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(backups))
+
+	setNowMock("2011-09-10T00:36:13Z")
+	sink.EmitEvent("myjob", "three", nil)
+
+	backups, err = sink.backups()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(dir, "app.log.2011-09-10T00-36-13.000000000.gz")}, backups)
+	assert.Equal(t, 2, strings.Count(readGzipFile(t, backups[0]), "\n"))
+	assert.Contains(t, readFile(t, sink.Filename), "event:three\n")
+}
+
+func TestRotatingFileSinkDirectoryRemoved(t *testing.T) {
+	setNowMock("2011-09-09T23:36:13Z")
+	defer resetNowMock()
+
+	dir, err := ioutil.TempDir("", "health")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	sink := NewRotatingFileSink(filepath.Join(dir, "logs", "app.log"))
+	defer sink.Close()
+
+	sink.EmitEvent("myjob", "one", nil)
+	assert.NoError(t, os.RemoveAll(filepath.Join(dir, "logs")))
+
+	setNowMock("2011-09-09T23:36:15Z")
+	sink.EmitEvent("myjob", "two", nil)
+
+	contents := readFile(t, sink.Filename)
+	assert.NotContains(t, contents, "event:one\n")
+	assert.Contains(t, contents, "event:two\n")
+}
@@ -0,0 +1,108 @@
+package health
+
+import (
+	"runtime"
+	"sync"
+	"time"
+)
+
+// RuntimeStatsEmitter periodically emits gauges about the Go runtime to Sink, so every service doesn't need its own copy of this loop:
+//
+//	stats := health.NewRuntimeStatsEmitter(statsdSink)
+//	stats.Start(10 * time.Second)
+//	defer stats.Stop()
+//
+// Each round emits these gauges under Job:
+//   - goroutines: the number of goroutines
+//   - heap_alloc: bytes of allocated heap objects
+//   - heap_objects: the number of allocated heap objects
+//   - heap_sys: bytes of heap memory obtained from the OS
+//   - gc_count: the number of completed GC cycles
+//   - gc_pause_ns: how long the most recent GC stopped the world, in nanoseconds
+//   - gc_pause_total_ns: how long all GCs have stopped the world, in nanoseconds
+type RuntimeStatsEmitter struct {
+	Sink Sink
+
+	// Job is the job the gauges are emitted under. Defaults to "runtime".
+	Job string
+
+	mutex    sync.Mutex
+	doneChan chan int
+	stopped  chan int
+}
+
+// NewRuntimeStatsEmitter returns a RuntimeStatsEmitter that emits to sink. Call Start to begin.
+func NewRuntimeStatsEmitter(sink Sink) *RuntimeStatsEmitter {
+	return &RuntimeStatsEmitter{Sink: sink, Job: "runtime"}
+}
+
+// Start emits the gauges once now and then every interval, until Stop is called. It does nothing if it's already started,
+// or if interval isn't positive.
+func (e *RuntimeStatsEmitter) Start(interval time.Duration) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if e.doneChan != nil || interval <= 0 {
+		return
+	}
+	e.doneChan = make(chan int)
+	e.stopped = make(chan int)
+	go runtimeStatsEmitterLoop(e, interval, e.doneChan, e.stopped)
+}
+
+// Stop stops the emitting started by Start, and waits for any round in progress to finish. It's safe to call Stop more than once,
+// and Start may be called again afterwards.
+func (e *RuntimeStatsEmitter) Stop() {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if e.doneChan == nil {
+		return
+	}
+	close(e.doneChan)
+	<-e.stopped
+	e.doneChan = nil
+	e.stopped = nil
+}
+
+// Emit emits one round of gauges now.
+func (e *RuntimeStatsEmitter) Emit() {
+	job := e.Job
+	if job == "" {
+		job = "runtime"
+	}
+
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+
+	var lastPause uint64
+	if m.NumGC > 0 {
+		lastPause = m.PauseNs[(m.NumGC+255)%256]
+	}
+
+	e.Sink.EmitGauge(job, "goroutines", float64(runtime.NumGoroutine()), nil)
+	e.Sink.EmitGauge(job, "heap_alloc", float64(m.HeapAlloc), nil)
+	e.Sink.EmitGauge(job, "heap_objects", float64(m.HeapObjects), nil)
+	e.Sink.EmitGauge(job, "heap_sys", float64(m.HeapSys), nil)
+	e.Sink.EmitGauge(job, "gc_count", float64(m.NumGC), nil)
+	e.Sink.EmitGauge(job, "gc_pause_ns", float64(lastPause), nil)
+	e.Sink.EmitGauge(job, "gc_pause_total_ns", float64(m.PauseTotalNs), nil)
+}
+
+func runtimeStatsEmitterLoop(e *RuntimeStatsEmitter, interval time.Duration, doneChan chan int, stopped chan int) {
+	defer close(stopped)
+
+	e.Emit()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-doneChan:
+			return
+		case <-ticker.C:
+			e.Emit()
+		}
+	}
+}
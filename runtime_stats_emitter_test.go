@@ -0,0 +1,64 @@
+package health
+
+import (
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRuntimeStatsEmitterEmit(t *testing.T) {
+	sink := &MemorySink{}
+	e := NewRuntimeStatsEmitter(sink)
+	runtime.GC()
+	e.Emit()
+
+	gauges := map[string]float64{}
+	for _, ev := range sink.EventsOfKind(KindGauge) {
+		assert.Equal(t, "runtime", ev.Job)
+		gauges[ev.Event] = ev.Value
+	}
+	assert.Equal(t, 7, len(gauges))
+	assert.True(t, gauges["goroutines"] >= 1)
+	assert.True(t, gauges["heap_alloc"] > 0)
+	assert.True(t, gauges["heap_sys"] > 0)
+	assert.True(t, gauges["gc_count"] >= 1)
+	assert.Contains(t, gauges, "gc_pause_ns")
+	assert.Contains(t, gauges, "gc_pause_total_ns")
+	assert.Contains(t, gauges, "heap_objects")
+}
+
+func TestRuntimeStatsEmitterStartStop(t *testing.T) {
+	sink := &MemorySink{}
+	e := NewRuntimeStatsEmitter(sink)
+	e.Job = "myapp"
+	e.Start(time.Millisecond)
+	e.Start(time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	e.Stop()
+	e.Stop()
+
+	n := len(sink.Events())
+	assert.True(t, n >= 14, "only %d gauges", n)
+	assert.Equal(t, "myapp", sink.Events()[0].Job)
+
+	// Nothing more once stopped.
+	time.Sleep(5 * time.Millisecond)
+	assert.Equal(t, n, len(sink.Events()))
+}
+
+func TestRuntimeStatsEmitterStartNonPositiveInterval(t *testing.T) {
+	sink := &MemorySink{}
+	e := NewRuntimeStatsEmitter(sink)
+	e.Start(0)
+	e.Start(-time.Second)
+	time.Sleep(5 * time.Millisecond)
+	e.Stop()
+	assert.Equal(t, 0, len(sink.Events()))
+
+	// A later Start with a real interval still works.
+	e.Start(time.Hour)
+	e.Stop()
+	assert.Equal(t, 7, len(sink.Events()))
+}
@@ -0,0 +1,97 @@
+package health
+
+import (
+	"fmt"
+	"os"
+)
+
+// SafeSink keeps a panicking Sink from crashing the goroutine that emitted, eg a request handler.
+// A panic in the wrapped Sink's emit is recovered and passed to ErrorHandler as a *SinkPanicError, and the emit returns normally.
+// Only panics are recovered; anything else the wrapped Sink does (including reporting its own errors) is left alone.
+//
+//	stream.AddSink(health.NewSafeSink(thirdPartySink, func(err error) { log.Println(err) }))
+type SafeSink struct {
+	Sink Sink
+
+	// ErrorHandler, if set, is called with a *SinkPanicError for each recovered panic. If nil, they're printed to stderr.
+	ErrorHandler func(error)
+
+	// Name, if set, identifies this sink in errors passed to ErrorHandler or printed to stderr (see SinkError).
+	Name string
+}
+
+// SinkPanicError is a panic recovered by SafeSink.
+type SinkPanicError struct {
+	// Value is what was passed to panic.
+	Value interface{}
+}
+
+func (e *SinkPanicError) Error() string {
+	return fmt.Sprintf("sink panicked: %v", e.Value)
+}
+
+// Unwrap returns Value if it's an error, eg a runtime.Error, so errors.Is and errors.As see it.
+func (e *SinkPanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// NewSafeSink returns a SafeSink that recovers panics in sink and passes them to errorHandler.
+func NewSafeSink(sink Sink, errorHandler func(error)) *SafeSink {
+	return &SafeSink{Sink: sink, ErrorHandler: errorHandler}
+}
+
+func (s *SafeSink) EmitEvent(job string, event string, kvs map[string]string) {
+	defer s.recover()
+	s.Sink.EmitEvent(job, event, kvs)
+}
+
+func (s *SafeSink) EmitEventErr(job string, event string, inputErr error, kvs map[string]string) {
+	defer s.recover()
+	s.Sink.EmitEventErr(job, event, inputErr, kvs)
+}
+
+func (s *SafeSink) EmitTiming(job string, event string, nanos int64, kvs map[string]string) {
+	defer s.recover()
+	s.Sink.EmitTiming(job, event, nanos, kvs)
+}
+
+func (s *SafeSink) EmitComplete(job string, status CompletionStatus, nanos int64, kvs map[string]string) {
+	defer s.recover()
+	s.Sink.EmitComplete(job, status, nanos, kvs)
+}
+
+func (s *SafeSink) EmitGauge(job string, event string, value float64, kvs map[string]string) {
+	defer s.recover()
+	s.Sink.EmitGauge(job, event, value, kvs)
+}
+
+func (s *SafeSink) EmitCount(job string, event string, delta int64, kvs map[string]string) {
+	defer s.recover()
+	s.Sink.EmitCount(job, event, delta, kvs)
+}
+
+// Flush flushes the wrapped Sink if it implements Flusher. A panic while flushing is returned as a *SinkPanicError
+// rather than passed to ErrorHandler.
+func (s *SafeSink) Flush() (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = NameError(s.Name, &SinkPanicError{Value: r})
+		}
+	}()
+	return FlushAll(s.Sink)
+}
+
+// recover passes a panic in progress to ErrorHandler. It must be deferred directly.
+func (s *SafeSink) recover() {
+	r := recover()
+	if r == nil {
+		return
+	}
+	err := NameError(s.Name, &SinkPanicError{Value: r})
+	if s.ErrorHandler != nil {
+		s.ErrorHandler(err)
+	} else {
+		fmt.Fprintf(os.Stderr, "health.SafeSink: %v\n", err)
+	}
+}
@@ -0,0 +1,73 @@
+package health
+
+import (
+	"errors"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// panickingFlusher is a panickingSink that also panics on Flush.
+type panickingFlusher struct {
+	panickingSink
+}
+
+func (s *panickingFlusher) Flush() error { panic("flush") }
+
+func TestSafeSink(t *testing.T) {
+	var errs []error
+	sink := NewSafeSink(&panickingFlusher{}, func(err error) { errs = append(errs, err) })
+
+	assert.NotPanics(t, func() { emitOneOfEach(sink) })
+	assert.Equal(t, 6, len(errs))
+	assert.Equal(t, "sink panicked: event", errs[0].Error())
+	assert.Equal(t, "sink panicked: count", errs[5].Error())
+	var panicErr *SinkPanicError
+	assert.True(t, errors.As(errs[0], &panicErr))
+	assert.Equal(t, "event", panicErr.Value)
+
+	err := sink.Flush()
+	assert.EqualError(t, err, "sink panicked: flush")
+	assert.Equal(t, 6, len(errs))
+}
+
+func TestSafeSinkRuntimeError(t *testing.T) {
+	var errs []error
+	sink := &SafeSink{Sink: &nilMapSink{}, Name: "custom", ErrorHandler: func(err error) { errs = append(errs, err) }}
+
+	sink.EmitEvent("myjob", "myevent", nil)
+	assert.Equal(t, 1, len(errs))
+	assert.EqualError(t, errs[0], "custom: sink panicked: assignment to entry in nil map")
+	var runtimeErr runtime.Error
+	assert.True(t, errors.As(errs[0], &runtimeErr))
+}
+
+// nilMapSink panics with a runtime error on EmitEvent.
+type nilMapSink struct {
+	countingSink
+}
+
+func (s *nilMapSink) EmitEvent(job string, event string, kvs map[string]string) {
+	kvs["wat"] = "ok"
+}
+
+func TestSafeSinkPassesThrough(t *testing.T) {
+	inner := &MemorySink{}
+	var errs []error
+	sink := NewSafeSink(inner, func(err error) { errs = append(errs, err) })
+
+	emitOneOfEach(sink)
+	assert.Equal(t, 6, len(inner.Events()))
+	assert.NoError(t, sink.Flush())
+	assert.Empty(t, errs)
+
+	// A Flush error is returned as-is.
+	sink = NewSafeSink(&WriterSink{Writer: &erroringFlusher{}}, nil)
+	assert.Equal(t, testErr, sink.Flush())
+}
+
+type erroringFlusher struct{}
+
+func (erroringFlusher) Write(p []byte) (int, error) { return len(p), nil }
+func (erroringFlusher) Flush() error                { return testErr }
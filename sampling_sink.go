@@ -0,0 +1,102 @@
+package health
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// SamplingSink forwards a random fraction of emits to the wrapped Sink to cut down on volume.
+// Errors are never sampled: every EmitEventErr is forwarded, and so is every emit whose kvs["level"] is error or fatal.
+// Dropped counts the emits that weren't forwarded.
+type SamplingSink struct {
+	dropCounter
+
+	Sink Sink
+
+	// Rate is the fraction of emits to forward, from 0.0 (none) to 1.0 (all).
+	Rate float64
+
+	// Rand is the source of sampling decisions. If nil, the math/rand top-level functions are used.
+	// Set it to a seeded *rand.Rand for repeatable sampling.
+	Rand *rand.Rand
+
+	// randMutex guards Rand, which isn't safe for concurrent use.
+	randMutex sync.Mutex
+}
+
+func NewSamplingSink(sink Sink, rate float64) *SamplingSink {
+	return &SamplingSink{Sink: sink, Rate: rate}
+}
+
+func (s *SamplingSink) EmitEvent(job string, event string, kvs map[string]string) {
+	if s.sample(kvs) {
+		s.Sink.EmitEvent(job, event, kvs)
+	}
+}
+
+func (s *SamplingSink) EmitEventErr(job string, event string, inputErr error, kvs map[string]string) {
+	s.Sink.EmitEventErr(job, event, inputErr, kvs)
+}
+
+func (s *SamplingSink) EmitTiming(job string, event string, nanos int64, kvs map[string]string) {
+	if s.sample(kvs) {
+		s.Sink.EmitTiming(job, event, nanos, kvs)
+	}
+}
+
+func (s *SamplingSink) EmitComplete(job string, status CompletionStatus, nanos int64, kvs map[string]string) {
+	if s.sample(kvs) {
+		s.Sink.EmitComplete(job, status, nanos, kvs)
+	}
+}
+
+func (s *SamplingSink) EmitGauge(job string, event string, value float64, kvs map[string]string) {
+	if s.sample(kvs) {
+		s.Sink.EmitGauge(job, event, value, kvs)
+	}
+}
+
+func (s *SamplingSink) EmitCount(job string, event string, delta int64, kvs map[string]string) {
+	if s.sample(kvs) {
+		s.Sink.EmitCount(job, event, delta, kvs)
+	}
+}
+
+// SummarizeDrops emits an event to the wrapped Sink every interval in which something was dropped, until Close is called:
+//
+//	[2016-01-02T15:04:05Z]: job:health event:sampling_sink.dropped kvs:[dropped:412 interval:10s]
+func (s *SamplingSink) SummarizeDrops(interval time.Duration) {
+	s.startSummaries(s.Sink, "sampling_sink.dropped", interval)
+}
+
+// Flush flushes the wrapped Sink if it implements Flusher.
+func (s *SamplingSink) Flush() error {
+	return FlushAll(s.Sink)
+}
+
+// sample decides whether to forward an emit with kvs, counting it as dropped if not.
+func (s *SamplingSink) sample(kvs map[string]string) bool {
+	if EmitLevel(kvs, LevelInfo) >= LevelError || s.keep() {
+		return true
+	}
+	s.drop()
+	return false
+}
+
+func (s *SamplingSink) keep() bool {
+	if s.Rate >= 1 {
+		return true
+	}
+	if s.Rate <= 0 {
+		return false
+	}
+
+	if s.Rand == nil {
+		return rand.Float64() < s.Rate
+	}
+
+	s.randMutex.Lock()
+	defer s.randMutex.Unlock()
+	return s.Rand.Float64() < s.Rate
+}
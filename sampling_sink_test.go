@@ -0,0 +1,98 @@
+package health
+
+import (
+	"github.com/stretchr/testify/assert"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestSamplingSinkRate(t *testing.T) {
+	inner := &countingSink{}
+	sink := NewSamplingSink(inner, 0.25)
+	sink.Rand = rand.New(rand.NewSource(1))
+	for i := 0; i < 1000; i++ {
+		emitOneOfEach(sink)
+	}
+
+	// Errors always get through:
+	assert.Equal(t, 1000, inner.EventErrs)
+
+	for _, n := range []int{inner.Events, inner.Timings, inner.Completions, inner.Gauges, inner.Counts} {
+		assert.InDelta(t, 250, n, 50)
+	}
+
+	forwarded := inner.Events + inner.Timings + inner.Completions + inner.Gauges + inner.Counts
+	assert.Equal(t, uint64(5000-forwarded), sink.Dropped())
+}
+
+func TestSamplingSinkSummarizeDrops(t *testing.T) {
+	inner := &MemorySink{}
+	sink := NewSamplingSink(inner, 0)
+	sink.SummarizeDrops(time.Hour)
+	for i := 0; i < 3; i++ {
+		sink.EmitEvent("myjob", "myevent", nil)
+	}
+	sink.EmitEventErr("myjob", "myevent", testErr, nil)
+	assert.NoError(t, sink.Close())
+	assert.NoError(t, sink.Close())
+
+	events := inner.Events()
+	assert.Equal(t, 2, len(events))
+	assert.Equal(t, KindEventErr, events[0].Kind)
+	assert.Equal(t, "health", events[1].Job)
+	assert.Equal(t, "sampling_sink.dropped", events[1].Event)
+	assert.Equal(t, map[string]string{"dropped": "3", "interval": "1h0m0s"}, events[1].Kvs)
+	assert.Equal(t, uint64(3), sink.Dropped())
+}
+
+func TestSamplingSinkDeterministic(t *testing.T) {
+	a, b := &countingSink{}, &countingSink{}
+	sinkA := NewSamplingSink(a, 0.5)
+	sinkA.Rand = rand.New(rand.NewSource(42))
+	sinkB := NewSamplingSink(b, 0.5)
+	sinkB.Rand = rand.New(rand.NewSource(42))
+	for i := 0; i < 100; i++ {
+		emitOneOfEach(sinkA)
+		emitOneOfEach(sinkB)
+	}
+	assert.Equal(t, *a, *b)
+}
+
+func TestSamplingSinkAllOrNothing(t *testing.T) {
+	all := &countingSink{}
+	none := &countingSink{}
+	allSink := NewSamplingSink(all, 1)
+	noneSink := NewSamplingSink(none, 0)
+	for i := 0; i < 10; i++ {
+		emitOneOfEach(allSink)
+		emitOneOfEach(noneSink)
+	}
+	assert.Equal(t, countingSink{10, 10, 10, 10, 10, 10}, *all)
+	assert.Equal(t, countingSink{0, 10, 0, 0, 0, 0}, *none)
+}
+
+func TestSamplingSinkErrorLevels(t *testing.T) {
+	inner := &countingSink{}
+	sink := NewSamplingSink(inner, 0.1)
+	sink.Rand = rand.New(rand.NewSource(1))
+	for i := 0; i < 100; i++ {
+		for _, kvs := range []map[string]string{{"level": "ERROR"}, {"level": "err"}, {"level": "fatal"}} {
+			sink.EmitEvent("myjob", "myevent", kvs)
+			sink.EmitTiming("myjob", "myevent", 5, kvs)
+			sink.EmitComplete("myjob", Error, 5, kvs)
+			sink.EmitGauge("myjob", "myevent", 3.14, kvs)
+			sink.EmitCount("myjob", "myevent", 1, kvs)
+		}
+	}
+	assert.Equal(t, countingSink{300, 0, 300, 300, 300, 300}, *inner)
+	assert.Equal(t, uint64(0), sink.Dropped())
+
+	// Less severe levels are sampled as usual.
+	*inner = countingSink{}
+	for i := 0; i < 100; i++ {
+		sink.EmitEvent("myjob", "myevent", map[string]string{"level": "warn"})
+	}
+	assert.InDelta(t, 10, inner.Events, 8)
+	assert.Equal(t, uint64(100-inner.Events), sink.Dropped())
+}
@@ -0,0 +1,26 @@
+package health
+
+// SinkError is what a sink with a Name passes to its ErrorHandler, so that a handler shared by several sinks can tell which one failed.
+// By convention every sink that takes an ErrorHandler also has a Name (on the sink, or on its Config for sinks in sinks/).
+type SinkError struct {
+	// Sink is the Name of the sink that hit Err.
+	Sink string
+	Err  error
+}
+
+func (e *SinkError) Error() string {
+	return e.Sink + ": " + e.Err.Error()
+}
+
+// Unwrap returns Err, so errors.Is and errors.As see through a SinkError.
+func (e *SinkError) Unwrap() error {
+	return e.Err
+}
+
+// NameError wraps err in a *SinkError naming the sink. If name is empty, err is returned as-is.
+func NameError(name string, err error) error {
+	if name == "" || err == nil {
+		return err
+	}
+	return &SinkError{Sink: name, Err: err}
+}
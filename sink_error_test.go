@@ -0,0 +1,21 @@
+package health
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNameError(t *testing.T) {
+	assert.Equal(t, testErr, NameError("", testErr))
+	assert.Nil(t, NameError("file", nil))
+
+	err := NameError("file", testErr)
+	assert.Equal(t, "file: my test error", err.Error())
+	assert.True(t, errors.Is(err, testErr))
+
+	var sinkErr *SinkError
+	assert.True(t, errors.As(err, &sinkErr))
+	assert.Equal(t, "file", sinkErr.Sink)
+}
@@ -0,0 +1,87 @@
+package health
+
+import (
+	"bytes"
+	"sync"
+)
+
+// maxSinkWriterLineLen is the most a SinkWriter buffers waiting for a newline. Longer lines are emitted in pieces of this size.
+const maxSinkWriterLineLen = 64 * 1024
+
+// SinkWriter is an io.Writer that emits each line written to it as an event on Sink, with the line in kvs under Key.
+// Use it to capture the output of code that only knows how to write to an io.Writer, eg a log.Logger:
+//
+//	sink := &health.WriterSink{Writer: os.Stderr}
+//	log.SetOutput(health.NewSinkWriter(sink, "legacy", "log"))
+//
+// Partial lines are held until the rest of them is written, or Flush is called.
+// Trailing carriage returns are dropped, and so are empty lines.
+type SinkWriter struct {
+	Sink  Sink
+	Job   string
+	Event string
+
+	// Key is the kvs key each line is emitted under. Defaults to "line".
+	Key string
+
+	mutex   sync.Mutex
+	partial []byte
+}
+
+func NewSinkWriter(sink Sink, job, event string) *SinkWriter {
+	return &SinkWriter{Sink: sink, Job: job, Event: event}
+}
+
+// Write emits every complete line in p, and holds on to what follows the last newline. It always consumes all of p.
+func (w *SinkWriter) Write(p []byte) (int, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	n := len(p)
+	for len(p) > 0 {
+		i := bytes.IndexByte(p, '\n')
+		if i < 0 {
+			w.partial = append(w.partial, p...)
+			for len(w.partial) >= maxSinkWriterLineLen {
+				w.emit(w.partial[:maxSinkWriterLineLen])
+				w.partial = w.partial[maxSinkWriterLineLen:]
+			}
+			break
+		}
+
+		if len(w.partial) > 0 {
+			w.partial = append(w.partial, p[:i]...)
+			w.emit(w.partial)
+			w.partial = w.partial[:0]
+		} else {
+			w.emit(p[:i])
+		}
+		p = p[i+1:]
+	}
+	return n, nil
+}
+
+// Flush emits the partial line being held, if any, and then flushes Sink if it's a Flusher.
+func (w *SinkWriter) Flush() error {
+	w.mutex.Lock()
+	if len(w.partial) > 0 {
+		w.emit(w.partial)
+		w.partial = w.partial[:0]
+	}
+	w.mutex.Unlock()
+
+	return FlushAll(w.Sink)
+}
+
+func (w *SinkWriter) emit(line []byte) {
+	line = bytes.TrimSuffix(line, []byte("\r"))
+	if len(line) == 0 {
+		return
+	}
+
+	key := w.Key
+	if key == "" {
+		key = "line"
+	}
+	w.Sink.EmitEvent(w.Job, w.Event, map[string]string{key: string(line)})
+}
@@ -0,0 +1,63 @@
+package health
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func sinkWriterLines(sink *MemorySink) []string {
+	var lines []string
+	for _, e := range sink.Events() {
+		lines = append(lines, e.Kvs["line"])
+	}
+	return lines
+}
+
+func TestSinkWriter(t *testing.T) {
+	sink := &MemorySink{}
+	w := NewSinkWriter(sink, "legacy", "log")
+
+	n, err := w.Write([]byte("first\nsec"))
+	assert.NoError(t, err)
+	assert.Equal(t, 9, n)
+	w.Write([]byte("ond\r\n\nthi"))
+	w.Write([]byte("rd"))
+	assert.Equal(t, []string{"first", "second"}, sinkWriterLines(sink))
+
+	assert.NoError(t, w.Flush())
+	assert.Equal(t, []string{"first", "second", "third"}, sinkWriterLines(sink))
+
+	events := sink.Events()
+	assert.Equal(t, MemorySinkEvent{Kind: KindEvent, Job: "legacy", Event: "log", Kvs: map[string]string{"line": "first"}}, events[0])
+}
+
+func TestSinkWriterKey(t *testing.T) {
+	sink := &MemorySink{}
+	w := &SinkWriter{Sink: sink, Job: "legacy", Event: "log", Key: "msg"}
+	fmt.Fprintln(w, "hi")
+	assert.Equal(t, map[string]string{"msg": "hi"}, sink.Events()[0].Kvs)
+}
+
+func TestSinkWriterLongLines(t *testing.T) {
+	sink := &MemorySink{}
+	w := NewSinkWriter(sink, "legacy", "log")
+	w.Write([]byte(strings.Repeat("a", maxSinkWriterLineLen+10)))
+	w.Write([]byte("\n"))
+
+	lines := sinkWriterLines(sink)
+	assert.Equal(t, 2, len(lines))
+	assert.Equal(t, maxSinkWriterLineLen, len(lines[0]))
+	assert.Equal(t, strings.Repeat("a", 10), lines[1])
+}
+
+func TestSinkWriterLogger(t *testing.T) {
+	sink := &MemorySink{}
+	logger := log.New(NewSinkWriter(sink, "legacy", "log"), "", 0)
+	logger.Printf("one")
+	logger.Printf("two\nthree")
+	assert.Equal(t, []string{"one", "two", "three"}, sinkWriterLines(sink))
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"github.com/gocraft/health"
 	"github.com/gocraft/health/stack"
 	"io/ioutil"
 	"net/http"
@@ -31,6 +32,29 @@ type Config struct {
 	// The hostname of the current server. This defaults to the return value of
 	// os.Hostname() and is graphed in the Bugsnag dashboard.
 	Hostname string
+
+	// MinLevel is the least severe level that the Sink reports; emits below it are ignored (see health.Level). Defaults to health.LevelError.
+	MinLevel health.Level
+
+	// NotifyFunc sends each of the Sink's notifications. Defaults to Send, which posts it to Endpoint.
+	NotifyFunc func(config *Config, n *Notification) error
+}
+
+// Notification is a single error report sent to Bugsnag.
+type Notification struct {
+	Job   string
+	Event string // Becomes the error class.
+	Err   error
+	Stack *stack.Trace // Optional.
+
+	// Severity is one of "error", "warning" or "info".
+	Severity string
+
+	// GroupingHash makes Bugsnag group notifications with the same hash together, rather than by stack trace.
+	GroupingHash string
+
+	// Kvs are attached as the "kvs" tab of the notification's meta data.
+	Kvs map[string]string
 }
 
 type payload struct {
@@ -53,8 +77,9 @@ type payloadEvent struct {
 
 	Context string `json:"context"`
 
-	// groupingHash
-	// severity
+	GroupingHash string `json:"groupingHash,omitempty"`
+	Severity     string `json:"severity,omitempty"`
+
 	// user
 
 	App struct {
@@ -67,7 +92,7 @@ type payloadEvent struct {
 		Hostname string `json:"hostname"`
 	} `json:"device"`
 
-	// meta data
+	MetaData map[string]map[string]string `json:"metaData,omitempty"`
 }
 
 type payloadException struct {
@@ -86,9 +111,14 @@ type payloadFrame struct {
 
 // Notify will send the error and stack trace to Bugsnag. Note that this doesn't take advantage of all of Bugsnag's capabilities.
 func Notify(config *Config, jobName string, eventName string, err error, trace *stack.Trace) error {
+	return Send(config, &Notification{Job: jobName, Event: eventName, Err: err, Stack: trace, Severity: "error"})
+}
+
+// Send posts n to config.Endpoint.
+func Send(config *Config, n *Notification) error {
 
 	// Make a struct that serializes to the JSON needed for the API request to bugsnag
-	p := newPayload(config, jobName, eventName, err, trace)
+	p := newPayload(config, n)
 
 	// JSON serialize it
 	data, err := json.MarshalIndent(p, "", "\t")
@@ -113,12 +143,16 @@ func Notify(config *Config, jobName string, eventName string, err error, trace *
 	return nil
 }
 
-func newPayload(config *Config, jobName string, eventName string, err error, trace *stack.Trace) *payload {
+func newPayload(config *Config, n *Notification) *payload {
 	except := payloadException{
-		ErrorClass: eventName,
-		Message:    err.Error(),
+		ErrorClass: n.Event,
+		Message:    n.Err.Error(),
 	}
-	for _, frame := range trace.Frames() {
+	var frames []stack.Frame
+	if n.Stack != nil {
+		frames = n.Stack.Frames()
+	}
+	for _, frame := range frames {
 		pf := payloadFrame{
 			File:       frame.File,
 			LineNumber: frame.LineNumber,
@@ -131,7 +165,15 @@ func newPayload(config *Config, jobName string, eventName string, err error, tra
 	evt := payloadEvent{
 		PayloadVersion: "2",
 		Exceptions:     []payloadException{except},
-		Context:        jobName,
+		Context:        n.Job,
+		GroupingHash:   n.GroupingHash,
+		Severity:       n.Severity,
+	}
+	evt.MetaData = map[string]map[string]string{
+		"health": {"job": n.Job, "event": n.Event},
+	}
+	if len(n.Kvs) > 0 {
+		evt.MetaData["kvs"] = n.Kvs
 	}
 	evt.App.ReleaseStage = config.ReleaseStage
 	evt.Device.Hostname = config.Hostname
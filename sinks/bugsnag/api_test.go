@@ -75,3 +75,23 @@ func (h notifyHandler) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
 
 	fmt.Fprintf(rw, "OK")
 }
+
+func TestNewPayload(t *testing.T) {
+	config := &Config{APIKey: "abcd", ReleaseStage: "staging"}
+	p := newPayload(config, &Notification{
+		Job:          "users/get",
+		Event:        "foo.bar",
+		Err:          fmt.Errorf("imanerror"),
+		Severity:     "warning",
+		GroupingHash: "users/get/foo.bar",
+		Kvs:          map[string]string{"user": "bob"},
+	})
+
+	evt := p.Events[0]
+	assert.Equal(t, "users/get", evt.Context)
+	assert.Equal(t, "warning", evt.Severity)
+	assert.Equal(t, "users/get/foo.bar", evt.GroupingHash)
+	assert.Equal(t, map[string]string{"job": "users/get", "event": "foo.bar"}, evt.MetaData["health"])
+	assert.Equal(t, map[string]string{"user": "bob"}, evt.MetaData["kvs"])
+	assert.Empty(t, evt.Exceptions[0].Stacktrace)
+}
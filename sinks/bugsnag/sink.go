@@ -1,35 +1,49 @@
 package bugsnag
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"github.com/gocraft/health"
+	"github.com/gocraft/health/stack"
 	"os"
+	"strings"
 )
 
-// This sink emits to a StatsD deaemon by sending it a UDP packet.
+var severities = map[health.Level]string{
+	health.LevelTrace: "info",
+	health.LevelDebug: "info",
+	health.LevelInfo:  "info",
+	health.LevelWarn:  "warning",
+	health.LevelError: "error",
+	health.LevelFatal: "error",
+}
+
+// Sink notifies Bugsnag about emits at or above MinLevel. Errors are reported with the stack trace the Job captured;
+// other emits are reported with their rendered line as the message. Each notification uses the job as its context,
+// the event (or completion status) as its error class, and is grouped by job and event.
 type Sink struct {
 	*Config
-	cmdChan  chan *cmdEventErr
+	cmdChan  chan *Notification
 	doneChan chan int
 }
 
-type cmdEventErr struct {
-	Job   string
-	Event string
-	Err   *health.UnmutedError
-	Kvs   map[string]string
-}
-
 func NewSink(config *Config) *Sink {
 	const maxChanSize = 25
 
 	if config.Endpoint == "" {
 		config.Endpoint = "https://notify.bugsnag.com/"
 	}
+	if config.MinLevel == health.LevelNone {
+		config.MinLevel = health.LevelError
+	}
+	if config.NotifyFunc == nil {
+		config.NotifyFunc = Send
+	}
 
 	s := &Sink{
 		Config:   config,
-		cmdChan:  make(chan *cmdEventErr, maxChanSize),
+		cmdChan:  make(chan *Notification, maxChanSize),
 		doneChan: make(chan int),
 	}
 
@@ -39,14 +53,14 @@ func NewSink(config *Config) *Sink {
 }
 
 func (s *Sink) EmitEvent(job string, event string, kvs map[string]string) {
-	// no-op
+	s.notify(health.EmitLevel(kvs, health.LevelInfo), job, event, kvs, nil, nil, func(w *health.WriterSink) { w.EmitEvent(job, event, kvs) })
 }
 
 func (s *Sink) EmitEventErr(job string, event string, inputErr error, kvs map[string]string) {
 	switch inputErr := inputErr.(type) {
 	case *health.UnmutedError:
 		if !inputErr.Emitted {
-			s.cmdChan <- &cmdEventErr{Job: job, Event: event, Err: inputErr, Kvs: kvs}
+			s.notify(health.EmitLevel(kvs, health.LevelError), job, event, kvs, inputErr, inputErr.Stack, nil)
 		}
 	case *health.MutedError:
 		// Do nothing!
@@ -56,17 +70,48 @@ func (s *Sink) EmitEventErr(job string, event string, inputErr error, kvs map[st
 }
 
 func (s *Sink) EmitTiming(job string, event string, nanos int64, kvs map[string]string) {
-	// no-op
+	s.notify(health.EmitLevel(kvs, health.LevelInfo), job, event, kvs, nil, nil, func(w *health.WriterSink) { w.EmitTiming(job, event, nanos, kvs) })
 }
 
 func (s *Sink) EmitComplete(job string, status health.CompletionStatus, nanos int64, kvs map[string]string) {
-	// no-op
+	s.notify(health.EmitLevel(kvs, health.CompletionLevel(status)), job, status.String(), kvs, nil, nil, func(w *health.WriterSink) { w.EmitComplete(job, status, nanos, kvs) })
+}
+
+func (s *Sink) EmitGauge(job string, event string, value float64, kvs map[string]string) {
+	s.notify(health.EmitLevel(kvs, health.LevelInfo), job, event, kvs, nil, nil, func(w *health.WriterSink) { w.EmitGauge(job, event, value, kvs) })
+}
+
+func (s *Sink) EmitCount(job string, event string, delta int64, kvs map[string]string) {
+	s.notify(health.EmitLevel(kvs, health.LevelInfo), job, event, kvs, nil, nil, func(w *health.WriterSink) { w.EmitCount(job, event, delta, kvs) })
 }
 
 func (s *Sink) ShutdownServer() {
 	s.doneChan <- 1
 }
 
+// notify queues a notification at lvl, unless lvl is below MinLevel. If err is nil, the message is the emit rendered with emit.
+func (s *Sink) notify(lvl health.Level, job, event string, kvs map[string]string, err error, trace *stack.Trace, emit func(w *health.WriterSink)) {
+	if lvl < s.MinLevel {
+		return
+	}
+
+	if err == nil {
+		var b bytes.Buffer
+		emit(&health.WriterSink{Writer: &b, NoTimestamp: true})
+		err = errors.New(strings.TrimSuffix(b.String(), "\n"))
+	}
+
+	s.cmdChan <- &Notification{
+		Job:          job,
+		Event:        event,
+		Err:          err,
+		Stack:        trace,
+		Severity:     severities[lvl],
+		GroupingHash: job + "/" + event,
+		Kvs:          kvs,
+	}
+}
+
 func errorProcessingLoop(sink *Sink) {
 	cmdChan := sink.cmdChan
 	doneChan := sink.doneChan
@@ -74,9 +119,9 @@ func errorProcessingLoop(sink *Sink) {
 	for {
 		select {
 		case <-doneChan:
-			break
-		case cmd := <-cmdChan:
-			if err := Notify(sink.Config, cmd.Job, cmd.Event, cmd.Err, cmd.Err.Stack); err != nil {
+			return
+		case n := <-cmdChan:
+			if err := sink.NotifyFunc(sink.Config, n); err != nil {
 				fmt.Fprintf(os.Stderr, "bugsnag.Notify: could not notify bugsnag. err=%v\n", err)
 			}
 		}
@@ -51,3 +51,70 @@ func TestSink(t *testing.T) {
 		// yay
 	}
 }
+
+func newTestSink(minLevel health.Level) (*Sink, chan *Notification) {
+	notifications := make(chan *Notification, 10)
+	s := NewSink(&Config{
+		MinLevel: minLevel,
+		NotifyFunc: func(config *Config, n *Notification) error {
+			notifications <- n
+			return nil
+		},
+	})
+	return s, notifications
+}
+
+func TestSinkNotification(t *testing.T) {
+	s, notifications := newTestSink(health.LevelNone)
+	defer s.ShutdownServer()
+
+	trace := stack.NewTrace(0)
+	s.EmitEventErr("thejob", "theevent", &health.UnmutedError{Err: fmt.Errorf("err str"), Stack: trace}, map[string]string{"user": "bob"})
+
+	n := <-notifications
+	assert.Equal(t, "thejob", n.Job)
+	assert.Equal(t, "theevent", n.Event)
+	assert.Equal(t, "err str", n.Err.Error())
+	assert.Equal(t, trace, n.Stack)
+	assert.Equal(t, "error", n.Severity)
+	assert.Equal(t, "thejob/theevent", n.GroupingHash)
+	assert.Equal(t, map[string]string{"user": "bob"}, n.Kvs)
+}
+
+func TestSinkMinLevel(t *testing.T) {
+	s, notifications := newTestSink(health.LevelNone)
+	defer s.ShutdownServer()
+
+	s.EmitEvent("thejob", "ignored", nil)
+	s.EmitEvent("thejob", "ignored", map[string]string{"level": "warn"})
+	s.EmitEventErr("thejob", "ignored", &health.UnmutedError{Err: fmt.Errorf("err str")}, map[string]string{"level": "info"})
+	s.EmitEventErr("thejob", "ignored", &health.MutedError{Err: fmt.Errorf("err str")}, nil)
+	s.EmitComplete("thejob", health.Success, 100, nil)
+	s.EmitEvent("thejob", "fatal", map[string]string{"level": "FATAL"})
+	s.EmitComplete("thejob", health.Panic, 100, nil)
+
+	n := <-notifications
+	assert.Equal(t, "fatal", n.Event)
+	assert.Equal(t, "error", n.Severity)
+	assert.Equal(t, "job:thejob event:fatal kvs:[level:FATAL]", n.Err.Error())
+	assert.Nil(t, n.Stack)
+
+	n = <-notifications
+	assert.Equal(t, "panic", n.Event)
+	assert.Equal(t, "thejob/panic", n.GroupingHash)
+
+	time.Sleep(1 * time.Millisecond)
+	assert.Empty(t, notifications)
+}
+
+func TestSinkWarnLevel(t *testing.T) {
+	s, notifications := newTestSink(health.LevelWarn)
+	defer s.ShutdownServer()
+
+	s.EmitCount("thejob", "ignored", 1, nil)
+	s.EmitGauge("thejob", "heap", 5, map[string]string{"level": "warning"})
+
+	n := <-notifications
+	assert.Equal(t, "heap", n.Event)
+	assert.Equal(t, "warning", n.Severity)
+}
@@ -0,0 +1,196 @@
+package cloudwatch
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/gocraft/health"
+	"github.com/gocraft/health/internal/batcher"
+	"os"
+	"strings"
+	"time"
+)
+
+// CloudWatch Logs limits on a PutLogEvents call. Each event counts as its message's length plus 26 bytes.
+const (
+	maxBatchEvents     = 10000
+	maxBatchBytes      = 1048576
+	perEventOverhead   = 26
+	maxSequenceRetries = 3
+)
+
+// LogEvent is a line to put, with its timestamp in milliseconds since the epoch.
+type LogEvent struct {
+	Message   string
+	Timestamp int64
+}
+
+// Client is the part of the CloudWatch Logs API the sink uses. Wrap your AWS SDK client in it.
+type Client interface {
+	// PutLogEvents puts events into the stream and returns the next sequence token.
+	// If sequenceToken is wrong, it should return an *InvalidSequenceTokenError.
+	PutLogEvents(group, stream string, events []LogEvent, sequenceToken *string) (nextSequenceToken *string, err error)
+
+	// SequenceToken returns the stream's current upload sequence token, eg from DescribeLogStreams.
+	SequenceToken(group, stream string) (*string, error)
+}
+
+// InvalidSequenceTokenError is returned by Client.PutLogEvents for an InvalidSequenceTokenException.
+// ExpectedSequenceToken is the token CloudWatch said it expected, if it said.
+type InvalidSequenceTokenError struct {
+	ExpectedSequenceToken *string
+}
+
+func (e *InvalidSequenceTokenError) Error() string {
+	return "cloudwatch: invalid sequence token"
+}
+
+type Config struct {
+	LogGroup  string
+	LogStream string
+
+	Client Client
+
+	// FlushInterval is how often a partial batch is put. Defaults to 5s.
+	FlushInterval time.Duration
+
+	// ErrorHandler, if set, is called when a put fails. If nil, the error is printed to stderr.
+	ErrorHandler func(error)
+
+	// Name, if set, identifies this sink in errors passed to ErrorHandler or printed to stderr (see health.SinkError).
+	Name string
+}
+
+// Sink puts each emit into a CloudWatch Logs stream, rendered like a health.WriterSink's lines minus the timestamp
+// (CloudWatch keeps its own). Lines are batched and put in the background; call Close before exiting to put what's left.
+type Sink struct {
+	*Config
+
+	batcher *batcher.Batcher
+
+	// batch and sequenceToken are only used by the batcher's goroutine.
+	batch         []LogEvent
+	sequenceToken *string
+}
+
+func NewSink(config *Config) *Sink {
+	const maxChanSize = 1024
+
+	if config.FlushInterval <= 0 {
+		config.FlushInterval = 5 * time.Second
+	}
+
+	s := &Sink{Config: config}
+	s.batcher = batcher.New(maxChanSize, config.FlushInterval, s.add, s.putBatch, s.handleError)
+	return s
+}
+
+func (s *Sink) EmitEvent(job string, event string, kvs map[string]string) {
+	s.send(func(w *health.WriterSink) { w.EmitEvent(job, event, kvs) })
+}
+
+func (s *Sink) EmitEventErr(job string, event string, inputErr error, kvs map[string]string) {
+	s.send(func(w *health.WriterSink) { w.EmitEventErr(job, event, inputErr, kvs) })
+}
+
+func (s *Sink) EmitTiming(job string, event string, nanos int64, kvs map[string]string) {
+	s.send(func(w *health.WriterSink) { w.EmitTiming(job, event, nanos, kvs) })
+}
+
+func (s *Sink) EmitComplete(job string, status health.CompletionStatus, nanos int64, kvs map[string]string) {
+	s.send(func(w *health.WriterSink) { w.EmitComplete(job, status, nanos, kvs) })
+}
+
+func (s *Sink) EmitGauge(job string, event string, value float64, kvs map[string]string) {
+	s.send(func(w *health.WriterSink) { w.EmitGauge(job, event, value, kvs) })
+}
+
+func (s *Sink) EmitCount(job string, event string, delta int64, kvs map[string]string) {
+	s.send(func(w *health.WriterSink) { w.EmitCount(job, event, delta, kvs) })
+}
+
+// Flush puts everything emitted so far, and returns the error if a put failed.
+func (s *Sink) Flush() error {
+	return s.batcher.Flush()
+}
+
+// Close puts everything emitted so far and stops the background goroutine. The sink can't be used afterwards,
+// except to call Close again, which does nothing.
+func (s *Sink) Close() error {
+	return s.batcher.Close()
+}
+
+func (s *Sink) send(emit func(w *health.WriterSink)) {
+	var b bytes.Buffer
+	emit(&health.WriterSink{Writer: &b, NoTimestamp: true})
+
+	s.batcher.Add(LogEvent{
+		Message:   strings.TrimSuffix(b.String(), "\n"),
+		Timestamp: time.Now().UnixNano() / int64(time.Millisecond),
+	})
+}
+
+func (s *Sink) handleError(err error) {
+	err = health.NameError(s.Name, err)
+	if s.ErrorHandler != nil {
+		s.ErrorHandler(err)
+	} else {
+		fmt.Fprintf(os.Stderr, "cloudwatch.Sink: could not put log events. err=%v\n", err)
+	}
+}
+
+// add adds e to the batch, and reports whether it has as many events as a put can take.
+func (s *Sink) add(e interface{}) bool {
+	s.batch = append(s.batch, e.(LogEvent))
+	return len(s.batch) >= maxBatchEvents
+}
+
+// putBatch puts the batch and empties it.
+func (s *Sink) putBatch() error {
+	err := s.putAll(s.batch)
+	s.batch = nil
+	return err
+}
+
+// putAll puts events in as many batches as the limits need, and returns the first error.
+func (s *Sink) putAll(events []LogEvent) error {
+	var firstErr error
+	for len(events) > 0 {
+		n, size := 0, 0
+		for n < len(events) && n < maxBatchEvents {
+			eventSize := len(events[n].Message) + perEventOverhead
+			if n > 0 && size+eventSize > maxBatchBytes {
+				break
+			}
+			size += eventSize
+			n++
+		}
+
+		if err := s.put(events[:n]); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		events = events[n:]
+	}
+	return firstErr
+}
+
+// put puts one batch, refetching the sequence token and retrying if it's rejected.
+func (s *Sink) put(events []LogEvent) error {
+	for retry := 0; ; retry++ {
+		next, err := s.Client.PutLogEvents(s.LogGroup, s.LogStream, events, s.sequenceToken)
+		if err == nil {
+			s.sequenceToken = next
+			return nil
+		}
+
+		tokenErr, ok := err.(*InvalidSequenceTokenError)
+		if !ok || retry >= maxSequenceRetries {
+			return err
+		}
+
+		if tokenErr.ExpectedSequenceToken != nil {
+			s.sequenceToken = tokenErr.ExpectedSequenceToken
+		} else if s.sequenceToken, err = s.Client.SequenceToken(s.LogGroup, s.LogStream); err != nil {
+			return err
+		}
+	}
+}
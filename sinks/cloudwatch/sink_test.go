@@ -0,0 +1,143 @@
+package cloudwatch
+
+import (
+	"errors"
+	"github.com/gocraft/health"
+	"github.com/stretchr/testify/assert"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClient is a CloudWatch Logs stream that checks sequence tokens like the real one.
+type fakeClient struct {
+	mutex   sync.Mutex
+	token   int
+	batches [][]LogEvent
+
+	// staleTokens makes the next few puts fail as if another writer had put first.
+	staleTokens int
+	// omitExpected leaves ExpectedSequenceToken out of InvalidSequenceTokenErrors.
+	omitExpected bool
+	tokenFetches int
+	err          error
+}
+
+func (c *fakeClient) PutLogEvents(group, stream string, events []LogEvent, sequenceToken *string) (*string, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.err != nil {
+		return nil, c.err
+	}
+	if c.staleTokens > 0 {
+		c.staleTokens--
+		c.token++
+	}
+	current := strconv.Itoa(c.token)
+	if (c.token == 0 && sequenceToken != nil) || (c.token > 0 && (sequenceToken == nil || *sequenceToken != current)) {
+		if c.omitExpected {
+			return nil, &InvalidSequenceTokenError{}
+		}
+		return nil, &InvalidSequenceTokenError{ExpectedSequenceToken: &current}
+	}
+
+	c.batches = append(c.batches, append([]LogEvent(nil), events...))
+	c.token++
+	next := strconv.Itoa(c.token)
+	return &next, nil
+}
+
+func (c *fakeClient) SequenceToken(group, stream string) (*string, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.tokenFetches++
+	if c.token == 0 {
+		return nil, nil
+	}
+	current := strconv.Itoa(c.token)
+	return &current, nil
+}
+
+func (c *fakeClient) Messages() []string {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	var msgs []string
+	for _, b := range c.batches {
+		for _, e := range b {
+			msgs = append(msgs, e.Message)
+		}
+	}
+	return msgs
+}
+
+func newTestSink(client Client) *Sink {
+	return NewSink(&Config{LogGroup: "group", LogStream: "stream", Client: client, FlushInterval: time.Hour})
+}
+
+func TestSink(t *testing.T) {
+	client := &fakeClient{}
+	s := newTestSink(client)
+
+	s.EmitEvent("myjob", "myevent", map[string]string{"foo": "bar"})
+	assert.NoError(t, s.Flush())
+	s.EmitComplete("myjob", health.Success, 34567890, nil)
+	assert.NoError(t, s.Close())
+	assert.NoError(t, s.Close())
+
+	assert.Equal(t, []string{"job:myjob event:myevent kvs:[foo:bar]", "job:myjob status:success time:34 ms"}, client.Messages())
+	assert.Equal(t, 2, len(client.batches))
+}
+
+func TestSinkInvalidSequenceToken(t *testing.T) {
+	client := &fakeClient{}
+	s := newTestSink(client)
+
+	s.EmitEvent("myjob", "first", nil)
+	assert.NoError(t, s.Flush())
+
+	client.staleTokens = 1
+	s.EmitEvent("myjob", "second", nil)
+	assert.NoError(t, s.Flush())
+
+	client.staleTokens = 1
+	client.omitExpected = true
+	s.EmitEvent("myjob", "third", nil)
+	assert.NoError(t, s.Close())
+
+	assert.Equal(t, 3, len(client.Messages()))
+	assert.Equal(t, 1, client.tokenFetches)
+}
+
+func TestSinkBatchLimits(t *testing.T) {
+	client := &fakeClient{}
+	s := newTestSink(client)
+
+	big := strings.Repeat("x", 300000)
+	for i := 0; i < 4; i++ {
+		s.EmitEvent("myjob", "myevent", map[string]string{"big": big})
+	}
+	assert.NoError(t, s.Close())
+
+	var sizes []int
+	for _, b := range client.batches {
+		sizes = append(sizes, len(b))
+	}
+	assert.Equal(t, []int{3, 1}, sizes)
+}
+
+func TestSinkErrorHandler(t *testing.T) {
+	errs := make(chan error, 1)
+	s := NewSink(&Config{Client: &fakeClient{err: errors.New("throttled")}, FlushInterval: 10 * time.Millisecond, ErrorHandler: func(err error) {
+		select {
+		case errs <- err:
+		default:
+		}
+	}})
+	defer s.Close()
+
+	s.EmitEvent("myjob", "myevent", nil)
+	assert.Equal(t, "throttled", (<-errs).Error())
+}
@@ -0,0 +1,139 @@
+package dogstatsd
+
+import (
+	"bytes"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// UDPClient is a Client that sends metrics to a DogStatsD agent over UDP, batching them into as few packets as it can.
+// A packet is sent when the next metric wouldn't fit in MaxPacketSize, every flush interval, and on Flush/Close.
+type UDPClient struct {
+	// MaxPacketSize is the most bytes sent in one packet. Defaults to 1432, which fits in a typical ethernet MTU.
+	MaxPacketSize int
+
+	conn     net.Conn
+	mutex    sync.Mutex
+	batch    bytes.Buffer
+	doneChan chan int
+	closed   bool
+}
+
+var _ Client = &UDPClient{}
+
+// NewUDPClient returns a UDPClient sending to addr, eg "127.0.0.1:8125". If flushInterval is positive, batches are sent at least that often.
+func NewUDPClient(addr string, flushInterval time.Duration) (*UDPClient, error) {
+	conn, err := net.DialTimeout("udp", addr, 2*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &UDPClient{MaxPacketSize: 1432, conn: conn}
+	if flushInterval > 0 {
+		c.doneChan = make(chan int)
+		go udpClientFlushLoop(c, flushInterval)
+	}
+	return c, nil
+}
+
+func (c *UDPClient) Count(name string, value int64, tags []string, rate float64) error {
+	return c.send(name, strconv.FormatInt(value, 10), "c", tags, rate)
+}
+
+func (c *UDPClient) Gauge(name string, value float64, tags []string, rate float64) error {
+	return c.send(name, strconv.FormatFloat(value, 'f', -1, 64), "g", tags, rate)
+}
+
+func (c *UDPClient) Histogram(name string, value float64, tags []string, rate float64) error {
+	return c.send(name, strconv.FormatFloat(value, 'f', -1, 64), "h", tags, rate)
+}
+
+func (c *UDPClient) Timing(name string, value time.Duration, tags []string, rate float64) error {
+	return c.send(name, strconv.FormatFloat(float64(value)/float64(time.Millisecond), 'f', -1, 64), "ms", tags, rate)
+}
+
+// Flush sends any batched metrics.
+func (c *UDPClient) Flush() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.flushBatch()
+}
+
+// Close sends any batched metrics and closes the connection. Calling it more than once is fine.
+func (c *UDPClient) Close() error {
+	c.mutex.Lock()
+	if c.closed {
+		c.mutex.Unlock()
+		return nil
+	}
+	c.closed = true
+	c.mutex.Unlock()
+
+	if c.doneChan != nil {
+		c.doneChan <- 1
+	}
+	err := c.Flush()
+	if closeErr := c.conn.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// send adds a metric, formatted as "name:value|type|@rate|#tag,tag", to the batch.
+func (c *UDPClient) send(name, value, metricType string, tags []string, rate float64) error {
+	var line bytes.Buffer
+	line.WriteString(name)
+	line.WriteByte(':')
+	line.WriteString(value)
+	line.WriteByte('|')
+	line.WriteString(metricType)
+	if rate < 1 {
+		line.WriteString("|@")
+		line.WriteString(strconv.FormatFloat(rate, 'f', -1, 64))
+	}
+	for i, tag := range tags {
+		if i == 0 {
+			line.WriteString("|#")
+		} else {
+			line.WriteByte(',')
+		}
+		line.WriteString(tag)
+	}
+	line.WriteByte('\n')
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	var err error
+	if c.batch.Len() > 0 && c.batch.Len()+line.Len() > c.MaxPacketSize {
+		err = c.flushBatch()
+	}
+	c.batch.Write(line.Bytes())
+	return err
+}
+
+// flushBatch sends the current batch. mutex must be held.
+func (c *UDPClient) flushBatch() error {
+	if c.batch.Len() == 0 {
+		return nil
+	}
+	_, err := c.conn.Write(c.batch.Bytes())
+	c.batch.Reset()
+	return err
+}
+
+func udpClientFlushLoop(c *UDPClient, flushInterval time.Duration) {
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.doneChan:
+			return
+		case <-ticker.C:
+			c.Flush()
+		}
+	}
+}
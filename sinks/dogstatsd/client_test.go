@@ -0,0 +1,61 @@
+package dogstatsd
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func readPacket(t *testing.T, conn net.PacketConn) string {
+	buf := make([]byte, 2048)
+	assert.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+	n, _, err := conn.ReadFrom(buf)
+	assert.NoError(t, err)
+	return string(buf[:n])
+}
+
+func TestUDPClient(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	c, err := NewUDPClient(conn.LocalAddr().String(), 0)
+	assert.NoError(t, err)
+
+	c.Count("signup.email_sent", 1, []string{"job:signup", "plan:pro"}, 1)
+	c.Gauge("queue", 3.5, nil, 1)
+	c.Histogram("fetch", 1.204, []string{"job:signup"}, 0.5)
+	c.Timing("fetch", 1204*time.Microsecond, nil, 1)
+	assert.NoError(t, c.Flush())
+
+	assert.Equal(t, "signup.email_sent:1|c|#job:signup,plan:pro\n"+
+		"queue:3.5|g\n"+
+		"fetch:1.204|h|@0.5|#job:signup\n"+
+		"fetch:1.204|ms\n", readPacket(t, conn))
+
+	assert.NoError(t, c.Close())
+	assert.NoError(t, c.Close())
+}
+
+func TestUDPClientBatches(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	c, err := NewUDPClient(conn.LocalAddr().String(), time.Hour)
+	assert.NoError(t, err)
+	c.MaxPacketSize = 40
+
+	// Each line is 20 bytes, so two fit in a packet.
+	for i := 0; i < 5; i++ {
+		c.Count("my.counter", 1, []string{"a:b"}, 1)
+	}
+	assert.Equal(t, "my.counter:1|c|#a:b\nmy.counter:1|c|#a:b\n", readPacket(t, conn))
+	assert.Equal(t, "my.counter:1|c|#a:b\nmy.counter:1|c|#a:b\n", readPacket(t, conn))
+
+	// Close sends the rest.
+	assert.NoError(t, c.Close())
+	assert.Equal(t, 1, strings.Count(readPacket(t, conn), "\n"))
+}
@@ -0,0 +1,165 @@
+package dogstatsd
+
+import (
+	"fmt"
+	"github.com/gocraft/health"
+	"io"
+	"os"
+	"sort"
+	"time"
+)
+
+// Client sends metrics to DogStatsD. *statsd.Client from github.com/DataDog/datadog-go implements it, and so does UDPClient.
+type Client interface {
+	Count(name string, value int64, tags []string, rate float64) error
+	Gauge(name string, value float64, tags []string, rate float64) error
+	Histogram(name string, value float64, tags []string, rate float64) error
+	Timing(name string, value time.Duration, tags []string, rate float64) error
+}
+
+type Config struct {
+	// Client sends the metrics. Use NewUDPClient to send them to a DogStatsD agent in batched UDP packets.
+	Client Client
+
+	// Prefix, if set, starts every metric name, eg "myapp" makes "myapp.signup.email_sent".
+	Prefix string
+
+	// TagKeys, if non-empty, are the only kvs keys that become tags. Use it to keep high-cardinality kvs (eg, user ids) out of Datadog.
+	// If empty, every kvs key becomes a tag, up to MaxTags.
+	TagKeys []string
+
+	// MaxTags is the most tags made from kvs per metric. The job and status tags don't count towards it.
+	// Keys past the limit are left out, in sorted order. Defaults to 10 if it isn't positive.
+	MaxTags int
+
+	// Histograms sends timings as histograms (in ms) rather than as timings.
+	Histograms bool
+
+	// ErrorHandler, if set, is called with errors from Client. If nil, they're printed to stderr.
+	ErrorHandler func(error)
+
+	// Name, if set, identifies this sink in errors passed to ErrorHandler or printed to stderr (see health.SinkError).
+	Name string
+}
+
+// Sink sends emits to Datadog through DogStatsD, with kvs turned into "key:value" tags and the job as a "job" tag:
+//   - events count up "<event>", and errors "<event>.error"
+//   - timings are timings (or histograms) named "<event>"
+//   - completions count up "completion", tagged with "status", and are timed as "completion.time"
+//   - gauges and counts are gauges and counts named "<event>"
+//
+// Names are sanitized with health.SanitizeDottedMetricName, and tags with health.SanitizeMetricTag.
+type Sink struct {
+	*Config
+}
+
+var _ health.Sink = &Sink{}
+
+func NewSink(config *Config) *Sink {
+	if config.MaxTags <= 0 {
+		config.MaxTags = 10
+	}
+	return &Sink{Config: config}
+}
+
+func (s *Sink) EmitEvent(job string, event string, kvs map[string]string) {
+	s.handleError(s.Client.Count(s.metricName(event), 1, s.tags(job, "", kvs), 1))
+}
+
+func (s *Sink) EmitEventErr(job string, event string, inputErr error, kvs map[string]string) {
+	s.handleError(s.Client.Count(s.metricName(event)+".error", 1, s.tags(job, "", kvs), 1))
+}
+
+func (s *Sink) EmitTiming(job string, event string, nanos int64, kvs map[string]string) {
+	s.timing(s.metricName(event), nanos, s.tags(job, "", kvs))
+}
+
+func (s *Sink) EmitComplete(job string, status health.CompletionStatus, nanos int64, kvs map[string]string) {
+	tags := s.tags(job, status.String(), kvs)
+	s.handleError(s.Client.Count(s.metricName("completion"), 1, tags, 1))
+	s.timing(s.metricName("completion.time"), nanos, tags)
+}
+
+func (s *Sink) EmitGauge(job string, event string, value float64, kvs map[string]string) {
+	s.handleError(s.Client.Gauge(s.metricName(event), value, s.tags(job, "", kvs), 1))
+}
+
+func (s *Sink) EmitCount(job string, event string, delta int64, kvs map[string]string) {
+	s.handleError(s.Client.Count(s.metricName(event), delta, s.tags(job, "", kvs), 1))
+}
+
+// Flush flushes Client if it has a Flush method, as UDPClient and *statsd.Client do.
+func (s *Sink) Flush() error {
+	if f, ok := s.Client.(health.Flusher); ok {
+		return health.NameError(s.Name, f.Flush())
+	}
+	return nil
+}
+
+// Close closes Client if it has a Close method, as UDPClient and *statsd.Client do, which sends anything still batched.
+func (s *Sink) Close() error {
+	if c, ok := s.Client.(io.Closer); ok {
+		return health.NameError(s.Name, c.Close())
+	}
+	return nil
+}
+
+func (s *Sink) timing(name string, nanos int64, tags []string) {
+	if s.Histograms {
+		s.handleError(s.Client.Histogram(name, float64(nanos)/float64(time.Millisecond), tags, 1))
+		return
+	}
+	s.handleError(s.Client.Timing(name, time.Duration(nanos), tags, 1))
+}
+
+// metricName returns name sanitized, with Prefix.
+func (s *Sink) metricName(name string) string {
+	name = health.SanitizeDottedMetricName(name)
+	if s.Prefix != "" {
+		return s.Prefix + "." + name
+	}
+	return name
+}
+
+// tags returns the job tag, the status tag if status is set, and then up to MaxTags tags from kvs, in key order.
+func (s *Sink) tags(job, status string, kvs map[string]string) []string {
+	tags := make([]string, 0, 2+len(kvs))
+	tags = append(tags, "job:"+health.SanitizeMetricTag(job))
+	if status != "" {
+		tags = append(tags, "status:"+status)
+	}
+
+	keys := make([]string, 0, len(kvs))
+	if len(s.TagKeys) > 0 {
+		for _, k := range s.TagKeys {
+			if _, ok := kvs[k]; ok {
+				keys = append(keys, k)
+			}
+		}
+	} else {
+		for k := range kvs {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	if len(keys) > s.MaxTags {
+		keys = keys[:s.MaxTags]
+	}
+
+	for _, k := range keys {
+		tags = append(tags, health.SanitizeMetricTag(k)+":"+health.SanitizeMetricTag(kvs[k]))
+	}
+	return tags
+}
+
+func (s *Sink) handleError(err error) {
+	if err == nil {
+		return
+	}
+	err = health.NameError(s.Name, err)
+	if s.ErrorHandler != nil {
+		s.ErrorHandler(err)
+	} else {
+		fmt.Fprintf(os.Stderr, "dogstatsd.Sink: could not send metric. err=%v\n", err)
+	}
+}
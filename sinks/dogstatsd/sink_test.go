@@ -0,0 +1,99 @@
+package dogstatsd
+
+import (
+	"errors"
+	"github.com/gocraft/health"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+type metric struct {
+	kind  string
+	name  string
+	value float64
+	tags  []string
+}
+
+type mockClient struct {
+	metrics []metric
+	err     error
+}
+
+func (c *mockClient) Count(name string, value int64, tags []string, rate float64) error {
+	c.metrics = append(c.metrics, metric{"count", name, float64(value), tags})
+	return c.err
+}
+
+func (c *mockClient) Gauge(name string, value float64, tags []string, rate float64) error {
+	c.metrics = append(c.metrics, metric{"gauge", name, value, tags})
+	return c.err
+}
+
+func (c *mockClient) Histogram(name string, value float64, tags []string, rate float64) error {
+	c.metrics = append(c.metrics, metric{"histogram", name, value, tags})
+	return c.err
+}
+
+func (c *mockClient) Timing(name string, value time.Duration, tags []string, rate float64) error {
+	c.metrics = append(c.metrics, metric{"timing", name, float64(value), tags})
+	return c.err
+}
+
+func TestSink(t *testing.T) {
+	client := &mockClient{}
+	s := NewSink(&Config{Client: client, Prefix: "myapp"})
+
+	s.EmitEvent("signup", "email_sent", map[string]string{"plan": "pro", "region": "us east"})
+	s.EmitEventErr("signup", "email_sent", errors.New("bounced"), nil)
+	s.EmitTiming("signup", "fetch", 1204000, nil)
+	s.EmitComplete("signup", health.Error, 2000000, map[string]string{"plan": "pro"})
+	s.EmitGauge("signup", "queue", 3.5, nil)
+	s.EmitCount("signup", "items", 7, nil)
+
+	assert.Equal(t, []metric{
+		{"count", "myapp.email_sent", 1, []string{"job:signup", "plan:pro", "region:us_east"}},
+		{"count", "myapp.email_sent.error", 1, []string{"job:signup"}},
+		{"timing", "myapp.fetch", 1204000, []string{"job:signup"}},
+		{"count", "myapp.completion", 1, []string{"job:signup", "status:error", "plan:pro"}},
+		{"timing", "myapp.completion.time", 2000000, []string{"job:signup", "status:error", "plan:pro"}},
+		{"gauge", "myapp.queue", 3.5, []string{"job:signup"}},
+		{"count", "myapp.items", 7, []string{"job:signup"}},
+	}, client.metrics)
+}
+
+func TestSinkHistograms(t *testing.T) {
+	client := &mockClient{}
+	s := NewSink(&Config{Client: client, Histograms: true})
+
+	s.EmitTiming("signup", "fetch|all", 1204000, nil)
+	assert.Equal(t, []metric{{"histogram", "fetch_all", 1.204, []string{"job:signup"}}}, client.metrics)
+}
+
+func TestSinkTagLimits(t *testing.T) {
+	client := &mockClient{}
+	s := NewSink(&Config{Client: client, TagKeys: []string{"plan", "region", "missing"}})
+	s.EmitEvent("signup", "email_sent", map[string]string{"plan": "pro", "region": "us", "user_id": "1234"})
+	assert.Equal(t, []string{"job:signup", "plan:pro", "region:us"}, client.metrics[0].tags)
+
+	client = &mockClient{}
+	s = NewSink(&Config{Client: client, MaxTags: 2})
+	s.EmitEvent("signup", "email_sent", map[string]string{"c": "3", "a": "1", "b": "2"})
+	assert.Equal(t, []string{"job:signup", "a:1", "b:2"}, client.metrics[0].tags)
+
+	client = &mockClient{}
+	s = NewSink(&Config{Client: client, MaxTags: -1})
+	assert.Equal(t, 10, s.MaxTags)
+	s.EmitEvent("signup", "email_sent", map[string]string{"c": "3", "a": "1", "b": "2"})
+	assert.Equal(t, []string{"job:signup", "a:1", "b:2", "c:3"}, client.metrics[0].tags)
+}
+
+func TestSinkErrorHandler(t *testing.T) {
+	var errs []error
+	client := &mockClient{err: errors.New("no agent")}
+	s := NewSink(&Config{Client: client, Name: "datadog", ErrorHandler: func(err error) { errs = append(errs, err) }})
+
+	s.EmitComplete("signup", health.Success, 1204, nil)
+	assert.Equal(t, 2, len(errs))
+	assert.Equal(t, "datadog: no agent", errs[0].Error())
+}
@@ -0,0 +1,114 @@
+package eventlog
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/gocraft/health"
+	"os"
+	"strings"
+)
+
+// Writer writes messages to the Windows Event Log. *eventlog.Log from golang.org/x/sys/windows/svc/eventlog implements it.
+type Writer interface {
+	Error(eid uint32, msg string) error
+	Warning(eid uint32, msg string) error
+	Info(eid uint32, msg string) error
+}
+
+// Event types, as named by the Event Log.
+const (
+	TypeError       = "error"
+	TypeWarning     = "warning"
+	TypeInformation = "information"
+)
+
+// Sink writes each emit to the Windows Event Log as an Error, Warning, or Information event.
+// Lines are rendered like a health.WriterSink's, minus the timestamp (the Event Log adds its own).
+// On Windows, use Open to get one that writes to an event source, registering it if needed:
+//
+//	sink, err := eventlog.Open("myservice")
+//	stream.AddSink(sink)
+//
+// The type comes from the emit's health.Level: fatal and error map to Error, warn to Warning, and the rest to Information.
+// So errors and completions with a Panic, Error, or Timeout status are Error events, and everything else is Information, unless kvs has a "level".
+type Sink struct {
+	Writer Writer
+
+	// EventID is the event id of every event written.
+	EventID uint32
+
+	// ErrorHandler, if set, is called with any error returned by Writer. If nil, the error is printed to stderr.
+	ErrorHandler func(error)
+
+	// Name, if set, identifies this sink in errors passed to ErrorHandler or printed to stderr (see health.SinkError).
+	Name string
+
+	// closer closes Writer, if the sink opened it.
+	closer interface {
+		Close() error
+	}
+}
+
+var _ health.Sink = &Sink{}
+
+func (s *Sink) EmitEvent(job string, event string, kvs map[string]string) {
+	s.write(health.EmitLevel(kvs, health.LevelInfo), func(w *health.WriterSink) { w.EmitEvent(job, event, kvs) })
+}
+
+func (s *Sink) EmitEventErr(job string, event string, inputErr error, kvs map[string]string) {
+	s.write(health.EmitLevel(kvs, health.LevelError), func(w *health.WriterSink) { w.EmitEventErr(job, event, inputErr, kvs) })
+}
+
+func (s *Sink) EmitTiming(job string, event string, nanos int64, kvs map[string]string) {
+	s.write(health.EmitLevel(kvs, health.LevelInfo), func(w *health.WriterSink) { w.EmitTiming(job, event, nanos, kvs) })
+}
+
+func (s *Sink) EmitComplete(job string, status health.CompletionStatus, nanos int64, kvs map[string]string) {
+	s.write(health.EmitLevel(kvs, health.CompletionLevel(status)), func(w *health.WriterSink) { w.EmitComplete(job, status, nanos, kvs) })
+}
+
+func (s *Sink) EmitGauge(job string, event string, value float64, kvs map[string]string) {
+	s.write(health.EmitLevel(kvs, health.LevelInfo), func(w *health.WriterSink) { w.EmitGauge(job, event, value, kvs) })
+}
+
+func (s *Sink) EmitCount(job string, event string, delta int64, kvs map[string]string) {
+	s.write(health.EmitLevel(kvs, health.LevelInfo), func(w *health.WriterSink) { w.EmitCount(job, event, delta, kvs) })
+}
+
+// Close closes the event log handle, if the sink was made by Open. Otherwise it does nothing.
+func (s *Sink) Close() error {
+	if s.closer == nil {
+		return nil
+	}
+	return s.closer.Close()
+}
+
+// write renders the line with a health.WriterSink and writes it to Writer as an event of the type for level.
+func (s *Sink) write(level health.Level, emit func(w *health.WriterSink)) {
+	var b bytes.Buffer
+	emit(&health.WriterSink{Writer: &b, NoTimestamp: true})
+	msg := strings.TrimSuffix(b.String(), "\n")
+
+	var err error
+	switch {
+	case level >= health.LevelError:
+		err = s.Writer.Error(s.EventID, msg)
+	case level == health.LevelWarn:
+		err = s.Writer.Warning(s.EventID, msg)
+	default:
+		err = s.Writer.Info(s.EventID, msg)
+	}
+
+	if err != nil {
+		s.handleError(err)
+	}
+}
+
+func (s *Sink) handleError(err error) {
+	err = health.NameError(s.Name, err)
+	if s.ErrorHandler != nil {
+		s.ErrorHandler(err)
+	} else {
+		fmt.Fprintf(os.Stderr, "eventlog.Sink: could not write event. err=%v\n", err)
+	}
+}
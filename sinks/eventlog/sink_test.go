@@ -0,0 +1,68 @@
+package eventlog
+
+import (
+	"errors"
+	"github.com/gocraft/health"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type fakeEvent struct {
+	typ string
+	eid uint32
+	msg string
+}
+
+type fakeWriter struct {
+	events []fakeEvent
+	err    error
+}
+
+func (w *fakeWriter) Error(eid uint32, msg string) error {
+	w.events = append(w.events, fakeEvent{TypeError, eid, msg})
+	return w.err
+}
+
+func (w *fakeWriter) Warning(eid uint32, msg string) error {
+	w.events = append(w.events, fakeEvent{TypeWarning, eid, msg})
+	return w.err
+}
+
+func (w *fakeWriter) Info(eid uint32, msg string) error {
+	w.events = append(w.events, fakeEvent{TypeInformation, eid, msg})
+	return w.err
+}
+
+func TestSink(t *testing.T) {
+	w := &fakeWriter{}
+	sink := &Sink{Writer: w, EventID: 7}
+
+	sink.EmitEvent("myjob", "myevent", nil)
+	sink.EmitEventErr("myjob", "myevent", errors.New("disk full"), nil)
+	sink.EmitTiming("myjob", "myevent", 1204, map[string]string{"level": "WARN"})
+	sink.EmitComplete("myjob", health.Timeout, 1204, nil)
+	sink.EmitComplete("myjob", health.Success, 1204, nil)
+	sink.EmitGauge("myjob", "myevent", 1, map[string]string{"level": "error"})
+	sink.EmitCount("myjob", "myevent", 1, map[string]string{"level": "debug"})
+
+	assert.Equal(t, []fakeEvent{
+		{TypeInformation, 7, "job:myjob event:myevent"},
+		{TypeError, 7, "job:myjob event:myevent err:disk full"},
+		{TypeWarning, 7, "job:myjob event:myevent time:1204 ns kvs:[level:WARN]"},
+		{TypeError, 7, "job:myjob status:timeout time:1204 ns"},
+		{TypeInformation, 7, "job:myjob status:success time:1204 ns"},
+		{TypeError, 7, "job:myjob event:myevent gauge:1 kvs:[level:error]"},
+		{TypeInformation, 7, "job:myjob event:myevent count:1 kvs:[level:debug]"},
+	}, w.events)
+	assert.NoError(t, sink.Close())
+}
+
+func TestSinkErrorHandler(t *testing.T) {
+	var gotErr error
+	sink := &Sink{Writer: &fakeWriter{err: errors.New("log full")}, Name: "eventlog", ErrorHandler: func(err error) {
+		gotErr = err
+	}}
+
+	sink.EmitEvent("myjob", "myevent", nil)
+	assert.Equal(t, "eventlog: log full", gotErr.Error())
+}
@@ -0,0 +1,37 @@
+//go:build windows
+
+package eventlog
+
+import (
+	"golang.org/x/sys/windows/registry"
+	"golang.org/x/sys/windows/svc/eventlog"
+)
+
+// sourcesKey is where event sources of the Application log are registered.
+const sourcesKey = `SYSTEM\CurrentControlSet\Services\EventLog\Application\`
+
+// Open returns a Sink that writes to the Application log as source, registering source first if it isn't already.
+// Registering needs administrator rights, so services usually do it once at install time; after that, Open doesn't need them.
+// Close the sink when done to release the handle.
+func Open(source string) (*Sink, error) {
+	if !isRegistered(source) {
+		if err := eventlog.InstallAsEventCreate(source, eventlog.Error|eventlog.Warning|eventlog.Info); err != nil {
+			return nil, err
+		}
+	}
+
+	l, err := eventlog.Open(source)
+	if err != nil {
+		return nil, err
+	}
+	return &Sink{Writer: l, EventID: 1, closer: l}, nil
+}
+
+func isRegistered(source string) bool {
+	k, err := registry.OpenKey(registry.LOCAL_MACHINE, sourcesKey+source, registry.QUERY_VALUE)
+	if err != nil {
+		return false
+	}
+	k.Close()
+	return true
+}
@@ -0,0 +1,153 @@
+package kafka
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/gocraft/health"
+	"github.com/gocraft/health/internal/batcher"
+	"os"
+	"time"
+)
+
+// Message is a message to produce to Kafka.
+type Message struct {
+	Topic string
+	Key   []byte
+	Value []byte
+}
+
+// Producer sends batches of messages to Kafka. Wrap your Kafka client (eg, a sarama SyncProducer) in it.
+type Producer interface {
+	Produce(messages []Message) error
+}
+
+type Config struct {
+	// Topic is the topic every message is produced to.
+	Topic string
+
+	Producer Producer
+
+	// BatchSize is the most messages handed to Producer at once. Defaults to 100.
+	BatchSize int
+
+	// FlushInterval is how often a partial batch is produced. Defaults to 1s.
+	FlushInterval time.Duration
+
+	// ErrorHandler, if set, is called with errors from Producer. If nil, they're printed to stderr.
+	ErrorHandler func(error)
+
+	// Name, if set, identifies this sink in errors passed to ErrorHandler or printed to stderr (see health.SinkError).
+	Name string
+}
+
+// Sink produces each emit to Kafka as a JSON object, in the same format as health.JsonSink.
+// Messages are keyed by job name, so each job's messages land on the same partition.
+// They're batched and produced in the background; call Close before exiting to produce what's left.
+type Sink struct {
+	*Config
+
+	batcher *batcher.Batcher
+
+	// batch is only used by the batcher's goroutine.
+	batch []Message
+}
+
+func NewSink(config *Config) *Sink {
+	const maxChanSize = 1024
+
+	if config.BatchSize <= 0 {
+		config.BatchSize = 100
+	}
+	if config.FlushInterval <= 0 {
+		config.FlushInterval = time.Second
+	}
+
+	s := &Sink{Config: config}
+	s.batcher = batcher.New(maxChanSize, config.FlushInterval, s.add, s.produceBatch, s.handleError)
+	return s
+}
+
+func (s *Sink) EmitEvent(job string, event string, kvs map[string]string) {
+	s.send(job, func(js *health.JsonSink) { js.EmitEvent(job, event, kvs) })
+}
+
+func (s *Sink) EmitEventErr(job string, event string, inputErr error, kvs map[string]string) {
+	s.send(job, func(js *health.JsonSink) { js.EmitEventErr(job, event, inputErr, kvs) })
+}
+
+func (s *Sink) EmitTiming(job string, event string, nanos int64, kvs map[string]string) {
+	s.send(job, func(js *health.JsonSink) { js.EmitTiming(job, event, nanos, kvs) })
+}
+
+func (s *Sink) EmitComplete(job string, status health.CompletionStatus, nanos int64, kvs map[string]string) {
+	s.send(job, func(js *health.JsonSink) { js.EmitComplete(job, status, nanos, kvs) })
+}
+
+func (s *Sink) EmitGauge(job string, event string, value float64, kvs map[string]string) {
+	s.send(job, func(js *health.JsonSink) { js.EmitGauge(job, event, value, kvs) })
+}
+
+func (s *Sink) EmitCount(job string, event string, delta int64, kvs map[string]string) {
+	s.send(job, func(js *health.JsonSink) { js.EmitCount(job, event, delta, kvs) })
+}
+
+// Flush produces everything emitted so far, and returns the error from Producer, if any.
+func (s *Sink) Flush() error {
+	return s.batcher.Flush()
+}
+
+// Close produces everything emitted so far and stops the background goroutine. The sink can't be used afterwards,
+// except to call Close again, which does nothing.
+func (s *Sink) Close() error {
+	return s.batcher.Close()
+}
+
+// send renders an emit with a health.JsonSink and queues it as a message.
+func (s *Sink) send(job string, emit func(js *health.JsonSink)) {
+	var b bytes.Buffer
+	emit(&health.JsonSink{Writer: &b})
+
+	s.batcher.Add(Message{
+		Topic: s.Topic,
+		Key:   []byte(job),
+		Value: bytes.TrimSuffix(b.Bytes(), []byte("\n")),
+	})
+}
+
+func (s *Sink) handleError(err error) {
+	err = health.NameError(s.Name, err)
+	if s.ErrorHandler != nil {
+		s.ErrorHandler(err)
+	} else {
+		fmt.Fprintf(os.Stderr, "kafka.Sink: could not produce. err=%v\n", err)
+	}
+}
+
+// add adds msg to the batch, and reports whether it's full.
+func (s *Sink) add(msg interface{}) bool {
+	s.batch = append(s.batch, msg.(Message))
+	return len(s.batch) >= s.BatchSize
+}
+
+// produceBatch produces the batch and empties it.
+func (s *Sink) produceBatch() error {
+	err := s.produce(s.batch)
+	s.batch = nil
+	return err
+}
+
+// produce hands batch to Producer, BatchSize messages at a time, and returns the first error.
+func (s *Sink) produce(batch []Message) error {
+	var firstErr error
+	for len(batch) > 0 {
+		n := len(batch)
+		if n > s.BatchSize {
+			n = s.BatchSize
+		}
+		if err := s.Producer.Produce(batch[:n]); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		batch = batch[n:]
+	}
+	return firstErr
+}
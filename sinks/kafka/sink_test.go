@@ -0,0 +1,124 @@
+package kafka
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/gocraft/health"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+	"time"
+)
+
+type mockProducer struct {
+	mutex   sync.Mutex
+	batches [][]Message
+	err     error
+}
+
+func (p *mockProducer) Produce(messages []Message) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.batches = append(p.batches, append([]Message(nil), messages...))
+	return p.err
+}
+
+func (p *mockProducer) Messages() []Message {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	var msgs []Message
+	for _, b := range p.batches {
+		msgs = append(msgs, b...)
+	}
+	return msgs
+}
+
+func TestSink(t *testing.T) {
+	producer := &mockProducer{}
+	s := NewSink(&Config{Topic: "health", Producer: producer, FlushInterval: time.Hour})
+
+	s.EmitEvent("myjob", "myevent", map[string]string{"foo": "bar"})
+	s.EmitComplete("otherjob", health.Success, 34567890, nil)
+	assert.NoError(t, s.Close())
+	assert.NoError(t, s.Close())
+
+	msgs := producer.Messages()
+	assert.Equal(t, 2, len(msgs))
+
+	assert.Equal(t, "health", msgs[0].Topic)
+	assert.Equal(t, "myjob", string(msgs[0].Key))
+	var line map[string]interface{}
+	assert.NoError(t, json.Unmarshal(msgs[0].Value, &line))
+	assert.Equal(t, "myevent", line["event"])
+	assert.Equal(t, map[string]interface{}{"foo": "bar"}, line["kvs"])
+
+	assert.Equal(t, "otherjob", string(msgs[1].Key))
+	assert.NoError(t, json.Unmarshal(msgs[1].Value, &line))
+	assert.Equal(t, "success", line["status"])
+}
+
+func TestSinkBatchSize(t *testing.T) {
+	producer := &mockProducer{}
+	s := NewSink(&Config{Topic: "health", Producer: producer, BatchSize: 3, FlushInterval: time.Hour})
+
+	for i := 0; i < 7; i++ {
+		s.EmitEvent("myjob", "myevent", nil)
+	}
+	assert.NoError(t, s.Close())
+
+	producer.mutex.Lock()
+	defer producer.mutex.Unlock()
+	var sizes []int
+	for _, b := range producer.batches {
+		sizes = append(sizes, len(b))
+	}
+	assert.Equal(t, []int{3, 3, 1}, sizes)
+}
+
+func TestSinkFlushInterval(t *testing.T) {
+	producer := &mockProducer{}
+	s := NewSink(&Config{Topic: "health", Producer: producer, FlushInterval: 10 * time.Millisecond})
+	defer s.Close()
+
+	s.EmitEvent("myjob", "myevent", nil)
+
+	deadline := time.Now().Add(time.Second)
+	for len(producer.Messages()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	assert.Equal(t, 1, len(producer.Messages()))
+}
+
+func TestSinkErrorHandler(t *testing.T) {
+	producer := &mockProducer{err: errors.New("broker down")}
+	errs := make(chan error, 1)
+	s := NewSink(&Config{Topic: "health", Producer: producer, BatchSize: 1, FlushInterval: time.Hour, ErrorHandler: func(err error) {
+		errs <- err
+	}})
+	defer s.Close()
+
+	s.EmitEvent("myjob", "myevent", nil)
+	assert.Equal(t, "broker down", (<-errs).Error())
+}
+
+func TestSinkErrorHandlerName(t *testing.T) {
+	producer := &mockProducer{err: errors.New("broker down")}
+	errs := make(chan error, 1)
+	s := NewSink(&Config{Topic: "health", Producer: producer, BatchSize: 1, FlushInterval: time.Hour, Name: "kafka-audit", ErrorHandler: func(err error) {
+		errs <- err
+	}})
+	defer s.Close()
+
+	s.EmitEvent("myjob", "myevent", nil)
+	assert.Equal(t, "kafka-audit: broker down", (<-errs).Error())
+}
+
+func TestSinkFlushError(t *testing.T) {
+	producer := &mockProducer{err: errors.New("broker down")}
+	s := NewSink(&Config{Topic: "health", Producer: producer, FlushInterval: time.Hour})
+
+	s.EmitEvent("myjob", "myevent", nil)
+	err := s.Close()
+	assert.Error(t, err)
+	assert.Equal(t, "broker down", err.Error())
+}
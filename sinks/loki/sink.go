@@ -0,0 +1,266 @@
+package loki
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/gocraft/health"
+	"github.com/gocraft/health/internal/batcher"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HTTPClient sends requests to Loki. *http.Client implements it.
+type HTTPClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+type Config struct {
+	// URL is Loki's push endpoint, eg "http://localhost:3100/loki/api/v1/push".
+	URL string
+
+	// Client sends the pushes. Defaults to an *http.Client with a 10s timeout.
+	Client HTTPClient
+
+	// Labels are added to every stream, eg {"app": "myapp"}.
+	Labels map[string]string
+
+	// BatchSize is the most lines sent in one push. Defaults to 1000.
+	BatchSize int
+
+	// FlushInterval is how often a partial batch is pushed. Defaults to 1s.
+	FlushInterval time.Duration
+
+	// MaxRetries is how many times a push is retried after a 429 or 5xx response, or a connection error. Defaults to 5.
+	MaxRetries int
+
+	// RetryBackoff is the wait before the first retry. It doubles for each retry after that. Defaults to 500ms.
+	RetryBackoff time.Duration
+
+	// ErrorHandler, if set, is called when a push fails for good. If nil, the error is printed to stderr.
+	ErrorHandler func(error)
+
+	// Name, if set, identifies this sink in errors passed to ErrorHandler or printed to stderr (see health.SinkError).
+	Name string
+}
+
+// Sink pushes each emit to Loki as a line of JSON, in the same format as health.JsonSink.
+// Each line goes in the stream labeled with its job, Labels, and kvs["level"] if it has one.
+// Lines are batched and pushed in the background; call Close before exiting to push what's left.
+type Sink struct {
+	*Config
+
+	batcher *batcher.Batcher
+
+	// batch is only used by the batcher's goroutine.
+	batch []*line
+}
+
+type line struct {
+	labels map[string]string
+	nanos  int64
+	body   string
+}
+
+// pushRequest is the body of a push, as described at https://grafana.com/docs/loki/latest/reference/loki-http-api/#ingest-logs.
+type pushRequest struct {
+	Streams []*pushStream `json:"streams"`
+}
+
+type pushStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+func NewSink(config *Config) *Sink {
+	const maxChanSize = 1024
+
+	if config.Client == nil {
+		config.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = 1000
+	}
+	if config.FlushInterval <= 0 {
+		config.FlushInterval = time.Second
+	}
+	if config.MaxRetries <= 0 {
+		config.MaxRetries = 5
+	}
+	if config.RetryBackoff <= 0 {
+		config.RetryBackoff = 500 * time.Millisecond
+	}
+
+	s := &Sink{Config: config}
+	s.batcher = batcher.New(maxChanSize, config.FlushInterval, s.add, s.pushBatch, s.handleError)
+	return s
+}
+
+func (s *Sink) EmitEvent(job string, event string, kvs map[string]string) {
+	s.send(job, kvs, func(js *health.JsonSink) { js.EmitEvent(job, event, kvs) })
+}
+
+func (s *Sink) EmitEventErr(job string, event string, inputErr error, kvs map[string]string) {
+	s.send(job, kvs, func(js *health.JsonSink) { js.EmitEventErr(job, event, inputErr, kvs) })
+}
+
+func (s *Sink) EmitTiming(job string, event string, nanos int64, kvs map[string]string) {
+	s.send(job, kvs, func(js *health.JsonSink) { js.EmitTiming(job, event, nanos, kvs) })
+}
+
+func (s *Sink) EmitComplete(job string, status health.CompletionStatus, nanos int64, kvs map[string]string) {
+	s.send(job, kvs, func(js *health.JsonSink) { js.EmitComplete(job, status, nanos, kvs) })
+}
+
+func (s *Sink) EmitGauge(job string, event string, value float64, kvs map[string]string) {
+	s.send(job, kvs, func(js *health.JsonSink) { js.EmitGauge(job, event, value, kvs) })
+}
+
+func (s *Sink) EmitCount(job string, event string, delta int64, kvs map[string]string) {
+	s.send(job, kvs, func(js *health.JsonSink) { js.EmitCount(job, event, delta, kvs) })
+}
+
+// Flush pushes everything emitted so far, and returns the error if the push failed for good.
+func (s *Sink) Flush() error {
+	return s.batcher.Flush()
+}
+
+// Close pushes everything emitted so far and stops the background goroutine. The sink can't be used afterwards,
+// except to call Close again, which does nothing.
+func (s *Sink) Close() error {
+	return s.batcher.Close()
+}
+
+// send renders an emit with a health.JsonSink and queues it.
+func (s *Sink) send(job string, kvs map[string]string, emit func(js *health.JsonSink)) {
+	var b bytes.Buffer
+	emit(&health.JsonSink{Writer: &b})
+
+	labels := make(map[string]string, len(s.Labels)+2)
+	for k, v := range s.Labels {
+		labels[k] = v
+	}
+	labels["job"] = job
+	if level, ok := kvs["level"]; ok {
+		labels["level"] = level
+	}
+
+	s.batcher.Add(&line{labels: labels, nanos: time.Now().UnixNano(), body: strings.TrimSuffix(b.String(), "\n")})
+}
+
+func (s *Sink) handleError(err error) {
+	err = health.NameError(s.Name, err)
+	if s.ErrorHandler != nil {
+		s.ErrorHandler(err)
+	} else {
+		fmt.Fprintf(os.Stderr, "loki.Sink: could not push. err=%v\n", err)
+	}
+}
+
+// add adds l to the batch, and reports whether it's full.
+func (s *Sink) add(l interface{}) bool {
+	s.batch = append(s.batch, l.(*line))
+	return len(s.batch) >= s.BatchSize
+}
+
+// pushBatch pushes the batch and empties it.
+func (s *Sink) pushBatch() error {
+	err := s.pushAll(s.batch)
+	s.batch = nil
+	return err
+}
+
+// pushAll pushes lines, BatchSize at a time, and returns the first error.
+func (s *Sink) pushAll(lines []*line) error {
+	var firstErr error
+	for len(lines) > 0 {
+		n := len(lines)
+		if n > s.BatchSize {
+			n = s.BatchSize
+		}
+		if err := s.pushWithRetries(lines[:n]); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		lines = lines[n:]
+	}
+	return firstErr
+}
+
+func (s *Sink) pushWithRetries(lines []*line) error {
+	body, err := json.Marshal(groupStreams(lines))
+	if err != nil {
+		return err
+	}
+
+	backoff := s.RetryBackoff
+	for retry := 0; ; retry++ {
+		retryable, err := s.push(body)
+		if err == nil || !retryable || retry >= s.MaxRetries {
+			return err
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// push sends one push request. If it fails, retryable reports whether it's worth trying again.
+func (s *Sink) push(body []byte) (retryable bool, err error) {
+	req, err := http.NewRequest("POST", s.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode/100 == 2 {
+		return false, nil
+	}
+	err = fmt.Errorf("loki.Sink: push got status %d", resp.StatusCode)
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500, err
+}
+
+// groupStreams puts lines with the same labels in the same stream, keeping streams in the order they first appear.
+func groupStreams(lines []*line) *pushRequest {
+	req := &pushRequest{}
+	streams := make(map[string]*pushStream)
+	for _, l := range lines {
+		key := labelsKey(l.labels)
+		stream, ok := streams[key]
+		if !ok {
+			stream = &pushStream{Stream: l.labels}
+			streams[key] = stream
+			req.Streams = append(req.Streams, stream)
+		}
+		stream.Values = append(stream.Values, [2]string{strconv.FormatInt(l.nanos, 10), l.body})
+	}
+	return req
+}
+
+func labelsKey(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b bytes.Buffer
+	for _, k := range keys {
+		b.WriteString(strconv.Quote(k))
+		b.WriteRune('=')
+		b.WriteString(strconv.Quote(labels[k]))
+		b.WriteRune(',')
+	}
+	return b.String()
+}
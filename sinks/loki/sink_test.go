@@ -0,0 +1,125 @@
+package loki
+
+import (
+	"encoding/json"
+	"github.com/gocraft/health"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeLoki records pushes, answering each with the next of statuses (or 204 once they run out).
+type fakeLoki struct {
+	mutex    sync.Mutex
+	pushes   []*pushRequest
+	statuses []int
+}
+
+func (f *fakeLoki) Do(req *http.Request) (*http.Response, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	status := http.StatusNoContent
+	if len(f.statuses) > 0 {
+		status, f.statuses = f.statuses[0], f.statuses[1:]
+	}
+
+	body, _ := ioutil.ReadAll(req.Body)
+	var push pushRequest
+	if err := json.Unmarshal(body, &push); err != nil {
+		return nil, err
+	}
+	f.pushes = append(f.pushes, &push)
+
+	rec := httptest.NewRecorder()
+	rec.WriteHeader(status)
+	return rec.Result(), nil
+}
+
+func (f *fakeLoki) Pushes() []*pushRequest {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return append([]*pushRequest(nil), f.pushes...)
+}
+
+func TestSink(t *testing.T) {
+	loki := &fakeLoki{}
+	s := NewSink(&Config{URL: "http://loki/loki/api/v1/push", Client: loki, Labels: map[string]string{"app": "myapp"}, FlushInterval: time.Hour})
+
+	s.EmitEvent("myjob", "myevent", map[string]string{"level": "info"})
+	s.EmitEvent("myjob", "another", map[string]string{"level": "info"})
+	s.EmitComplete("otherjob", health.Success, 34567890, nil)
+	assert.NoError(t, s.Close())
+	assert.NoError(t, s.Close())
+
+	pushes := loki.Pushes()
+	assert.Equal(t, 1, len(pushes))
+	streams := pushes[0].Streams
+	assert.Equal(t, 2, len(streams))
+
+	assert.Equal(t, map[string]string{"app": "myapp", "job": "myjob", "level": "info"}, streams[0].Stream)
+	assert.Equal(t, 2, len(streams[0].Values))
+	var line map[string]interface{}
+	assert.NoError(t, json.Unmarshal([]byte(streams[0].Values[0][1]), &line))
+	assert.Equal(t, "myevent", line["event"])
+
+	assert.Equal(t, map[string]string{"app": "myapp", "job": "otherjob"}, streams[1].Stream)
+	assert.NoError(t, json.Unmarshal([]byte(streams[1].Values[0][1]), &line))
+	assert.Equal(t, "success", line["status"])
+}
+
+func TestSinkRetries(t *testing.T) {
+	loki := &fakeLoki{statuses: []int{429, 503}}
+	s := NewSink(&Config{URL: "http://loki/", Client: loki, FlushInterval: time.Hour, RetryBackoff: time.Millisecond})
+
+	s.EmitEvent("myjob", "myevent", nil)
+	assert.NoError(t, s.Close())
+	assert.Equal(t, 3, len(loki.Pushes()))
+}
+
+func TestSinkGivesUp(t *testing.T) {
+	loki := &fakeLoki{statuses: []int{429, 429, 429}}
+	s := NewSink(&Config{URL: "http://loki/", Client: loki, FlushInterval: time.Hour, MaxRetries: 2, RetryBackoff: time.Millisecond})
+
+	s.EmitEvent("myjob", "myevent", nil)
+	err := s.Close()
+	assert.Error(t, err)
+	assert.Equal(t, "loki.Sink: push got status 429", err.Error())
+	assert.Equal(t, 3, len(loki.Pushes()))
+}
+
+func TestSinkDoesntRetryBadRequests(t *testing.T) {
+	loki := &fakeLoki{statuses: []int{400}}
+	errs := make(chan error, 1)
+	s := NewSink(&Config{URL: "http://loki/", Client: loki, BatchSize: 1, FlushInterval: time.Hour, ErrorHandler: func(err error) {
+		errs <- err
+	}})
+	defer s.Close()
+
+	s.EmitEvent("myjob", "myevent", nil)
+	assert.Equal(t, "loki.Sink: push got status 400", (<-errs).Error())
+	assert.Equal(t, 1, len(loki.Pushes()))
+}
+
+func TestSinkHTTP(t *testing.T) {
+	pushed := make(chan *pushRequest, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		var push pushRequest
+		json.NewDecoder(r.Body).Decode(&push)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		pushed <- &push
+		rw.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	s := NewSink(&Config{URL: server.URL + "/loki/api/v1/push", FlushInterval: 10 * time.Millisecond})
+	defer s.Close()
+
+	s.EmitTiming("myjob", "myevent", 34567890, nil)
+	push := <-pushed
+	assert.Equal(t, "myjob", push.Streams[0].Stream["job"])
+}
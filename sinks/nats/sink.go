@@ -0,0 +1,110 @@
+package nats
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/gocraft/health"
+	"os"
+	"strings"
+)
+
+// Conn publishes messages to NATS. *nats.Conn from github.com/nats-io/nats.go implements it.
+type Conn interface {
+	Publish(subject string, data []byte) error
+}
+
+type Config struct {
+	// Conn is the connection to publish on. Reconnecting is up to it; *nats.Conn buffers while it reconnects.
+	Conn Conn
+
+	// SubjectPrefix starts every subject. Defaults to "health".
+	SubjectPrefix string
+
+	// ErrorHandler, if set, is called with errors from Conn. If nil, they're printed to stderr.
+	ErrorHandler func(error)
+
+	// Name, if set, identifies this sink in errors passed to ErrorHandler or printed to stderr (see health.SinkError).
+	Name string
+}
+
+// Sink publishes each emit to NATS as a JSON object, in the same format as health.JsonSink.
+// Emits go to the subject "<SubjectPrefix>.<job>.<event>", eg "health.signup.email_sent", and completions to "<SubjectPrefix>.<job>.completion".
+// Characters that NATS doesn't allow in a subject token ('.', '*', '>', and whitespace) are replaced with '_' in job and event names.
+type Sink struct {
+	*Config
+}
+
+var _ health.Sink = &Sink{}
+
+func NewSink(config *Config) *Sink {
+	if config.SubjectPrefix == "" {
+		config.SubjectPrefix = "health"
+	}
+	return &Sink{Config: config}
+}
+
+func (s *Sink) EmitEvent(job string, event string, kvs map[string]string) {
+	s.publish(job, event, func(js *health.JsonSink) { js.EmitEvent(job, event, kvs) })
+}
+
+func (s *Sink) EmitEventErr(job string, event string, inputErr error, kvs map[string]string) {
+	s.publish(job, event, func(js *health.JsonSink) { js.EmitEventErr(job, event, inputErr, kvs) })
+}
+
+func (s *Sink) EmitTiming(job string, event string, nanos int64, kvs map[string]string) {
+	s.publish(job, event, func(js *health.JsonSink) { js.EmitTiming(job, event, nanos, kvs) })
+}
+
+func (s *Sink) EmitComplete(job string, status health.CompletionStatus, nanos int64, kvs map[string]string) {
+	s.publish(job, "completion", func(js *health.JsonSink) { js.EmitComplete(job, status, nanos, kvs) })
+}
+
+func (s *Sink) EmitGauge(job string, event string, value float64, kvs map[string]string) {
+	s.publish(job, event, func(js *health.JsonSink) { js.EmitGauge(job, event, value, kvs) })
+}
+
+func (s *Sink) EmitCount(job string, event string, delta int64, kvs map[string]string) {
+	s.publish(job, event, func(js *health.JsonSink) { js.EmitCount(job, event, delta, kvs) })
+}
+
+// Flush flushes Conn if it has a Flush method, as *nats.Conn does, which waits for the server to have everything published so far.
+func (s *Sink) Flush() error {
+	if f, ok := s.Conn.(health.Flusher); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
+// Subject returns the subject emits for job and event are published to.
+func (s *Sink) Subject(job, event string) string {
+	return s.SubjectPrefix + "." + subjectToken(job) + "." + subjectToken(event)
+}
+
+// publish renders an emit with a health.JsonSink and publishes it.
+func (s *Sink) publish(job, event string, emit func(js *health.JsonSink)) {
+	var b bytes.Buffer
+	emit(&health.JsonSink{Writer: &b})
+
+	if err := s.Conn.Publish(s.Subject(job, event), bytes.TrimSuffix(b.Bytes(), []byte("\n"))); err != nil {
+		s.handleError(err)
+	}
+}
+
+func (s *Sink) handleError(err error) {
+	err = health.NameError(s.Name, err)
+	if s.ErrorHandler != nil {
+		s.ErrorHandler(err)
+	} else {
+		fmt.Fprintf(os.Stderr, "nats.Sink: could not publish. err=%v\n", err)
+	}
+}
+
+var subjectTokenReplacer = strings.NewReplacer(".", "_", "*", "_", ">", "_", " ", "_", "\t", "_", "\r", "_", "\n", "_")
+
+// subjectToken makes name usable as one token of a subject.
+func subjectToken(name string) string {
+	if name == "" {
+		return "_"
+	}
+	return subjectTokenReplacer.Replace(name)
+}
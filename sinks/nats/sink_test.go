@@ -0,0 +1,82 @@
+package nats
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/gocraft/health"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type message struct {
+	subject string
+	data    map[string]interface{}
+}
+
+type mockConn struct {
+	messages []message
+	flushes  int
+	err      error
+}
+
+func (c *mockConn) Publish(subject string, data []byte) error {
+	var m map[string]interface{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return err
+	}
+	c.messages = append(c.messages, message{subject, m})
+	return c.err
+}
+
+func (c *mockConn) Flush() error {
+	c.flushes++
+	return nil
+}
+
+func TestSink(t *testing.T) {
+	conn := &mockConn{}
+	s := NewSink(&Config{Conn: conn})
+
+	s.EmitEvent("signup", "email_sent", map[string]string{"wat": "ok"})
+	s.EmitEventErr("signup", "email_sent", errors.New("bounced"), nil)
+	s.EmitTiming("signup", "db.query", 1204, nil)
+	s.EmitComplete("signup", health.Success, 1204, nil)
+	s.EmitGauge("api v2", "queue>depth", 3.5, nil)
+	s.EmitCount("signup", "*", 2, nil)
+
+	subjects := make([]string, 0, len(conn.messages))
+	for _, m := range conn.messages {
+		subjects = append(subjects, m.subject)
+	}
+	assert.Equal(t, []string{
+		"health.signup.email_sent",
+		"health.signup.email_sent",
+		"health.signup.db_query",
+		"health.signup.completion",
+		"health.api_v2.queue_depth",
+		"health.signup._",
+	}, subjects)
+
+	assert.Equal(t, "email_sent", conn.messages[0].data["event"])
+	assert.Equal(t, map[string]interface{}{"wat": "ok"}, conn.messages[0].data["kvs"])
+	assert.Equal(t, "bounced", conn.messages[1].data["err"])
+	assert.Equal(t, "success", conn.messages[3].data["status"])
+
+	assert.NoError(t, s.Flush())
+	assert.Equal(t, 1, conn.flushes)
+}
+
+func TestSinkSubjectPrefix(t *testing.T) {
+	s := NewSink(&Config{Conn: &mockConn{}, SubjectPrefix: "prod.health"})
+	assert.Equal(t, "prod.health.myjob.myevent", s.Subject("myjob", "myevent"))
+}
+
+func TestSinkErrorHandler(t *testing.T) {
+	var gotErr error
+	s := NewSink(&Config{Conn: &mockConn{err: errors.New("nats: connection closed")}, ErrorHandler: func(err error) {
+		gotErr = err
+	}})
+
+	s.EmitEvent("myjob", "myevent", nil)
+	assert.Equal(t, "nats: connection closed", gotErr.Error())
+}
@@ -0,0 +1,175 @@
+package objectstore
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"github.com/gocraft/health"
+	"github.com/gocraft/health/internal/batcher"
+	"os"
+	"time"
+)
+
+// Uploader stores an object under key, eg with an S3 PutObject or by writing a GCS object.
+// Wrap your object store's client in one.
+type Uploader interface {
+	Upload(key string, body []byte) error
+}
+
+type Config struct {
+	// Uploader stores each batch. Required.
+	Uploader Uploader
+
+	// Prefix is prepended to every key, eg "health/myapp/".
+	Prefix string
+
+	// Hostname goes in every key, so that hosts don't overwrite each other's batches. Defaults to os.Hostname().
+	Hostname string
+
+	// MaxBatchBytes is how big a batch can get, before compression, before it's uploaded. Defaults to 5MB.
+	MaxBatchBytes int
+
+	// FlushInterval is how often a partial batch is uploaded. Defaults to 5m.
+	FlushInterval time.Duration
+
+	// ErrorHandler, if set, is called when an upload fails. If nil, the error is printed to stderr.
+	ErrorHandler func(error)
+
+	// Name, if set, identifies this sink in errors passed to ErrorHandler or printed to stderr (see health.SinkError).
+	Name string
+}
+
+// Sink archives emits to an object store, as gzipped files with one line of JSON per emit, in the same format as health.JsonSink.
+// Lines are batched in the background, and each batch is uploaded when it reaches MaxBatchBytes or FlushInterval passes.
+// Keys look like "<Prefix>20160102T150405.000000000Z-<Hostname>.ndjson.gz", with the time of the batch's first emit in UTC.
+// A batch whose upload fails is dropped, so use an Uploader that retries if that matters.
+// Call Close before exiting to upload what's left.
+type Sink struct {
+	*Config
+
+	now func() time.Time
+
+	batcher *batcher.Batcher
+
+	// batch is only used by the batcher's goroutine.
+	batch batch
+}
+
+type line struct {
+	time time.Time
+	body []byte
+}
+
+// batch is the lines waiting to be uploaded.
+type batch struct {
+	start time.Time
+	buf   bytes.Buffer
+}
+
+func NewSink(config *Config) *Sink {
+	const maxChanSize = 1024
+
+	if config.Hostname == "" {
+		host, err := os.Hostname()
+		if err != nil {
+			host = "hostname_errored"
+		}
+		config.Hostname = host
+	}
+	if config.MaxBatchBytes <= 0 {
+		config.MaxBatchBytes = 5 * 1024 * 1024
+	}
+	if config.FlushInterval <= 0 {
+		config.FlushInterval = 5 * time.Minute
+	}
+
+	s := &Sink{Config: config, now: time.Now}
+	s.batcher = batcher.New(maxChanSize, config.FlushInterval, s.add, s.upload, s.handleError)
+	return s
+}
+
+func (s *Sink) EmitEvent(job string, event string, kvs map[string]string) {
+	s.send(func(js *health.JsonSink) { js.EmitEvent(job, event, kvs) })
+}
+
+func (s *Sink) EmitEventErr(job string, event string, inputErr error, kvs map[string]string) {
+	s.send(func(js *health.JsonSink) { js.EmitEventErr(job, event, inputErr, kvs) })
+}
+
+func (s *Sink) EmitTiming(job string, event string, nanos int64, kvs map[string]string) {
+	s.send(func(js *health.JsonSink) { js.EmitTiming(job, event, nanos, kvs) })
+}
+
+func (s *Sink) EmitComplete(job string, status health.CompletionStatus, nanos int64, kvs map[string]string) {
+	s.send(func(js *health.JsonSink) { js.EmitComplete(job, status, nanos, kvs) })
+}
+
+func (s *Sink) EmitGauge(job string, event string, value float64, kvs map[string]string) {
+	s.send(func(js *health.JsonSink) { js.EmitGauge(job, event, value, kvs) })
+}
+
+func (s *Sink) EmitCount(job string, event string, delta int64, kvs map[string]string) {
+	s.send(func(js *health.JsonSink) { js.EmitCount(job, event, delta, kvs) })
+}
+
+// Flush uploads everything emitted so far, and returns the error if the upload failed.
+func (s *Sink) Flush() error {
+	return s.batcher.Flush()
+}
+
+// Close uploads everything emitted so far and stops the background goroutine. The sink can't be used afterwards,
+// except to call Close again, which does nothing.
+func (s *Sink) Close() error {
+	return s.batcher.Close()
+}
+
+// send renders an emit with a health.JsonSink and queues it.
+func (s *Sink) send(emit func(js *health.JsonSink)) {
+	var b bytes.Buffer
+	emit(&health.JsonSink{Writer: &b})
+	s.batcher.Add(&line{time: s.now(), body: b.Bytes()})
+}
+
+func (s *Sink) handleError(err error) {
+	err = health.NameError(s.Name, err)
+	if s.ErrorHandler != nil {
+		s.ErrorHandler(err)
+	} else {
+		fmt.Fprintf(os.Stderr, "objectstore.Sink: could not upload. err=%v\n", err)
+	}
+}
+
+// add appends l to the batch, and reports whether the batch has reached MaxBatchBytes.
+func (s *Sink) add(item interface{}) bool {
+	l, b := item.(*line), &s.batch
+	if b.buf.Len() == 0 {
+		b.start = l.time
+	}
+	b.buf.Write(l.body)
+	return b.buf.Len() >= s.MaxBatchBytes
+}
+
+// upload gzips the batch and uploads it, then empties it. An empty batch isn't uploaded.
+func (s *Sink) upload() error {
+	b := &s.batch
+	if b.buf.Len() == 0 {
+		return nil
+	}
+	defer b.buf.Reset()
+
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	if _, err := zw.Write(b.buf.Bytes()); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+
+	return s.Uploader.Upload(s.key(b.start), gz.Bytes())
+}
+
+// key returns the key of a batch whose first emit was at start.
+func (s *Sink) key(start time.Time) string {
+	return s.Prefix + start.UTC().Format("20060102T150405.000000000Z") + "-" + s.Hostname + ".ndjson.gz"
+}
@@ -0,0 +1,145 @@
+package objectstore
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"github.com/gocraft/health"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeUploader records uploads, returning err from each.
+type fakeUploader struct {
+	mutex   sync.Mutex
+	keys    []string
+	objects [][]map[string]interface{}
+	err     error
+}
+
+func (f *fakeUploader) Upload(key string, body []byte) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	zr, err := gzip.NewReader(bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	data, err := ioutil.ReadAll(zr)
+	if err != nil {
+		return err
+	}
+
+	var lines []map[string]interface{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		var line map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			return err
+		}
+		lines = append(lines, line)
+	}
+
+	f.keys = append(f.keys, key)
+	f.objects = append(f.objects, lines)
+	return f.err
+}
+
+func (f *fakeUploader) Uploads() ([]string, [][]map[string]interface{}) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return append([]string(nil), f.keys...), append([][]map[string]interface{}(nil), f.objects...)
+}
+
+// newTestSink returns a Sink whose clock starts at 2016-01-02T15:04:05Z and ticks a second per emit.
+func newTestSink(config *Config) *Sink {
+	s := NewSink(config)
+	t := time.Date(2016, 1, 2, 15, 4, 4, 0, time.UTC)
+	s.now = func() time.Time {
+		t = t.Add(time.Second)
+		return t
+	}
+	return s
+}
+
+func TestSink(t *testing.T) {
+	uploader := &fakeUploader{}
+	s := newTestSink(&Config{Uploader: uploader, Prefix: "health/", Hostname: "web1", FlushInterval: time.Hour})
+
+	s.EmitEvent("myjob", "myevent", map[string]string{"wat": "ok"})
+	s.EmitComplete("myjob", health.Success, 34567890, nil)
+	assert.NoError(t, s.Flush())
+
+	keys, objects := uploader.Uploads()
+	assert.Equal(t, []string{"health/20160102T150405.000000000Z-web1.ndjson.gz"}, keys)
+	assert.Equal(t, 2, len(objects[0]))
+	assert.Equal(t, "myevent", objects[0][0]["event"])
+	assert.Equal(t, map[string]interface{}{"wat": "ok"}, objects[0][0]["kvs"])
+	assert.Equal(t, "success", objects[0][1]["status"])
+
+	// Nothing new to upload.
+	assert.NoError(t, s.Flush())
+	keys, _ = uploader.Uploads()
+	assert.Equal(t, 1, len(keys))
+
+	// Close uploads the partial batch.
+	s.EmitCount("myjob", "mycount", 1, nil)
+	assert.NoError(t, s.Close())
+	assert.NoError(t, s.Close())
+	keys, objects = uploader.Uploads()
+	assert.Equal(t, []string{"health/20160102T150405.000000000Z-web1.ndjson.gz", "health/20160102T150407.000000000Z-web1.ndjson.gz"}, keys)
+	assert.Equal(t, "mycount", objects[1][0]["event"])
+}
+
+func TestSinkMaxBatchBytes(t *testing.T) {
+	uploader := &fakeUploader{}
+	s := newTestSink(&Config{Uploader: uploader, Hostname: "web1", MaxBatchBytes: 1, FlushInterval: time.Hour})
+
+	s.EmitEvent("myjob", "first", nil)
+	s.EmitEvent("myjob", "second", nil)
+	assert.NoError(t, s.Close())
+
+	keys, objects := uploader.Uploads()
+	assert.Equal(t, []string{"20160102T150405.000000000Z-web1.ndjson.gz", "20160102T150406.000000000Z-web1.ndjson.gz"}, keys)
+	assert.Equal(t, "first", objects[0][0]["event"])
+	assert.Equal(t, "second", objects[1][0]["event"])
+}
+
+func TestSinkFlushInterval(t *testing.T) {
+	uploader := &fakeUploader{}
+	s := newTestSink(&Config{Uploader: uploader, Hostname: "web1", FlushInterval: time.Millisecond})
+	defer s.Close()
+
+	s.EmitEvent("myjob", "myevent", nil)
+	for i := 0; i < 100; i++ {
+		if keys, _ := uploader.Uploads(); len(keys) > 0 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	keys, _ := uploader.Uploads()
+	assert.Equal(t, 1, len(keys))
+}
+
+func TestSinkErrors(t *testing.T) {
+	uploader := &fakeUploader{err: errors.New("access denied")}
+	handled := make(chan error, 1)
+	s := newTestSink(&Config{Uploader: uploader, Hostname: "web1", MaxBatchBytes: 1, FlushInterval: time.Hour, Name: "archive",
+		ErrorHandler: func(err error) { handled <- err }})
+
+	// A full batch is uploaded in the background, so its error is handled.
+	s.EmitEvent("myjob", "myevent", nil)
+	err := <-handled
+	assert.Equal(t, "archive", err.(*health.SinkError).Sink)
+	assert.NoError(t, s.Close())
+
+	// Flush returns the error rather than handling it.
+	s = newTestSink(&Config{Uploader: uploader, Hostname: "web1", FlushInterval: time.Hour})
+	s.EmitEvent("myjob", "myevent", nil)
+	assert.EqualError(t, s.Close(), "access denied")
+}
@@ -0,0 +1,122 @@
+package otel
+
+import (
+	"context"
+	"github.com/gocraft/health"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"sync"
+	"time"
+)
+
+// This sink records OpenTelemetry metrics for everything it receives, using a Meter you provide (and so your exporter):
+//   - health.events{job,event} counts events
+//   - health.event_errors{job,event} counts errors
+//   - health.timing{job,event} is a histogram of timings, in seconds
+//   - health.completions{job,status} counts job completions; status is eg "success" or "error"
+//   - health.completion.duration{job,status} is a histogram of job durations, in seconds
+//   - health.gauge{job,event} observes the last gauge value
+//   - health.counts{job,event} sums counts; negative deltas are dropped since OpenTelemetry counters only go up
+type Sink struct {
+	// AttributeKeys are kvs keys that are added as attributes to each measurement that has them.
+	// Kvs are left out by default because every distinct value makes a new time series.
+	AttributeKeys []string
+
+	events            metric.Int64Counter
+	eventErrs         metric.Int64Counter
+	timings           metric.Float64Histogram
+	completions       metric.Int64Counter
+	completionTimings metric.Float64Histogram
+	counts            metric.Int64Counter
+
+	gaugesMutex sync.Mutex
+	gauges      map[gaugeKey]float64
+}
+
+type gaugeKey struct {
+	job, event string
+}
+
+// NewSink makes the sink's instruments with meter. An error is returned if any of them can't be made.
+func NewSink(meter metric.Meter) (*Sink, error) {
+	s := &Sink{gauges: make(map[gaugeKey]float64)}
+
+	var err error
+	if s.events, err = meter.Int64Counter("health.events", metric.WithDescription("Number of events emitted.")); err != nil {
+		return nil, err
+	}
+	if s.eventErrs, err = meter.Int64Counter("health.event_errors", metric.WithDescription("Number of errors emitted.")); err != nil {
+		return nil, err
+	}
+	if s.timings, err = meter.Float64Histogram("health.timing", metric.WithDescription("Timings emitted."), metric.WithUnit("s")); err != nil {
+		return nil, err
+	}
+	if s.completions, err = meter.Int64Counter("health.completions", metric.WithDescription("Number of completed jobs.")); err != nil {
+		return nil, err
+	}
+	if s.completionTimings, err = meter.Float64Histogram("health.completion.duration", metric.WithDescription("Durations of completed jobs."), metric.WithUnit("s")); err != nil {
+		return nil, err
+	}
+	if s.counts, err = meter.Int64Counter("health.counts", metric.WithDescription("Sum of the counts emitted.")); err != nil {
+		return nil, err
+	}
+	if _, err = meter.Float64ObservableGauge("health.gauge", metric.WithDescription("Last value of each gauge emitted."), metric.WithFloat64Callback(s.observeGauges)); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s *Sink) EmitEvent(job string, event string, kvs map[string]string) {
+	s.events.Add(context.Background(), 1, metric.WithAttributes(s.attributes("event", job, event, kvs)...))
+}
+
+func (s *Sink) EmitEventErr(job string, event string, inputErr error, kvs map[string]string) {
+	s.eventErrs.Add(context.Background(), 1, metric.WithAttributes(s.attributes("event", job, event, kvs)...))
+}
+
+func (s *Sink) EmitTiming(job string, event string, nanos int64, kvs map[string]string) {
+	s.timings.Record(context.Background(), seconds(nanos), metric.WithAttributes(s.attributes("event", job, event, kvs)...))
+}
+
+func (s *Sink) EmitComplete(job string, status health.CompletionStatus, nanos int64, kvs map[string]string) {
+	attrs := metric.WithAttributes(s.attributes("status", job, status.String(), kvs)...)
+	s.completions.Add(context.Background(), 1, attrs)
+	s.completionTimings.Record(context.Background(), seconds(nanos), attrs)
+}
+
+func (s *Sink) EmitGauge(job string, event string, value float64, kvs map[string]string) {
+	s.gaugesMutex.Lock()
+	defer s.gaugesMutex.Unlock()
+	s.gauges[gaugeKey{job, event}] = value
+}
+
+func (s *Sink) EmitCount(job string, event string, delta int64, kvs map[string]string) {
+	if delta > 0 {
+		s.counts.Add(context.Background(), delta, metric.WithAttributes(s.attributes("event", job, event, kvs)...))
+	}
+}
+
+func (s *Sink) observeGauges(ctx context.Context, o metric.Float64Observer) error {
+	s.gaugesMutex.Lock()
+	defer s.gaugesMutex.Unlock()
+	for k, v := range s.gauges {
+		o.Observe(v, metric.WithAttributes(attribute.String("job", k.job), attribute.String("event", k.event)))
+	}
+	return nil
+}
+
+// attributes returns the job attribute, the attribute named name, and any AttributeKeys in kvs.
+func (s *Sink) attributes(name string, job string, value string, kvs map[string]string) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{attribute.String("job", job), attribute.String(name, value)}
+	for _, k := range s.AttributeKeys {
+		if v, ok := kvs[k]; ok {
+			attrs = append(attrs, attribute.String(k, v))
+		}
+	}
+	return attrs
+}
+
+func seconds(nanos int64) float64 {
+	return float64(nanos) / float64(time.Second)
+}
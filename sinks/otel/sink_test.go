@@ -0,0 +1,158 @@
+package otel
+
+import (
+	"context"
+	"errors"
+	"github.com/gocraft/health"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/embedded"
+	"go.opentelemetry.io/otel/metric/noop"
+	"testing"
+)
+
+// recordingMeter records every measurement made with its instruments. Anything it doesn't override is a no-op.
+type recordingMeter struct {
+	noop.Meter
+	measurements []measurement
+	callbacks    []metric.Float64Callback
+}
+
+type measurement struct {
+	name  string
+	value float64
+	attrs attribute.Set
+}
+
+func (m *recordingMeter) Int64Counter(name string, options ...metric.Int64CounterOption) (metric.Int64Counter, error) {
+	return &recordingInt64Counter{name: name, meter: m}, nil
+}
+
+func (m *recordingMeter) Float64Histogram(name string, options ...metric.Float64HistogramOption) (metric.Float64Histogram, error) {
+	return &recordingFloat64Histogram{name: name, meter: m}, nil
+}
+
+func (m *recordingMeter) Float64ObservableGauge(name string, options ...metric.Float64ObservableGaugeOption) (metric.Float64ObservableGauge, error) {
+	m.callbacks = append(m.callbacks, metric.NewFloat64ObservableGaugeConfig(options...).Callbacks()...)
+	return noop.Float64ObservableGauge{}, nil
+}
+
+// observe runs the gauge callbacks, recording what they observe.
+func (m *recordingMeter) observe() {
+	for _, cb := range m.callbacks {
+		cb(context.Background(), &recordingObserver{name: "health.gauge", meter: m})
+	}
+}
+
+func (m *recordingMeter) find(name string) []measurement {
+	var found []measurement
+	for _, meas := range m.measurements {
+		if meas.name == name {
+			found = append(found, meas)
+		}
+	}
+	return found
+}
+
+type recordingInt64Counter struct {
+	embedded.Int64Counter
+	name  string
+	meter *recordingMeter
+}
+
+func (c *recordingInt64Counter) Add(ctx context.Context, incr int64, options ...metric.AddOption) {
+	c.meter.measurements = append(c.meter.measurements, measurement{c.name, float64(incr), metric.NewAddConfig(options).Attributes()})
+}
+
+type recordingFloat64Histogram struct {
+	embedded.Float64Histogram
+	name  string
+	meter *recordingMeter
+}
+
+func (h *recordingFloat64Histogram) Record(ctx context.Context, value float64, options ...metric.RecordOption) {
+	h.meter.measurements = append(h.meter.measurements, measurement{h.name, value, metric.NewRecordConfig(options).Attributes()})
+}
+
+type recordingObserver struct {
+	embedded.Float64Observer
+	name  string
+	meter *recordingMeter
+}
+
+func (o *recordingObserver) Observe(value float64, options ...metric.ObserveOption) {
+	o.meter.measurements = append(o.meter.measurements, measurement{o.name, value, metric.NewObserveConfig(options).Attributes()})
+}
+
+func attrValue(set attribute.Set, key string) string {
+	v, _ := set.Value(attribute.Key(key))
+	return v.AsString()
+}
+
+func TestSink(t *testing.T) {
+	meter := &recordingMeter{}
+	s, err := NewSink(meter)
+	assert.NoError(t, err)
+
+	s.EmitEvent("myjob", "myevent", nil)
+	s.EmitEventErr("myjob", "myevent", errors.New("oops"), nil)
+	s.EmitTiming("myjob", "fetch", 1500000000, nil)
+	s.EmitComplete("myjob", health.Error, 2000000000, nil)
+	s.EmitGauge("myjob", "queue_depth", 3.5, nil)
+	s.EmitCount("myjob", "cache.miss", 3, nil)
+	s.EmitCount("myjob", "cache.miss", -1, nil)
+	meter.observe()
+
+	events := meter.find("health.events")
+	assert.Equal(t, 1, len(events))
+	assert.Equal(t, "myjob", attrValue(events[0].attrs, "job"))
+	assert.Equal(t, "myevent", attrValue(events[0].attrs, "event"))
+
+	assert.Equal(t, 1, len(meter.find("health.event_errors")))
+
+	timings := meter.find("health.timing")
+	assert.Equal(t, 1, len(timings))
+	assert.Equal(t, 1.5, timings[0].value)
+	assert.Equal(t, "fetch", attrValue(timings[0].attrs, "event"))
+
+	completions := meter.find("health.completions")
+	assert.Equal(t, 1, len(completions))
+	assert.Equal(t, "error", attrValue(completions[0].attrs, "status"))
+
+	durations := meter.find("health.completion.duration")
+	assert.Equal(t, 1, len(durations))
+	assert.Equal(t, 2.0, durations[0].value)
+
+	counts := meter.find("health.counts")
+	assert.Equal(t, 1, len(counts))
+	assert.Equal(t, 3.0, counts[0].value)
+	assert.Equal(t, "cache.miss", attrValue(counts[0].attrs, "event"))
+
+	gauges := meter.find("health.gauge")
+	assert.Equal(t, 1, len(gauges))
+	assert.Equal(t, 3.5, gauges[0].value)
+	assert.Equal(t, "queue_depth", attrValue(gauges[0].attrs, "event"))
+}
+
+func TestSinkAttributeKeys(t *testing.T) {
+	meter := &recordingMeter{}
+	s, err := NewSink(meter)
+	assert.NoError(t, err)
+	s.AttributeKeys = []string{"region"}
+
+	s.EmitTiming("myjob", "fetch", 1, map[string]string{"region": "us-east", "request_id": "abc"})
+
+	timings := meter.find("health.timing")
+	assert.Equal(t, 1, len(timings))
+	assert.Equal(t, 3, timings[0].attrs.Len())
+	assert.Equal(t, "us-east", attrValue(timings[0].attrs, "region"))
+}
+
+func TestSinkNoopMeter(t *testing.T) {
+	s, err := NewSink(noop.NewMeterProvider().Meter("health"))
+	assert.NoError(t, err)
+
+	s.EmitEvent("myjob", "myevent", map[string]string{"foo": "bar"})
+	s.EmitComplete("myjob", health.Success, 1, nil)
+}
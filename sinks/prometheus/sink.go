@@ -0,0 +1,113 @@
+package prometheus
+
+import (
+	"github.com/gocraft/health"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"net/http"
+	"time"
+)
+
+// This sink keeps Prometheus metrics for everything it receives, to be scraped via Handler:
+//   - health_events_total{job,event} counts events
+//   - health_event_errors_total{job,event} counts errors
+//   - health_timing_seconds{job,event} is a histogram of timings
+//   - health_completions_total{job,status} counts job completions
+//   - health_completion_seconds{job,status} is a histogram of job durations
+//   - health_gauge{job,event} holds the last gauge value
+//   - health_counts_total{job,event} sums counts; negative deltas are dropped since Prometheus counters only go up
+type Sink struct {
+	registry *prometheus.Registry
+
+	events            *prometheus.CounterVec
+	eventErrs         *prometheus.CounterVec
+	timings           *prometheus.HistogramVec
+	completions       *prometheus.CounterVec
+	completionTimings *prometheus.HistogramVec
+	gauges            *prometheus.GaugeVec
+	counts            *prometheus.CounterVec
+}
+
+// NewSink registers the sink's metrics with registry. If registry is nil, a new one is made.
+// An error is returned if the metrics can't be registered, eg because registry already has a sink's metrics.
+func NewSink(registry *prometheus.Registry) (*Sink, error) {
+	if registry == nil {
+		registry = prometheus.NewRegistry()
+	}
+
+	s := &Sink{
+		registry: registry,
+		events: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "health_events_total",
+			Help: "Number of events emitted.",
+		}, []string{"job", "event"}),
+		eventErrs: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "health_event_errors_total",
+			Help: "Number of errors emitted.",
+		}, []string{"job", "event"}),
+		timings: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name: "health_timing_seconds",
+			Help: "Timings emitted, in seconds.",
+		}, []string{"job", "event"}),
+		completions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "health_completions_total",
+			Help: "Number of completed jobs.",
+		}, []string{"job", "status"}),
+		completionTimings: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name: "health_completion_seconds",
+			Help: "Durations of completed jobs, in seconds.",
+		}, []string{"job", "status"}),
+		gauges: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "health_gauge",
+			Help: "Last value of each gauge emitted.",
+		}, []string{"job", "event"}),
+		counts: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "health_counts_total",
+			Help: "Sum of the counts emitted.",
+		}, []string{"job", "event"}),
+	}
+
+	for _, c := range []prometheus.Collector{s.events, s.eventErrs, s.timings, s.completions, s.completionTimings, s.gauges, s.counts} {
+		if err := registry.Register(c); err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+// Handler serves the metrics of the sink's registry in the Prometheus exposition format.
+func (s *Sink) Handler() http.Handler {
+	return promhttp.HandlerFor(s.registry, promhttp.HandlerOpts{})
+}
+
+func (s *Sink) EmitEvent(job string, event string, kvs map[string]string) {
+	s.events.WithLabelValues(job, event).Inc()
+}
+
+func (s *Sink) EmitEventErr(job string, event string, inputErr error, kvs map[string]string) {
+	s.eventErrs.WithLabelValues(job, event).Inc()
+}
+
+func (s *Sink) EmitTiming(job string, event string, nanos int64, kvs map[string]string) {
+	s.timings.WithLabelValues(job, event).Observe(nanosToSeconds(nanos))
+}
+
+func (s *Sink) EmitComplete(job string, status health.CompletionStatus, nanos int64, kvs map[string]string) {
+	s.completions.WithLabelValues(job, status.String()).Inc()
+	s.completionTimings.WithLabelValues(job, status.String()).Observe(nanosToSeconds(nanos))
+}
+
+func (s *Sink) EmitGauge(job string, event string, value float64, kvs map[string]string) {
+	s.gauges.WithLabelValues(job, event).Set(value)
+}
+
+func (s *Sink) EmitCount(job string, event string, delta int64, kvs map[string]string) {
+	if delta > 0 {
+		s.counts.WithLabelValues(job, event).Add(float64(delta))
+	}
+}
+
+func nanosToSeconds(nanos int64) float64 {
+	return float64(nanos) / float64(time.Second)
+}
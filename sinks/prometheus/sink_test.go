@@ -0,0 +1,64 @@
+package prometheus
+
+import (
+	"errors"
+	"github.com/gocraft/health"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http/httptest"
+	"testing"
+)
+
+func scrape(t *testing.T, s *Sink) string {
+	rw := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rw, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Equal(t, 200, rw.Code)
+	body, err := ioutil.ReadAll(rw.Body)
+	assert.NoError(t, err)
+	return string(body)
+}
+
+func TestSink(t *testing.T) {
+	s, err := NewSink(nil)
+	assert.NoError(t, err)
+
+	s.EmitEvent("myjob", "myevent", nil)
+	s.EmitEvent("myjob", "myevent", nil)
+	s.EmitEventErr("myjob", "myevent", errors.New("my error"), nil)
+	s.EmitTiming("myjob", "myevent", 1500000000, nil)
+	s.EmitComplete("myjob", health.Success, 250000000, nil)
+	s.EmitGauge("myjob", "queue.depth", 42, nil)
+	s.EmitCount("myjob", "cache.miss", 3, nil)
+	s.EmitCount("myjob", "cache.miss", 2, nil)
+	s.EmitCount("myjob", "cache.miss", -1, nil)
+
+	body := scrape(t, s)
+	assert.Contains(t, body, `health_events_total{event="myevent",job="myjob"} 2`)
+	assert.Contains(t, body, `health_event_errors_total{event="myevent",job="myjob"} 1`)
+	assert.Contains(t, body, `health_timing_seconds_sum{event="myevent",job="myjob"} 1.5`)
+	assert.Contains(t, body, `health_timing_seconds_count{event="myevent",job="myjob"} 1`)
+	assert.Contains(t, body, `health_completions_total{job="myjob",status="success"} 1`)
+	assert.Contains(t, body, `health_completion_seconds_sum{job="myjob",status="success"} 0.25`)
+	assert.Contains(t, body, `health_gauge{event="queue.depth",job="myjob"} 42`)
+	assert.Contains(t, body, `health_counts_total{event="cache.miss",job="myjob"} 5`)
+}
+
+func TestSinkCustomRegistry(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	s, err := NewSink(registry)
+	assert.NoError(t, err)
+	s.EmitEvent("myjob", "myevent", nil)
+
+	families, err := registry.Gather()
+	assert.NoError(t, err)
+	names := []string{}
+	for _, f := range families {
+		names = append(names, f.GetName())
+	}
+	assert.Contains(t, names, "health_events_total")
+
+	// A second sink can't share the registry:
+	_, err = NewSink(registry)
+	assert.Error(t, err)
+}
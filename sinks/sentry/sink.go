@@ -0,0 +1,167 @@
+package sentry
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/gocraft/health"
+	"github.com/gocraft/health/stack"
+)
+
+// Hub captures events. *sentry.Hub implements it.
+type Hub interface {
+	CaptureEvent(event *sentry.Event) *sentry.EventID
+	Flush(timeout time.Duration) bool
+}
+
+var sentryLevels = map[health.Level]sentry.Level{
+	health.LevelTrace: sentry.LevelDebug,
+	health.LevelDebug: sentry.LevelDebug,
+	health.LevelInfo:  sentry.LevelInfo,
+	health.LevelWarn:  sentry.LevelWarning,
+	health.LevelError: sentry.LevelError,
+	health.LevelFatal: sentry.LevelFatal,
+}
+
+type Config struct {
+	// Hub is where events are captured. Defaults to sentry.CurrentHub(), so call sentry.Init first.
+	Hub Hub
+
+	// MinLevel is the least severe level that's sent to Sentry; emits below it are ignored (see health.Level). Defaults to health.LevelError.
+	MinLevel health.Level
+
+	// FlushTimeout is how long Flush waits for Hub to send what it's queued. Defaults to 2s.
+	FlushTimeout time.Duration
+}
+
+// Sink captures emits at or above MinLevel as Sentry events.
+// Each event is tagged with its job and event (and status, for completions), and carries its kvs in the "kvs" context.
+// Errors become Sentry exceptions, with the stack trace the Job captured if there is one.
+//
+// As with the bugsnag sink, errors a Job has muted (see health.Mute), or has already emitted once, aren't captured again.
+type Sink struct {
+	*Config
+}
+
+var _ health.Sink = &Sink{}
+
+func NewSink(config *Config) *Sink {
+	if config.Hub == nil {
+		config.Hub = sentry.CurrentHub()
+	}
+	if config.MinLevel == health.LevelNone {
+		config.MinLevel = health.LevelError
+	}
+	if config.FlushTimeout <= 0 {
+		config.FlushTimeout = 2 * time.Second
+	}
+
+	return &Sink{Config: config}
+}
+
+func (s *Sink) EmitEvent(job string, event string, kvs map[string]string) {
+	s.capture(health.EmitLevel(kvs, health.LevelInfo), job, event, "", kvs, func(w *health.WriterSink) { w.EmitEvent(job, event, kvs) }, nil)
+}
+
+func (s *Sink) EmitEventErr(job string, event string, inputErr error, kvs map[string]string) {
+	var trace *stack.Trace
+	switch err := inputErr.(type) {
+	case *health.MutedError:
+		return
+	case *health.UnmutedError:
+		if err.Emitted {
+			return
+		}
+		trace = err.Stack
+		inputErr = err.Err
+	}
+
+	s.capture(health.EmitLevel(kvs, health.LevelError), job, event, "", kvs, func(w *health.WriterSink) { w.EmitEventErr(job, event, inputErr, kvs) }, func(e *sentry.Event) {
+		e.Message = fmt.Sprint(inputErr)
+		e.SetException(inputErr, 10)
+		if trace != nil && len(e.Exception) > 0 {
+			e.Exception[len(e.Exception)-1].Stacktrace = stacktrace(trace)
+		}
+	})
+}
+
+func (s *Sink) EmitTiming(job string, event string, nanos int64, kvs map[string]string) {
+	s.capture(health.EmitLevel(kvs, health.LevelInfo), job, event, "", kvs, func(w *health.WriterSink) { w.EmitTiming(job, event, nanos, kvs) }, nil)
+}
+
+func (s *Sink) EmitComplete(job string, status health.CompletionStatus, nanos int64, kvs map[string]string) {
+	s.capture(health.EmitLevel(kvs, health.CompletionLevel(status)), job, "", status.String(), kvs, func(w *health.WriterSink) { w.EmitComplete(job, status, nanos, kvs) }, nil)
+}
+
+func (s *Sink) EmitGauge(job string, event string, value float64, kvs map[string]string) {
+	s.capture(health.EmitLevel(kvs, health.LevelInfo), job, event, "", kvs, func(w *health.WriterSink) { w.EmitGauge(job, event, value, kvs) }, nil)
+}
+
+func (s *Sink) EmitCount(job string, event string, delta int64, kvs map[string]string) {
+	s.capture(health.EmitLevel(kvs, health.LevelInfo), job, event, "", kvs, func(w *health.WriterSink) { w.EmitCount(job, event, delta, kvs) }, nil)
+}
+
+// Flush waits up to FlushTimeout for Hub to send the events it's queued.
+func (s *Sink) Flush() error {
+	if !s.Hub.Flush(s.FlushTimeout) {
+		return errors.New("sentry.Sink: timed out flushing events")
+	}
+	return nil
+}
+
+// capture sends an event at lvl to Hub, unless lvl is below MinLevel. The message is the emit rendered with emit,
+// and customize, if set, can fill in more of the event.
+func (s *Sink) capture(lvl health.Level, job, event, status string, kvs map[string]string, emit func(w *health.WriterSink), customize func(e *sentry.Event)) {
+	if lvl < s.MinLevel {
+		return
+	}
+
+	var b bytes.Buffer
+	emit(&health.WriterSink{Writer: &b, NoTimestamp: true})
+
+	e := sentry.NewEvent()
+	e.Level = sentryLevels[lvl]
+	e.Message = strings.TrimSuffix(b.String(), "\n")
+	e.Tags = map[string]string{"job": job}
+	if event != "" {
+		e.Tags["event"] = event
+	}
+	if status != "" {
+		e.Tags["status"] = status
+	}
+	if len(kvs) > 0 {
+		kvsContext := make(sentry.Context, len(kvs))
+		for k, v := range kvs {
+			kvsContext[k] = v
+		}
+		e.Contexts["kvs"] = kvsContext
+	}
+	if customize != nil {
+		customize(e)
+	}
+
+	s.Hub.CaptureEvent(e)
+}
+
+// stacktrace converts a stack trace captured by a Job to Sentry's format, which lists the outermost call first.
+func stacktrace(trace *stack.Trace) *sentry.Stacktrace {
+	frames := trace.Frames()
+	st := &sentry.Stacktrace{Frames: make([]sentry.Frame, 0, len(frames))}
+	for i := len(frames) - 1; i >= 0; i-- {
+		f := frames[i]
+		st.Frames = append(st.Frames, sentry.Frame{
+			Function: f.Name,
+			Module:   f.Package,
+			AbsPath:  f.File,
+			Filename: filepath.Base(f.File),
+			Lineno:   f.LineNumber,
+			InApp:    !f.IsSystemPackage,
+		})
+	}
+	return st
+}
@@ -0,0 +1,107 @@
+package sentry
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/gocraft/health"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeHub struct {
+	events  []*sentry.Event
+	flushed bool
+}
+
+func (h *fakeHub) CaptureEvent(event *sentry.Event) *sentry.EventID {
+	h.events = append(h.events, event)
+	id := sentry.EventID(fmt.Sprint(len(h.events)))
+	return &id
+}
+
+func (h *fakeHub) Flush(timeout time.Duration) bool {
+	return h.flushed
+}
+
+func TestSink(t *testing.T) {
+	hub := &fakeHub{}
+	s := NewSink(&Config{Hub: hub})
+
+	s.EmitEvent("myjob", "myevent", nil)
+	s.EmitEventErr("myjob", "myevent", errors.New("disk full"), map[string]string{"host": "web1"})
+	s.EmitTiming("myjob", "myevent", 1204, nil)
+	s.EmitComplete("myjob", health.Success, 1204, nil)
+	s.EmitComplete("myjob", health.Panic, 1204, nil)
+	s.EmitGauge("myjob", "myevent", 1, map[string]string{"level": "error"})
+	s.EmitCount("myjob", "myevent", 1, nil)
+
+	assert.Equal(t, 3, len(hub.events))
+
+	e := hub.events[0]
+	assert.Equal(t, sentry.LevelError, e.Level)
+	assert.Equal(t, "disk full", e.Message)
+	assert.Equal(t, map[string]string{"job": "myjob", "event": "myevent"}, e.Tags)
+	assert.Equal(t, sentry.Context{"host": "web1"}, e.Contexts["kvs"])
+	assert.Equal(t, 1, len(e.Exception))
+	assert.Equal(t, "disk full", e.Exception[0].Value)
+	assert.Equal(t, "*errors.errorString", e.Exception[0].Type)
+
+	e = hub.events[1]
+	assert.Equal(t, sentry.LevelFatal, e.Level)
+	assert.Equal(t, "job:myjob status:panic time:1204 ns", e.Message)
+	assert.Equal(t, map[string]string{"job": "myjob", "status": "panic"}, e.Tags)
+	assert.Nil(t, e.Contexts["kvs"])
+
+	e = hub.events[2]
+	assert.Equal(t, sentry.LevelError, e.Level)
+	assert.Equal(t, "job:myjob event:myevent gauge:1 kvs:[level:error]", e.Message)
+}
+
+func TestSinkMinLevel(t *testing.T) {
+	hub := &fakeHub{}
+	s := NewSink(&Config{Hub: hub, MinLevel: health.LevelWarn})
+
+	s.EmitEvent("myjob", "debug", map[string]string{"level": "debug"})
+	s.EmitEvent("myjob", "plain", nil)
+	s.EmitEvent("myjob", "warning", map[string]string{"level": "warning"})
+	s.EmitEventErr("myjob", "demoted", errors.New("meh"), map[string]string{"level": "trace"})
+
+	assert.Equal(t, 1, len(hub.events))
+	assert.Equal(t, sentry.LevelWarning, hub.events[0].Level)
+	assert.Equal(t, "warning", hub.events[0].Tags["event"])
+}
+
+func TestSinkJobErrors(t *testing.T) {
+	hub := &fakeHub{}
+	stream := health.NewStream()
+	stream.AddSink(NewSink(&Config{Hub: hub}))
+	job := stream.NewJob("myjob")
+
+	err := job.EventErr("first", fmt.Errorf("wrapped: %w", errors.New("disk full")))
+	assert.Equal(t, 1, len(hub.events))
+
+	// The chain is unwrapped, innermost first, with the Job's stack trace on the outermost error.
+	exceptions := hub.events[0].Exception
+	assert.Equal(t, 2, len(exceptions))
+	assert.Equal(t, "disk full", exceptions[0].Value)
+	assert.Equal(t, "wrapped: disk full", exceptions[1].Value)
+	frames := exceptions[1].Stacktrace.Frames
+	assert.Equal(t, "TestSinkJobErrors", frames[len(frames)-1].Function)
+
+	// Already emitted and muted errors aren't captured again.
+	job.EventErr("again", err)
+	job.EventErr("muted", health.Mute(errors.New("expected")))
+	assert.Equal(t, 1, len(hub.events))
+}
+
+func TestSinkFlush(t *testing.T) {
+	hub := &fakeHub{flushed: true}
+	s := NewSink(&Config{Hub: hub})
+	assert.NoError(t, s.Flush())
+
+	hub.flushed = false
+	assert.Error(t, s.Flush())
+}
@@ -0,0 +1,308 @@
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/gocraft/health"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"text/template"
+	"time"
+)
+
+// HTTPClient sends requests to the webhook. *http.Client implements it.
+type HTTPClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// DefaultTemplate posts {"text": "<the emit>"}, which is what Slack incoming webhooks expect.
+var DefaultTemplate = template.Must(NewTemplate(`{"text": {{json .Text}}}`))
+
+// NewTemplate parses text as a payload template. Templates are executed with an *Alert, and can use {{json x}} to write x as JSON.
+func NewTemplate(text string) (*template.Template, error) {
+	return template.New("webhook").Funcs(template.FuncMap{"json": toJSON}).Parse(text)
+}
+
+type Config struct {
+	// URL is the webhook's URL, eg a Slack incoming webhook.
+	URL string
+
+	// Client sends the posts. Defaults to an *http.Client with a 10s timeout.
+	Client HTTPClient
+
+	// MinLevel is the least severe level that's posted; emits below it are ignored (see health.Level). Defaults to health.LevelError.
+	MinLevel health.Level
+
+	// Template renders the body of each post from an *Alert. Make it with NewTemplate. Defaults to DefaultTemplate.
+	Template *template.Template
+
+	// Cooldown is how long after posting an alert the same one (same job, event, status, and error message) is ignored. Defaults to 5m.
+	Cooldown time.Duration
+
+	// RateLimiter caps how many alerts are queued to be posted. Alerts it doesn't allow are dropped and counted by Dropped.
+	// Defaults to a health.TokenBucket allowing 1 per second, in bursts of up to 5.
+	RateLimiter health.RateLimiter
+
+	// ErrorHandler, if set, is called when a post fails. If nil, the error is printed to stderr.
+	ErrorHandler func(error)
+
+	// Name, if set, identifies this sink in errors passed to ErrorHandler or printed to stderr (see health.SinkError).
+	Name string
+}
+
+// Alert is what Template is executed with.
+type Alert struct {
+	Job   string
+	Event string // Empty for completions.
+	Level string // The name of the emit's health.Level, eg "error".
+
+	// Err is the error message, for EmitEventErr.
+	Err string
+
+	// Status is the completion status, for EmitComplete.
+	Status string
+
+	Kvs  map[string]string
+	Time time.Time
+
+	// Text is the emit rendered like a health.WriterSink line, without the timestamp.
+	Text string
+}
+
+// Sink posts emits at or above MinLevel to a webhook, eg to get errors into a Slack channel during an incident.
+// Repeats of an alert within Cooldown are ignored, and RateLimiter caps the rest.
+// Alerts are posted in the background, so a slow webhook never holds up an emit: if too many alerts are waiting to be posted,
+// new ones are dropped. Call Close before exiting to post what's left.
+type Sink struct {
+	dropped uint64 // accessed atomically; keep first for 64-bit alignment on 32-bit platforms
+
+	*Config
+
+	now func() time.Time
+
+	mutex      sync.Mutex
+	lastPosted map[string]time.Time
+
+	alertChan chan *Alert
+	flushChan chan chan error
+	doneChan  chan int
+	closeOnce sync.Once
+}
+
+func NewSink(config *Config) *Sink {
+	const maxChanSize = 256
+
+	if config.Client == nil {
+		config.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+	if config.MinLevel == health.LevelNone {
+		config.MinLevel = health.LevelError
+	}
+	if config.Template == nil {
+		config.Template = DefaultTemplate
+	}
+	if config.Cooldown <= 0 {
+		config.Cooldown = 5 * time.Minute
+	}
+	if config.RateLimiter == nil {
+		config.RateLimiter = health.NewTokenBucket(1, 5)
+	}
+
+	s := &Sink{
+		Config:     config,
+		now:        time.Now,
+		lastPosted: make(map[string]time.Time),
+		alertChan:  make(chan *Alert, maxChanSize),
+		flushChan:  make(chan chan error),
+		doneChan:   make(chan int),
+	}
+
+	go postLoop(s)
+
+	return s
+}
+
+func (s *Sink) EmitEvent(job string, event string, kvs map[string]string) {
+	s.alert(health.EmitLevel(kvs, health.LevelInfo), &Alert{Job: job, Event: event, Kvs: kvs}, func(w *health.WriterSink) { w.EmitEvent(job, event, kvs) })
+}
+
+func (s *Sink) EmitEventErr(job string, event string, inputErr error, kvs map[string]string) {
+	s.alert(health.EmitLevel(kvs, health.LevelError), &Alert{Job: job, Event: event, Err: fmt.Sprint(inputErr), Kvs: kvs}, func(w *health.WriterSink) { w.EmitEventErr(job, event, inputErr, kvs) })
+}
+
+func (s *Sink) EmitTiming(job string, event string, nanos int64, kvs map[string]string) {
+	s.alert(health.EmitLevel(kvs, health.LevelInfo), &Alert{Job: job, Event: event, Kvs: kvs}, func(w *health.WriterSink) { w.EmitTiming(job, event, nanos, kvs) })
+}
+
+func (s *Sink) EmitComplete(job string, status health.CompletionStatus, nanos int64, kvs map[string]string) {
+	s.alert(health.EmitLevel(kvs, health.CompletionLevel(status)), &Alert{Job: job, Status: status.String(), Kvs: kvs}, func(w *health.WriterSink) { w.EmitComplete(job, status, nanos, kvs) })
+}
+
+func (s *Sink) EmitGauge(job string, event string, value float64, kvs map[string]string) {
+	s.alert(health.EmitLevel(kvs, health.LevelInfo), &Alert{Job: job, Event: event, Kvs: kvs}, func(w *health.WriterSink) { w.EmitGauge(job, event, value, kvs) })
+}
+
+func (s *Sink) EmitCount(job string, event string, delta int64, kvs map[string]string) {
+	s.alert(health.EmitLevel(kvs, health.LevelInfo), &Alert{Job: job, Event: event, Kvs: kvs}, func(w *health.WriterSink) { w.EmitCount(job, event, delta, kvs) })
+}
+
+// Dropped returns how many alerts have been dropped, by RateLimiter or because too many were waiting to be posted.
+// Alerts ignored for being below MinLevel or repeats within Cooldown aren't counted.
+func (s *Sink) Dropped() uint64 {
+	return atomic.LoadUint64(&s.dropped)
+}
+
+// Flush posts every alert queued so far, and returns the first error. After Close, it does nothing.
+func (s *Sink) Flush() error {
+	errChan := make(chan error)
+	select {
+	case s.flushChan <- errChan:
+		return <-errChan
+	case <-s.doneChan:
+		return nil
+	}
+}
+
+// Close posts every alert queued so far and stops the background goroutine. The sink can't be used afterwards,
+// except to call Close again, which does nothing.
+func (s *Sink) Close() error {
+	var err error
+	s.closeOnce.Do(func() {
+		err = s.Flush()
+		close(s.doneChan)
+	})
+	return err
+}
+
+// alert queues a at level, rendering its Text with emit, unless it's below MinLevel, a repeat within Cooldown, or rate limited.
+func (s *Sink) alert(level health.Level, a *Alert, emit func(w *health.WriterSink)) {
+	if level < s.MinLevel {
+		return
+	}
+	a.Level = level.String()
+
+	a.Time = s.now()
+	if !s.firstInCooldown(a) {
+		return
+	}
+	if !s.RateLimiter.Allow() {
+		atomic.AddUint64(&s.dropped, 1)
+		return
+	}
+
+	var b bytes.Buffer
+	emit(&health.WriterSink{Writer: &b, NoTimestamp: true})
+	a.Text = strings.TrimSuffix(b.String(), "\n")
+
+	// Template reads Kvs on the background goroutine, after the caller may have changed it.
+	a.Kvs = copyKvs(a.Kvs)
+
+	select {
+	case s.alertChan <- a:
+	default:
+		atomic.AddUint64(&s.dropped, 1)
+	}
+}
+
+// firstInCooldown reports whether a is the first of its kind within Cooldown, and if so starts a new cooldown for it.
+func (s *Sink) firstInCooldown(a *Alert) bool {
+	key := strings.Join([]string{a.Job, a.Event, a.Status, a.Err}, "\x00")
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if last, ok := s.lastPosted[key]; ok && a.Time.Sub(last) < s.Cooldown {
+		return false
+	}
+	for k, last := range s.lastPosted {
+		if a.Time.Sub(last) >= s.Cooldown {
+			delete(s.lastPosted, k)
+		}
+	}
+	s.lastPosted[key] = a.Time
+	return true
+}
+
+// copyKvs returns a copy of kvs, or nil if kvs is nil.
+func copyKvs(kvs map[string]string) map[string]string {
+	if kvs == nil {
+		return nil
+	}
+	c := make(map[string]string, len(kvs))
+	for k, v := range kvs {
+		c[k] = v
+	}
+	return c
+}
+
+func toJSON(v interface{}) (string, error) {
+	b, err := json.Marshal(v)
+	return string(b), err
+}
+
+func (s *Sink) handleError(err error) {
+	err = health.NameError(s.Name, err)
+	if s.ErrorHandler != nil {
+		s.ErrorHandler(err)
+	} else {
+		fmt.Fprintf(os.Stderr, "webhook.Sink: could not post. err=%v\n", err)
+	}
+}
+
+func (s *Sink) post(a *Alert) error {
+	var body bytes.Buffer
+	if err := s.Template.Execute(&body, a); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", s.URL, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("webhook.Sink: post got status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func postLoop(sink *Sink) {
+	for {
+		select {
+		case <-sink.doneChan:
+			return
+		case a := <-sink.alertChan:
+			if err := sink.post(a); err != nil {
+				sink.handleError(err)
+			}
+		case errChan := <-sink.flushChan:
+			var firstErr error
+		drain:
+			for {
+				select {
+				case a := <-sink.alertChan:
+					if err := sink.post(a); err != nil && firstErr == nil {
+						firstErr = err
+					}
+				default:
+					break drain
+				}
+			}
+			errChan <- firstErr
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package webhook
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gocraft/health"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeWebhook records the body of each post, answering each with status (or 200 if it's zero).
+type fakeWebhook struct {
+	mutex  sync.Mutex
+	bodies []string
+	status int
+}
+
+func (f *fakeWebhook) Do(req *http.Request) (*http.Response, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	body, _ := ioutil.ReadAll(req.Body)
+	f.bodies = append(f.bodies, string(body))
+
+	rec := httptest.NewRecorder()
+	if f.status != 0 {
+		rec.WriteHeader(f.status)
+	}
+	return rec.Result(), nil
+}
+
+func (f *fakeWebhook) Bodies() []string {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return append([]string(nil), f.bodies...)
+}
+
+func TestSink(t *testing.T) {
+	hook := &fakeWebhook{}
+	s := NewSink(&Config{URL: "http://hooks/", Client: hook})
+
+	s.EmitEvent("myjob", "myevent", nil)
+	s.EmitEventErr("myjob", "myevent", errors.New("disk full"), nil)
+	s.EmitComplete("myjob", health.Success, 1204000, nil)
+	s.EmitComplete("otherjob", health.Panic, 1204000, nil)
+	assert.NoError(t, s.Close())
+
+	bodies := hook.Bodies()
+	assert.Equal(t, 2, len(bodies))
+
+	var payload map[string]string
+	assert.NoError(t, json.Unmarshal([]byte(bodies[0]), &payload))
+	assert.Equal(t, "job:myjob event:myevent err:disk full", payload["text"])
+	assert.NoError(t, json.Unmarshal([]byte(bodies[1]), &payload))
+	assert.Equal(t, "job:otherjob status:panic time:1204 μs", payload["text"])
+}
+
+func TestSinkNilError(t *testing.T) {
+	hook := &fakeWebhook{}
+	s := NewSink(&Config{URL: "http://hooks/", Client: hook})
+
+	s.EmitEventErr("myjob", "myevent", nil, nil)
+	assert.NoError(t, s.Close())
+
+	assert.Equal(t, []string{`{"text": "job:myjob event:myevent err:\u003cnil\u003e"}`}, hook.Bodies())
+}
+
+func TestSinkLevels(t *testing.T) {
+	hook := &fakeWebhook{}
+	s := NewSink(&Config{URL: "http://hooks/", Client: hook, MinLevel: health.LevelWarn})
+
+	s.EmitEvent("myjob", "debug", map[string]string{"level": "debug"})
+	s.EmitEvent("myjob", "plain", nil)
+	s.EmitEvent("myjob", "warning", map[string]string{"level": "WARNING"})
+	s.EmitGauge("myjob", "fatal", 1, map[string]string{"level": "fatal"})
+	s.EmitEventErr("myjob", "demoted", errors.New("meh"), map[string]string{"level": "info"})
+	assert.NoError(t, s.Close())
+
+	bodies := hook.Bodies()
+	assert.Equal(t, 2, len(bodies))
+	assert.Contains(t, bodies[0], "event:warning")
+	assert.Contains(t, bodies[1], "event:fatal")
+}
+
+func TestSinkCooldown(t *testing.T) {
+	hook := &fakeWebhook{}
+	s := NewSink(&Config{URL: "http://hooks/", Client: hook, Cooldown: time.Minute})
+	now := time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+
+	err := errors.New("disk full")
+	s.EmitEventErr("myjob", "myevent", err, nil)
+	s.EmitEventErr("myjob", "myevent", err, nil)
+	s.EmitEventErr("myjob", "myevent", errors.New("disk on fire"), nil)
+	now = now.Add(time.Minute)
+	s.EmitEventErr("myjob", "myevent", err, nil)
+	assert.NoError(t, s.Close())
+
+	assert.Equal(t, 3, len(hook.Bodies()))
+}
+
+type denyAll struct{}
+
+func (denyAll) Allow() bool { return false }
+
+func TestSinkRateLimit(t *testing.T) {
+	hook := &fakeWebhook{}
+	s := NewSink(&Config{URL: "http://hooks/", Client: hook, RateLimiter: denyAll{}})
+
+	s.EmitEventErr("myjob", "myevent", errors.New("disk full"), nil)
+	s.EmitEventErr("myjob", "other", errors.New("disk full"), nil)
+	assert.NoError(t, s.Close())
+
+	assert.Equal(t, 0, len(hook.Bodies()))
+	assert.Equal(t, uint64(2), s.Dropped())
+}
+
+type allowAll struct{}
+
+func (allowAll) Allow() bool { return true }
+
+// blockingWebhook signals started when a post starts, then waits for release.
+type blockingWebhook struct {
+	fakeWebhook
+	started chan int
+	release chan int
+}
+
+func (f *blockingWebhook) Do(req *http.Request) (*http.Response, error) {
+	f.started <- 1
+	<-f.release
+	return f.fakeWebhook.Do(req)
+}
+
+func TestSinkQueueFull(t *testing.T) {
+	hook := &blockingWebhook{started: make(chan int, 1000), release: make(chan int)}
+	s := NewSink(&Config{URL: "http://hooks/", Client: hook, RateLimiter: allowAll{}})
+
+	s.EmitEventErr("myjob", "first", errors.New("disk full"), nil)
+	<-hook.started
+
+	// The first alert is being posted, so these fill the queue and the rest are dropped instead of blocking.
+	for i := 0; i < 300; i++ {
+		s.EmitEventErr("myjob", fmt.Sprint("event", i), errors.New("disk full"), nil)
+	}
+	assert.Equal(t, uint64(300-256), s.Dropped())
+
+	close(hook.release)
+	assert.NoError(t, s.Close())
+	assert.Equal(t, 257, len(hook.Bodies()))
+}
+
+func TestSinkCopiesKvs(t *testing.T) {
+	hook := &fakeWebhook{}
+	tmpl, err := NewTemplate(`{{index .Kvs "host"}}`)
+	assert.NoError(t, err)
+	s := NewSink(&Config{URL: "http://hooks/", Client: hook, Template: tmpl})
+
+	kvs := map[string]string{"host": "web1"}
+	s.EmitEventErr("myjob", "myevent", errors.New("disk full"), kvs)
+	kvs["host"] = "reused"
+	assert.NoError(t, s.Close())
+	assert.NoError(t, s.Close())
+
+	assert.Equal(t, []string{"web1"}, hook.Bodies())
+}
+
+func TestSinkFlushAfterClose(t *testing.T) {
+	hook := &fakeWebhook{}
+	s := NewSink(&Config{URL: "http://hooks/", Client: hook})
+
+	s.EmitEventErr("myjob", "myevent", errors.New("disk full"), nil)
+	assert.NoError(t, s.Close())
+
+	done := make(chan error)
+	go func() { done <- s.Flush() }()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("Flush after Close blocked")
+	}
+	assert.Equal(t, 1, len(hook.Bodies()))
+}
+
+func TestSinkTemplate(t *testing.T) {
+	hook := &fakeWebhook{}
+	tmpl, err := NewTemplate(`{"summary": {{json .Job}}, "severity": {{json .Level}}, "error": {{json .Err}}, "host": {{json (index .Kvs "host")}}}`)
+	assert.NoError(t, err)
+	s := NewSink(&Config{URL: "http://hooks/", Client: hook, Template: tmpl})
+
+	s.EmitEventErr("myjob", "myevent", errors.New(`bad "quote"`), map[string]string{"host": "web1"})
+	assert.NoError(t, s.Close())
+
+	assert.Equal(t, []string{`{"summary": "myjob", "severity": "error", "error": "bad \"quote\"", "host": "web1"}`}, hook.Bodies())
+}
+
+func TestSinkErrors(t *testing.T) {
+	hook := &fakeWebhook{status: http.StatusForbidden}
+	errs := make(chan error, 1)
+	s := NewSink(&Config{URL: "http://hooks/", Client: hook, ErrorHandler: func(err error) {
+		errs <- err
+	}})
+	defer s.Close()
+
+	s.EmitEventErr("myjob", "myevent", errors.New("disk full"), nil)
+	assert.Equal(t, "webhook.Sink: post got status 403", (<-errs).Error())
+}
+
+func TestSinkHTTP(t *testing.T) {
+	posted := make(chan map[string]string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		var payload map[string]string
+		json.NewDecoder(r.Body).Decode(&payload)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		posted <- payload
+	}))
+	defer server.Close()
+
+	s := NewSink(&Config{URL: server.URL})
+	defer s.Close()
+
+	s.EmitComplete("myjob", health.Error, 34567890, nil)
+	assert.Equal(t, "job:myjob status:error time:34 ms", (<-posted)["text"])
+}
@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"fmt"
 	"net"
+	"strconv"
+	"sync"
 	"time"
 )
 
@@ -20,6 +22,43 @@ type StatsDSink struct {
 	// Eg, don't include a trailing dot in the prefix.
 	// It can be "", that's fine.
 	prefix string
+
+	// If maxPacketSize is non-zero, metrics are batched into packets of up to maxPacketSize bytes.
+	// A packet is sent when it's full, on every flushInterval if that's positive, and on Flush/Close.
+	flushInterval time.Duration
+	maxPacketSize int
+	batchMutex    sync.Mutex
+	batch         bytes.Buffer
+	doneChan      chan int
+	closeOnce     sync.Once
+}
+
+// Keep batched packets under a typical ethernet MTU once IP/UDP headers are added.
+const defaultStatsDMaxPacketSize = 1432
+
+// NewBatchingStatsDSink is like NewStatsDSink, except that metrics are batched into as few UDP packets as possible.
+// Batches are sent at least every flushInterval. If flushInterval is 0 or less, a batch is only sent when it's full or on Flush.
+// Call Close on shutdown to send the last batch.
+func NewBatchingStatsDSink(addr, prefix string, flushInterval time.Duration) (*StatsDSink, error) {
+	c, err := net.DialTimeout("udp", addr, 2*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	sink := &StatsDSink{
+		SanitizationFunc: sanitizeKey,
+		conn:             c,
+		prefix:           prefix,
+		flushInterval:    flushInterval,
+		maxPacketSize:    defaultStatsDMaxPacketSize,
+	}
+
+	if flushInterval > 0 {
+		sink.doneChan = make(chan int)
+		go statsDFlushLoop(sink)
+	}
+
+	return sink, nil
 }
 
 func NewStatsDSink(addr, prefix string) (Sink, error) {
@@ -77,6 +116,20 @@ func (s *StatsDSink) EmitComplete(job string, status CompletionStatus, nanos int
 	s.measure(b.String(), nanos)
 }
 
+// If event is "my.event", and job is "cool.job", this will set the gauges "my.event" and "cool.job.my.event" (prefix applied if present)
+func (s *StatsDSink) EmitGauge(job string, event string, value float64, kvs map[string]string) {
+	key1, key2 := s.eventKeys(job, event, "")
+	s.gauge(key1, value)
+	s.gauge(key2, value)
+}
+
+// If event is "cache.miss", and job is "cool.job", this will add delta to the counters "cache.miss" and "cool.job.cache.miss" (prefix applied if present)
+func (s *StatsDSink) EmitCount(job string, event string, delta int64, kvs map[string]string) {
+	key1, key2 := s.eventKeys(job, event, "")
+	s.count(key1, delta)
+	s.count(key2, delta)
+}
+
 func (s *StatsDSink) eventKeys(job, event, suffix string) (string, string) {
 	var key1 bytes.Buffer // event
 	var key2 bytes.Buffer // job.event
@@ -110,6 +163,15 @@ func (s *StatsDSink) inc(key string) {
 	s.send(msg.Bytes())
 }
 
+func (s *StatsDSink) count(key string, delta int64) {
+	var msg bytes.Buffer
+	msg.WriteString(key)
+	msg.WriteRune(':')
+	msg.WriteString(strconv.FormatInt(delta, 10))
+	msg.WriteString("|c\n")
+	s.send(msg.Bytes())
+}
+
 func (s *StatsDSink) measure(key string, nanos int64) {
 	var msg bytes.Buffer
 	msg.WriteString(key)
@@ -119,8 +181,78 @@ func (s *StatsDSink) measure(key string, nanos int64) {
 	s.send(msg.Bytes())
 }
 
+// gauge sets key to value. StatsD reads a signed gauge value as a change to the gauge rather than a new value,
+// so a negative value is sent as a reset to 0 followed by the value, both in the same packet.
+func (s *StatsDSink) gauge(key string, value float64) {
+	var msg bytes.Buffer
+	if value < 0 {
+		msg.WriteString(key)
+		msg.WriteString(":0|g\n")
+	}
+	msg.WriteString(key)
+	msg.WriteRune(':')
+	msg.WriteString(strconv.FormatFloat(value, 'f', -1, 64))
+	msg.WriteString("|g\n")
+	s.send(msg.Bytes())
+}
+
 func (s *StatsDSink) send(msg []byte) {
-	s.conn.Write(msg)
+	if s.maxPacketSize == 0 {
+		s.conn.Write(msg)
+		return
+	}
+
+	s.batchMutex.Lock()
+	defer s.batchMutex.Unlock()
+	if s.batch.Len() > 0 && s.batch.Len()+len(msg) > s.maxPacketSize {
+		s.flushBatch()
+	}
+	s.batch.Write(msg)
+}
+
+// Flush sends any batched metrics. It's a no-op for sinks that aren't batching.
+func (s *StatsDSink) Flush() error {
+	s.batchMutex.Lock()
+	defer s.batchMutex.Unlock()
+	return s.flushBatch()
+}
+
+// Close sends any batched metrics and closes the connection to StatsD. The sink can't be used afterwards,
+// except to call Close again, which does nothing.
+func (s *StatsDSink) Close() error {
+	var err error
+	s.closeOnce.Do(func() {
+		if s.doneChan != nil {
+			s.doneChan <- 1
+		}
+		s.Flush()
+		err = s.conn.Close()
+	})
+	return err
+}
+
+// flushBatch sends the current batch. batchMutex must be held.
+func (s *StatsDSink) flushBatch() error {
+	if s.batch.Len() == 0 {
+		return nil
+	}
+	_, err := s.conn.Write(s.batch.Bytes())
+	s.batch.Reset()
+	return err
+}
+
+func statsDFlushLoop(sink *StatsDSink) {
+	ticker := time.NewTicker(sink.flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-sink.doneChan:
+			return
+		case <-ticker.C:
+			sink.Flush()
+		}
+	}
 }
 
 func sanitizeKey(k string) string {
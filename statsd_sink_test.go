@@ -131,3 +131,84 @@ func TestStatsDSinkEmitTimingSubMillisecond(t *testing.T) {
 		sink.EmitTiming("my.job", "my.event", 456789, nil)
 	})
 }
+
+func TestStatsDSinkEmitGaugePrefix(t *testing.T) {
+	sink, err := NewStatsDSink(testAddr, "metroid")
+	assert.NoError(t, err)
+	listenFor(t, []string{"metroid.my.event:3.5|g\n", "metroid.my.job.my.event:3.5|g\n"}, func() {
+		sink.EmitGauge("my.job", "my.event", 3.5, nil)
+	})
+}
+
+func TestStatsDSinkEmitGaugeNegative(t *testing.T) {
+	sink, err := NewStatsDSink(testAddr, "")
+	assert.NoError(t, err)
+	listenFor(t, []string{"my.event:0|g\nmy.event:-5|g\n", "my.job.my.event:0|g\nmy.job.my.event:-5|g\n"}, func() {
+		sink.EmitGauge("my.job", "my.event", -5, nil)
+	})
+}
+
+func TestStatsDSinkEmitGaugeNoPrefix(t *testing.T) {
+	sink, err := NewStatsDSink(testAddr, "")
+	assert.NoError(t, err)
+	listenFor(t, []string{"my.event:1024|g\n", "my.job.my.event:1024|g\n"}, func() {
+		sink.EmitGauge("my.job", "my.event", 1024, nil)
+	})
+}
+
+func TestStatsDSinkEmitCount(t *testing.T) {
+	sink, err := NewStatsDSink(testAddr, "metroid")
+	assert.NoError(t, err)
+	listenFor(t, []string{"metroid.cache.miss:3|c\n", "metroid.my.job.cache.miss:3|c\n"}, func() {
+		sink.EmitCount("my.job", "cache.miss", 3, nil)
+	})
+}
+
+func TestStatsDSinkBatching(t *testing.T) {
+	sink, err := NewBatchingStatsDSink(testAddr, "metroid", time.Hour)
+	assert.NoError(t, err)
+	defer sink.Close()
+	listenFor(t, []string{"metroid.my.event:1|c\nmetroid.my.job.my.event:1|c\nmetroid.my.event:3.5|g\nmetroid.my.job.my.event:3.5|g\n"}, func() {
+		sink.EmitEvent("my.job", "my.event", nil)
+		sink.EmitGauge("my.job", "my.event", 3.5, nil)
+		sink.Flush()
+	})
+}
+
+func TestStatsDSinkBatchingMaxPacketSize(t *testing.T) {
+	sink, err := NewBatchingStatsDSink(testAddr, "", time.Hour)
+	assert.NoError(t, err)
+	defer sink.Close()
+	sink.maxPacketSize = 40
+	listenFor(t, []string{"my.event:1|c\nmy.job.my.event:1|c\n", "my.event:1|c\nmy.job.my.event:1|c\n"}, func() {
+		sink.EmitEvent("my.job", "my.event", nil)
+		sink.EmitEvent("my.job", "my.event", nil)
+		sink.Flush()
+	})
+}
+
+func TestStatsDSinkBatchingFlushInterval(t *testing.T) {
+	sink, err := NewBatchingStatsDSink(testAddr, "", 10*time.Millisecond)
+	assert.NoError(t, err)
+	defer sink.Close()
+	listenFor(t, []string{"my.event:1|c\nmy.job.my.event:1|c\n"}, func() {
+		sink.EmitEvent("my.job", "my.event", nil)
+	})
+}
+
+func TestStatsDSinkBatchingNoFlushInterval(t *testing.T) {
+	sink, err := NewBatchingStatsDSink(testAddr, "", 0)
+	assert.NoError(t, err)
+	defer sink.Close()
+	listenFor(t, []string{"my.event:1|c\nmy.job.my.event:1|c\n"}, func() {
+		sink.EmitEvent("my.job", "my.event", nil)
+		sink.Flush()
+	})
+}
+
+func TestStatsDSinkCloseTwice(t *testing.T) {
+	sink, err := NewBatchingStatsDSink(testAddr, "", time.Hour)
+	assert.NoError(t, err)
+	assert.NoError(t, sink.Close())
+	assert.NoError(t, sink.Close())
+}
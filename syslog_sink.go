@@ -0,0 +1,81 @@
+package health
+
+import (
+	"bytes"
+	"strings"
+)
+
+// SyslogWriter is the part of *syslog.Writer (from log/syslog) that SyslogSink uses. Each method writes m at that severity.
+type SyslogWriter interface {
+	Err(m string) error
+	Warning(m string) error
+	Info(m string) error
+	Debug(m string) error
+}
+
+// SyslogSink writes each emit to syslog at a severity that fits it, rather than the single priority a syslog.Writer was opened with.
+// Lines are rendered like a WriterSink's, minus the timestamp (syslog adds its own):
+//
+//	w, err := syslog.New(syslog.LOG_INFO|syslog.LOG_DAEMON, "myapp")
+//	stream.AddSink(&health.SyslogSink{Writer: w})
+//
+// The severity comes from the emit's Level: fatal and error map to LOG_ERR, warn to LOG_WARNING, info to LOG_INFO, and debug and trace to LOG_DEBUG.
+// So errors and completions with a Panic, Error, or Timeout status go out at LOG_ERR, and everything else at LOG_INFO, unless kvs has a "level".
+type SyslogSink struct {
+	Writer SyslogWriter
+
+	// ErrorHandler, if set, is called with any error returned by Writer.
+	ErrorHandler func(error)
+
+	// Name, if set, identifies this sink in errors passed to ErrorHandler (see SinkError).
+	Name string
+}
+
+var _ Sink = &SyslogSink{}
+
+func (s *SyslogSink) EmitEvent(job string, event string, kvs map[string]string) {
+	s.write(EmitLevel(kvs, LevelInfo), func(w *WriterSink) { w.EmitEvent(job, event, kvs) })
+}
+
+func (s *SyslogSink) EmitEventErr(job string, event string, inputErr error, kvs map[string]string) {
+	s.write(EmitLevel(kvs, LevelError), func(w *WriterSink) { w.EmitEventErr(job, event, inputErr, kvs) })
+}
+
+func (s *SyslogSink) EmitTiming(job string, event string, nanos int64, kvs map[string]string) {
+	s.write(EmitLevel(kvs, LevelInfo), func(w *WriterSink) { w.EmitTiming(job, event, nanos, kvs) })
+}
+
+func (s *SyslogSink) EmitComplete(job string, status CompletionStatus, nanos int64, kvs map[string]string) {
+	s.write(EmitLevel(kvs, CompletionLevel(status)), func(w *WriterSink) { w.EmitComplete(job, status, nanos, kvs) })
+}
+
+func (s *SyslogSink) EmitGauge(job string, event string, value float64, kvs map[string]string) {
+	s.write(EmitLevel(kvs, LevelInfo), func(w *WriterSink) { w.EmitGauge(job, event, value, kvs) })
+}
+
+func (s *SyslogSink) EmitCount(job string, event string, delta int64, kvs map[string]string) {
+	s.write(EmitLevel(kvs, LevelInfo), func(w *WriterSink) { w.EmitCount(job, event, delta, kvs) })
+}
+
+// write renders the line with a WriterSink and sends it to Writer at the severity for level.
+func (s *SyslogSink) write(level Level, emit func(w *WriterSink)) {
+	var b bytes.Buffer
+	emit(&WriterSink{Writer: &b, NoTimestamp: true})
+	line := strings.TrimSuffix(b.String(), "\n")
+
+	var err error
+	switch {
+	case level >= LevelError:
+		err = s.Writer.Err(line)
+	case level == LevelWarn:
+		err = s.Writer.Warning(line)
+	case level <= LevelDebug:
+		err = s.Writer.Debug(line)
+	default:
+		err = s.Writer.Info(line)
+	}
+
+	if err != nil && s.ErrorHandler != nil {
+		s.ErrorHandler(NameError(s.Name, err))
+	}
+}
@@ -0,0 +1,65 @@
+package health
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// fakeSyslogWriter records the severity and message of each write.
+type fakeSyslogWriter struct {
+	severities []string
+	messages   []string
+	err        error
+}
+
+func (w *fakeSyslogWriter) record(severity string, m string) error {
+	w.severities = append(w.severities, severity)
+	w.messages = append(w.messages, m)
+	return w.err
+}
+
+func (w *fakeSyslogWriter) Err(m string) error     { return w.record("err", m) }
+func (w *fakeSyslogWriter) Warning(m string) error { return w.record("warning", m) }
+func (w *fakeSyslogWriter) Info(m string) error    { return w.record("info", m) }
+func (w *fakeSyslogWriter) Debug(m string) error   { return w.record("debug", m) }
+
+func TestSyslogSink(t *testing.T) {
+	w := &fakeSyslogWriter{}
+	sink := &SyslogSink{Writer: w}
+
+	sink.EmitEvent("myjob", "myevent", nil)
+	sink.EmitEventErr("myjob", "myevent", testErr, nil)
+	sink.EmitTiming("myjob", "myevent", 34567890, nil)
+	sink.EmitComplete("myjob", Success, 34567890, nil)
+	sink.EmitComplete("myjob", Panic, 34567890, nil)
+	sink.EmitGauge("myjob", "myevent", 3.14, nil)
+
+	assert.Equal(t, []string{"info", "err", "info", "info", "err", "info"}, w.severities)
+	assert.Equal(t, "job:myjob event:myevent", w.messages[0])
+	assert.Equal(t, "job:myjob event:myevent err:my test error", w.messages[1])
+	assert.Equal(t, "job:myjob status:panic time:34 ms", w.messages[4])
+}
+
+func TestSyslogSinkLevelKv(t *testing.T) {
+	w := &fakeSyslogWriter{}
+	sink := &SyslogSink{Writer: w}
+
+	for _, level := range []string{"error", "ERR", "warn", "Warning", "info", "debug", "trace", "bogus"} {
+		sink.EmitEvent("myjob", "myevent", map[string]string{"level": level})
+	}
+	sink.EmitEventErr("myjob", "myevent", testErr, map[string]string{"level": "warn"})
+
+	assert.Equal(t, []string{"err", "err", "warning", "warning", "info", "debug", "debug", "info", "warning"}, w.severities)
+	assert.Equal(t, "job:myjob event:myevent kvs:[level:error]", w.messages[0])
+}
+
+func TestSyslogSinkErrorHandler(t *testing.T) {
+	var handled error
+	sink := &SyslogSink{Writer: &fakeSyslogWriter{err: errors.New("syslog down")}, ErrorHandler: func(err error) {
+		handled = err
+	}}
+
+	sink.EmitEvent("myjob", "myevent", nil)
+	assert.Equal(t, "syslog down", handled.Error())
+}
@@ -0,0 +1,91 @@
+package health
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// stringKvs renders typed kvs as strings, for sinks that only deal in strings. See kvString.
+func stringKvs(kvs map[string]interface{}) map[string]string {
+	if kvs == nil {
+		return nil
+	}
+	strs := make(map[string]string, len(kvs))
+	for k, v := range kvs {
+		strs[k] = kvString(v)
+	}
+	return strs
+}
+
+// kvString renders a typed kvs value: strings as-is, bools as "true" or "false", integers in base 10,
+// floats like gauges (eg "3.5", never in exponent form), nil as "", and anything else with fmt.Sprint.
+func kvString(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case int:
+		return strconv.FormatInt(int64(v), 10)
+	case int8:
+		return strconv.FormatInt(int64(v), 10)
+	case int16:
+		return strconv.FormatInt(int64(v), 10)
+	case int32:
+		return strconv.FormatInt(int64(v), 10)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case uint:
+		return strconv.FormatUint(uint64(v), 10)
+	case uint8:
+		return strconv.FormatUint(uint64(v), 10)
+	case uint16:
+		return strconv.FormatUint(uint64(v), 10)
+	case uint32:
+		return strconv.FormatUint(uint64(v), 10)
+	case uint64:
+		return strconv.FormatUint(v, 10)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case json.Number:
+		return v.String()
+	}
+	return fmt.Sprint(v)
+}
+
+// jsonKvs returns typed kvs with values that JSON has a type for (strings, bools, numbers, and nil) kept as they are,
+// and anything else rendered by kvString, so that a value encoding/json can't handle (eg, a channel) doesn't lose the whole line.
+// Floats that aren't finite are rendered by kvString too, since JSON has no number for them.
+func jsonKvs(kvs map[string]interface{}) map[string]interface{} {
+	if len(kvs) == 0 {
+		return nil
+	}
+	vals := make(map[string]interface{}, len(kvs))
+	for k, v := range kvs {
+		switch v.(type) {
+		case nil, string, bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, json.Number:
+			vals[k] = v
+		case float32:
+			vals[k] = jsonFiniteFloat(float64(v), v)
+		case float64:
+			vals[k] = jsonFiniteFloat(v, v)
+		default:
+			vals[k] = kvString(v)
+		}
+	}
+	return vals
+}
+
+// jsonFiniteFloat returns v if f is finite, and otherwise f rendered by kvString.
+func jsonFiniteFloat(f float64, v interface{}) interface{} {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return kvString(f)
+	}
+	return v
+}
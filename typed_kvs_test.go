@@ -0,0 +1,32 @@
+package health
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKvString(t *testing.T) {
+	cases := []struct {
+		v    interface{}
+		want string
+	}{
+		{"abc", "abc"},
+		{true, "true"},
+		{false, "false"},
+		{42, "42"},
+		{int64(-7), "-7"},
+		{uint8(255), "255"},
+		{3.5, "3.5"},
+		{1e21, "1000000000000000000000"},
+		{float32(0.1), "0.1"},
+		{json.Number("12.50"), "12.50"},
+		{nil, ""},
+		{testErr, "my test error"},
+		{[]int{1, 2}, "[1 2]"},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.want, kvString(c.v), "%#v", c.v)
+	}
+}
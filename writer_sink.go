@@ -2,103 +2,1003 @@ package health
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
+	"github.com/gocraft/health/stack"
 	"io"
+	"os"
+	"path/filepath"
+	"runtime"
 	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
+	"unicode"
+	"unicode/utf8"
 )
 
 // This sink writes bytes in a format that a human might like to read in a logfile
 // This can be used to log to Stdout:
-//   .AddSink(WriterSink{os.Stdout})
+//
+//	.AddSink(&WriterSink{Writer: os.Stdout})
+//
 // And to a file:
-//   f, err := os.OpenFile(fname, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
-//   .AddSink(WriterSink{f})
+//
+//	f, err := os.OpenFile(fname, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
+//	.AddSink(&WriterSink{Writer: f})
+//
 // And to syslog:
-//   w, err := syslog.New(LOG_INFO, "wat")
-//   .AddSink(WriterSink{w})
+//
+//	w, err := syslog.New(LOG_INFO, "wat")
+//	.AddSink(&WriterSink{Writer: w})
 type WriterSink struct {
 	io.Writer
+
+	// TimeFormat is the layout used to format the timestamp at the start of each line.
+	// If empty, time.RFC3339Nano is used.
+	TimeFormat string
+
+	// Location is the time zone timestamps are rendered in. If nil, UTC is used.
+	Location *time.Location
+
+	// Clock returns the time used for each line's timestamp. If nil, time.Now is used.
+	// Set it to a fixed clock for stable output in tests.
+	Clock func() time.Time
+
+	// NoTimestamp leaves the timestamp off of each line, so lines start with "job:" (or "job=" for Logfmt).
+	// Use it when something else already timestamps each line, eg journald or Docker.
+	NoTimestamp bool
+
+	// CollapseTimestamps replaces a line's timestamp with a short `[ "]:` marker when it's the same as the previous line's,
+	// to cut the noise when tailing bursts of lines by eye. It only applies to the bracketed format, and is off by default
+	// since it makes lines harder to grep and parse.
+	CollapseTimestamps bool
+
+	// Prefix, if set, is written as-is right after the timestamp and trace field (or at the start of the line if there are neither),
+	// eg "[auth]" to tell apart the lines of subsystems that share a log.
+	Prefix string
+
+	// TraceKey, if set, is a kvs key (eg "trace_id") whose value is written as a "trace:" field right after the timestamp,
+	// ahead of Prefix and job, instead of in the kvs block. That keeps correlation IDs in the same spot on every line.
+	// Lines without the key are written as usual.
+	TraceKey string
+
+	// Caller adds the file and line the emit came from, eg "caller:main.go:42".
+	// Frames inside this package (eg, Job and the wrapping sinks) are skipped, so it's your code's call site.
+	// Emits that go through an AsyncSink come from its goroutine, so they don't have a useful caller.
+	// It costs a stack walk per line, so it's off by default.
+	Caller bool
+
+	// FieldSeparator goes between top-level fields, eg "\t" for tab-delimited output. If empty, a space is used.
+	// In the bracketed format, the kvs block counts as one field, and the pairs inside it are still separated by spaces.
+	// In Logfmt, each kv is a top-level field.
+	FieldSeparator string
+
+	// LineEnding ends each line. If empty, "\n" is used. Set it to "\r\n" for collectors that expect CRLF.
+	LineEnding string
+
+	// Format selects how lines are rendered. Defaults to the bracketed format shown in the README.
+	Format WriterSinkFormat
+
+	// Color wraps the err field and completion statuses in ANSI color codes: red for errors and panics,
+	// yellow for validation errors and junk, and green for success. Only enable it when writing to a terminal (see IsTerminal).
+	Color bool
+
+	// PriorityKeys are written first in kvs, in the given order. The remaining keys follow, sorted.
+	PriorityKeys []string
+
+	// KvsRenderer, if set, writes kvs in place of the Format's usual rendering, eg as JSON for a backend that wants that.
+	// It's called with the line so far and the kvs after StaticKvs, IncludeKeys, RedactKey, and MaxValueLen have been applied,
+	// and writes whatever should follow, including any leading separator. It's called for every line,
+	// so it must handle a nil or empty kvs (usually by writing nothing). PriorityKeys is up to the renderer.
+	KvsRenderer func(b *bytes.Buffer, kvs map[string]string)
+
+	// ReservedKeyPolicy controls what happens to kvs keys that collide with the names of fields a line can have
+	// (ts, time, job, event, err, status, gauge, count, attempts, caller, and kvs), which can make lines ambiguous, especially in Logfmt.
+	// Defaults to ReservedKeysPassThrough.
+	ReservedKeyPolicy ReservedKeyPolicy
+
+	// IncludeKeys, if non-empty, are the only kvs keys written (including ones from StaticKvs and UnwrapErrors); the rest are left out.
+	// Use it to keep lines short while still passing rich kvs to other sinks.
+	IncludeKeys []string
+
+	// RedactKey, if set, is called with each kvs key. Values of keys it returns true for are written as [REDACTED].
+	// See RedactKeysMatching for a ready-made one.
+	RedactKey func(key string) bool
+
+	// MaxErrLen is the longest the err field of EmitEventErr can be, in bytes, before it's truncated the same way as MaxValueLen truncates kvs values.
+	// Zero means no limit. Either way, newlines in the error (and whitespace around them) are collapsed to a single space so the line stays a line.
+	MaxErrLen int
+
+	// MaxValueLen is the longest a kvs value can be, in bytes, before it's truncated.
+	// Truncated values end with an ellipsis and their original length, eg "abc…(4096 bytes)". Zero means no limit.
+	MaxValueLen int
+
+	// MaxLineLen is the longest a line can be, in bytes, counting its LineEnding. Longer lines are cut short, without splitting a UTF-8 rune,
+	// and end with an ellipsis before the LineEnding. Use it for transports that reject long lines, eg syslog. Zero means no limit.
+	MaxLineLen int
+
+	// StaticKvs are added to the kvs of every line. If a key is in both, the emitted kvs win.
+	StaticKvs map[string]string
+
+	// Version, if set, is added to the kvs of every line as "version", eg the build's git sha for correlating lines with deploys.
+	// A "version" in StaticKvs or the emitted kvs wins.
+	Version string
+
+	// UnwrapErrors adds details about the error chain (as followed by errors.Unwrap) to error lines:
+	//   - err_chain: the type of each error in the chain, outermost first, eg "*fmt.wrapError>*fs.PathError>syscall.Errno"
+	//   - err_type: the type of the innermost error, eg "syscall.Errno"
+	//   - a "stack" kv with the first few frames of the stack trace, if the error was captured by a Job
+	// MutedError and UnmutedError wrappers are left out of err_chain.
+	UnwrapErrors bool
+
+	// ContextKeys maps context keys to kvs keys for the Emit*Context methods.
+	// Each context key with a value in the context is added to the line's kvs under its kvs key. The emitted kvs win on collisions.
+	ContextKeys map[interface{}]string
+
+	// DurationFormatter, if set, renders the time: field of timings and completions from nanoseconds.
+	// If nil, durations are rendered like "34 ms", "1204 μs", or "32 ns".
+	// Set it to FormatDuration to render them like time.Duration does, eg "2m3.5s" or "1.204ms".
+	DurationFormatter func(nanos int64) string
+
+	// TimingUnit forces every time: field into one unit, so timings are comparable at a glance, eg "34.56789 ms" with Millis.
+	// The default, Auto, picks a unit per value as described for DurationFormatter. DurationFormatter, if set, takes precedence.
+	TimingUnit TimingUnit
+
+	// DurationPrecision, if positive, renders time: fields with that many decimal places instead of as whole units.
+	// With Auto, the largest unit up to ms that the duration reaches is used, eg "1.204 ms" or "34.568 ms" with a precision of 3,
+	// and durations under a microsecond stay in whole ns. With a fixed TimingUnit, it rounds that unit's value, eg "0.035 s".
+	DurationPrecision int
+
+	// NanosKv adds a "nanos" kv with the exact duration in nanoseconds to timing and completion lines,
+	// for tooling that wants more than the rounded time: field. It replaces any "nanos" kv passed in.
+	NanosKv bool
+
+	// ErrorHandler, if set, is called with any error returned by Writer.
+	// If nil, write errors are ignored.
+	ErrorHandler func(error)
+
+	// Name, if set, identifies this sink in errors passed to ErrorHandler: they're wrapped in a *SinkError with this Name.
+	// It doesn't affect output.
+	Name string
+
+	// OnFatal, if set, is called after each fatal line is written: one whose level is LevelFatal, ie whose kvs["level"] is fatal,
+	// or a Panic completion without a level kv (see Level). Use it to flush other sinks, or to exit.
+	OnFatal func()
+
+	// ExitOnFatal, if set and OnFatal isn't, flushes Writer and exits the process with status 1 after a fatal line is written.
+	ExitOnFatal bool
+
+	// writeMutex makes sure each line is written to Writer atomically with respect to other goroutines. It also guards closed and lastTimestamp.
+	writeMutex    sync.Mutex
+	closed        bool
+	lastTimestamp string
+}
+
+var _ Sink = &WriterSink{}
+
+const (
+	ansiRed    = "\x1b[31m"
+	ansiGreen  = "\x1b[32m"
+	ansiYellow = "\x1b[33m"
+	ansiReset  = "\x1b[0m"
+)
+
+var completionStatusColors = map[CompletionStatus]string{
+	Success:         ansiGreen,
+	ValidationError: ansiYellow,
+	Panic:           ansiRed,
+	Error:           ansiRed,
+	Junk:            ansiYellow,
+	Timeout:         ansiRed,
+	Cancelled:       ansiYellow,
+}
+
+// ReservedKeyPolicy is what a WriterSink does with kvs keys that collide with field names.
+type ReservedKeyPolicy int
+
+const (
+	// ReservedKeysPassThrough writes colliding keys as-is.
+	ReservedKeysPassThrough ReservedKeyPolicy = iota
+
+	// ReservedKeysRename prefixes colliding keys with "kv_", eg "job" becomes "kv_job". The renamed key wins over a "kv_job" already in kvs.
+	ReservedKeysRename
+
+	// ReservedKeysDrop leaves colliding keys out.
+	ReservedKeysDrop
+)
+
+var reservedKeys = map[string]bool{
+	"ts":       true,
+	"time":     true,
+	"job":      true,
+	"event":    true,
+	"err":      true,
+	"status":   true,
+	"gauge":    true,
+	"count":    true,
+	"attempts": true,
+	"caller":   true,
+	"kvs":      true,
 }
 
+type WriterSinkFormat int
+
+const (
+	// Bracketed renders lines like:
+	//   [2015-03-11T22:53:22.115855203Z]: job:myjob event:myevent kvs:[a:b c:d]
+	Bracketed WriterSinkFormat = iota
+
+	// Logfmt renders lines like:
+	//   ts=2015-03-11T22:53:22.115855203Z job=myjob event=myevent a=b c="has spaces"
+	Logfmt
+)
+
+// TimingUnit is the unit WriterSink renders durations in.
+type TimingUnit int
+
+const (
+	// Auto renders durations in whichever of ns, μs, or ms suits the value, as whole numbers, eg "34 ms".
+	Auto TimingUnit = iota
+
+	// Nanos renders durations as whole nanoseconds, eg "34567890 ns".
+	Nanos
+
+	// Micros renders durations in microseconds, with as many decimals as it takes to be exact, eg "34567.89 μs".
+	Micros
+
+	// Millis renders durations in milliseconds, with as many decimals as it takes to be exact, eg "34.56789 ms".
+	Millis
+
+	// Seconds renders durations in seconds, with as many decimals as it takes to be exact, eg "0.03456789 s".
+	Seconds
+)
+
 func (s *WriterSink) EmitEvent(job string, event string, kvs map[string]string) {
-	var b bytes.Buffer
-	b.WriteRune('[')
-	b.WriteString(timestamp())
-	b.WriteString("]: job:")
-	b.WriteString(job)
-	b.WriteString(" event:")
-	b.WriteString(event)
-	writeMapConsistently(&b, kvs)
-	b.WriteRune('\n')
-	s.Writer.Write(b.Bytes())
+	b := getWriterSinkBuffer()
+	defer putWriterSinkBuffer(b)
+	s.writeLineStart(b, kvs)
+	s.writeField(b, "job", s.escapedName(job))
+	s.writeField(b, "event", s.escapedName(event))
+	s.writeCaller(b)
+	s.writeKvs(b, kvs)
+	s.writeLineEnding(b)
+	s.write(b.Bytes())
+	s.fatal(EmitLevel(kvs, LevelInfo))
+}
+
+// EmitEventKV is EmitEvent with typed kvs values, so callers don't have to stringify numbers and bools themselves.
+// Values are rendered as strings: bools as "true" or "false", numbers like gauges (eg "3.5"), nil as "", and anything else with fmt.Sprint.
+func (s *WriterSink) EmitEventKV(job string, event string, kvs map[string]interface{}) {
+	s.EmitEvent(job, event, stringKvs(kvs))
 }
 
 func (s *WriterSink) EmitEventErr(job string, event string, inputErr error, kvs map[string]string) {
-	var b bytes.Buffer
-	b.WriteRune('[')
-	b.WriteString(timestamp())
-	b.WriteString("]: job:")
-	b.WriteString(job)
-	b.WriteString(" event:")
-	b.WriteString(event)
-	b.WriteString(" err:")
-	b.WriteString(inputErr.Error())
-	writeMapConsistently(&b, kvs)
-	b.WriteRune('\n')
-	s.Writer.Write(b.Bytes())
+	b := getWriterSinkBuffer()
+	defer putWriterSinkBuffer(b)
+	s.writeLineStart(b, kvs)
+	s.writeField(b, "job", s.escapedName(job))
+	s.writeField(b, "event", s.escapedName(event))
+	s.writeColoredField(b, "err", s.errString(inputErr), ansiRed)
+	if s.UnwrapErrors {
+		kvs = s.writeErrorChain(b, inputErr, kvs)
+	}
+	s.writeCaller(b)
+	s.writeKvs(b, kvs)
+	s.writeLineEnding(b)
+	s.write(b.Bytes())
+	s.fatal(EmitLevel(kvs, LevelError))
+}
+
+// errString returns err's message on one line, truncated to MaxErrLen.
+func (s *WriterSink) errString(err error) string {
+	msg := errorMessage(err)
+	if strings.ContainsAny(msg, "\r\n") {
+		msg = collapseNewlines(msg)
+	}
+	if s.MaxErrLen > 0 && len(msg) > s.MaxErrLen {
+		msg = truncateString(msg, s.MaxErrLen)
+	}
+	return msg
+}
+
+// collapseNewlines replaces each line break in v, along with the whitespace around it, with a single space.
+func collapseNewlines(v string) string {
+	var lines []string
+	for _, line := range strings.FieldsFunc(v, func(r rune) bool { return r == '\n' || r == '\r' }) {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, " ")
 }
 
 func (s *WriterSink) EmitTiming(job string, event string, nanos int64, kvs map[string]string) {
-	var b bytes.Buffer
-	b.WriteRune('[')
-	b.WriteString(timestamp())
-	b.WriteString("]: job:")
-	b.WriteString(job)
-	b.WriteString(" event:")
-	b.WriteString(event)
-	b.WriteString(" time:")
-	writeNanoseconds(&b, nanos)
-	writeMapConsistently(&b, kvs)
-	b.WriteRune('\n')
-	s.Writer.Write(b.Bytes())
+	b := getWriterSinkBuffer()
+	defer putWriterSinkBuffer(b)
+	s.writeLineStart(b, kvs)
+	s.writeField(b, "job", s.escapedName(job))
+	s.writeField(b, "event", s.escapedName(event))
+	s.writeField(b, "time", s.duration(nanos))
+	s.writeCaller(b)
+	s.writeKvs(b, s.nanosKvs(nanos, kvs))
+	s.writeLineEnding(b)
+	s.write(b.Bytes())
+	s.fatal(EmitLevel(kvs, LevelInfo))
 }
 
 func (s *WriterSink) EmitComplete(job string, status CompletionStatus, nanos int64, kvs map[string]string) {
-	var b bytes.Buffer
+	b := getWriterSinkBuffer()
+	defer putWriterSinkBuffer(b)
+	s.writeLineStart(b, kvs)
+	s.writeField(b, "job", s.escapedName(job))
+	s.writeColoredField(b, "status", status.String(), completionStatusColors[status])
+	s.writeField(b, "time", s.duration(nanos))
+	s.writeCaller(b)
+	s.writeKvs(b, s.nanosKvs(nanos, kvs))
+	s.writeLineEnding(b)
+	s.write(b.Bytes())
+	s.fatal(EmitLevel(kvs, CompletionLevel(status)))
+}
+
+// EmitCompleteN is EmitComplete for jobs that retry: it also writes how many attempts the job took, as " attempts:3".
+func (s *WriterSink) EmitCompleteN(job string, status CompletionStatus, nanos int64, attempts int, kvs map[string]string) {
+	b := getWriterSinkBuffer()
+	defer putWriterSinkBuffer(b)
+	s.writeLineStart(b, kvs)
+	s.writeField(b, "job", s.escapedName(job))
+	s.writeColoredField(b, "status", status.String(), completionStatusColors[status])
+	s.writeField(b, "time", s.duration(nanos))
+	s.writeField(b, "attempts", strconv.Itoa(attempts))
+	s.writeCaller(b)
+	s.writeKvs(b, s.nanosKvs(nanos, kvs))
+	s.writeLineEnding(b)
+	s.write(b.Bytes())
+	s.fatal(EmitLevel(kvs, CompletionLevel(status)))
+}
+
+func (s *WriterSink) EmitGauge(job string, event string, value float64, kvs map[string]string) {
+	s.EmitGaugeUnit(job, event, value, "", kvs)
+}
+
+// EmitGaugeUnit is EmitGauge for values with a unit, eg "bytes" or "%". The unit is written right after the value, as " gauge:512bytes".
+// An empty unit writes the same line as EmitGauge.
+func (s *WriterSink) EmitGaugeUnit(job string, event string, value float64, unit string, kvs map[string]string) {
+	b := getWriterSinkBuffer()
+	defer putWriterSinkBuffer(b)
+	s.writeLineStart(b, kvs)
+	s.writeField(b, "job", s.escapedName(job))
+	s.writeField(b, "event", s.escapedName(event))
+	s.writeField(b, "gauge", s.escapedName(strconv.FormatFloat(value, 'f', -1, 64)+unit))
+	s.writeCaller(b)
+	s.writeKvs(b, kvs)
+	s.writeLineEnding(b)
+	s.write(b.Bytes())
+	s.fatal(EmitLevel(kvs, LevelInfo))
+}
+
+func (s *WriterSink) EmitCount(job string, event string, delta int64, kvs map[string]string) {
+	b := getWriterSinkBuffer()
+	defer putWriterSinkBuffer(b)
+	s.writeLineStart(b, kvs)
+	s.writeField(b, "job", s.escapedName(job))
+	s.writeField(b, "event", s.escapedName(event))
+	s.writeField(b, "count", strconv.FormatInt(delta, 10))
+	s.writeCaller(b)
+	s.writeKvs(b, kvs)
+	s.writeLineEnding(b)
+	s.write(b.Bytes())
+	s.fatal(EmitLevel(kvs, LevelInfo))
+}
+
+// writerSinkBuffers pools the buffers lines are assembled in so that emitting doesn't allocate a new buffer every time.
+var writerSinkBuffers = sync.Pool{
+	New: func() interface{} {
+		return new(bytes.Buffer)
+	},
+}
+
+// Buffers that grew larger than this (eg, from a giant kvs value) are dropped rather than pooled so they don't pin memory.
+const maxPooledWriterSinkBufferSize = 64 * 1024
+
+func getWriterSinkBuffer() *bytes.Buffer {
+	return writerSinkBuffers.Get().(*bytes.Buffer)
+}
+
+func putWriterSinkBuffer(b *bytes.Buffer) {
+	if b.Cap() > maxPooledWriterSinkBufferSize {
+		return
+	}
+	b.Reset()
+	writerSinkBuffers.Put(b)
+}
+
+// Flush flushes Writer if it buffers, eg if it's a *bufio.Writer. Otherwise it does nothing.
+func (s *WriterSink) Flush() error {
+	f, ok := s.Writer.(Flusher)
+	if !ok {
+		return nil
+	}
+	s.writeMutex.Lock()
+	defer s.writeMutex.Unlock()
+	return f.Flush()
+}
+
+// Close flushes Writer if it buffers and then closes it if it's an io.Closer, eg an *os.File, returning the first error.
+// Emits after Close are dropped, and calling it again does nothing. Don't call it if Writer is os.Stdout or os.Stderr
+// and you don't mean to close them.
+func (s *WriterSink) Close() error {
+	s.writeMutex.Lock()
+	defer s.writeMutex.Unlock()
+
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+
+	var err error
+	if f, ok := s.Writer.(Flusher); ok {
+		err = f.Flush()
+	}
+	if c, ok := s.Writer.(io.Closer); ok {
+		if closeErr := c.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return NameError(s.Name, err)
+}
+
+func (s *WriterSink) write(line []byte) {
+	s.writeMutex.Lock()
+	if s.closed {
+		s.writeMutex.Unlock()
+		return
+	}
+	if s.CollapseTimestamps && !s.NoTimestamp && s.Format == Bracketed {
+		line = s.collapseTimestamp(line)
+	}
+	_, err := s.Writer.Write(line)
+	s.writeMutex.Unlock()
+
+	// Call the handler outside the lock in case it emits to this sink.
+	if err != nil && s.ErrorHandler != nil {
+		s.ErrorHandler(NameError(s.Name, err))
+	}
+}
+
+// osExit is os.Exit, except in tests.
+var osExit = os.Exit
+
+// fatal calls OnFatal, or exits if ExitOnFatal is set, if level is LevelFatal. It's called after the line is written.
+func (s *WriterSink) fatal(level Level) {
+	if level != LevelFatal {
+		return
+	}
+	if s.OnFatal != nil {
+		s.OnFatal()
+	} else if s.ExitOnFatal {
+		s.Flush()
+		osExit(1)
+	}
+}
+
+const collapsedTimestamp = `[ "]:`
+
+// collapseTimestamp returns line with its "[timestamp]:" start replaced by collapsedTimestamp if the timestamp matches the previous line's.
+// It works in place, since line is a scratch buffer. writeMutex must be held, so that "previous" means previously written.
+func (s *WriterSink) collapseTimestamp(line []byte) []byte {
+	end := bytes.Index(line, []byte("]:"))
+	if len(line) == 0 || line[0] != '[' || end < 0 {
+		return line
+	}
+	ts := line[1:end]
+	if string(ts) != s.lastTimestamp {
+		s.lastTimestamp = string(ts)
+		return line
+	}
+
+	start := end + 2 - len(collapsedTimestamp)
+	if start < 0 {
+		return line
+	}
+	copy(line[start:], collapsedTimestamp)
+	return line[start:]
+}
+
+// healthFuncPrefix is the start of the name of every function in this package, as reported by runtime.
+const healthFuncPrefix = "github.com/gocraft/health."
+
+// writeCaller writes the caller field if Caller is set.
+func (s *WriterSink) writeCaller(b *bytes.Buffer) {
+	if !s.Caller {
+		return
+	}
+	if caller := callerLocation(); caller != "" {
+		s.writeField(b, "caller", caller)
+	}
+}
+
+// callerLocation returns "file.go:42" for the first frame on the stack outside of this package.
+// Frames in this package's tests count as outside of it.
+func callerLocation() string {
+	var pcs [32]uintptr
+	n := runtime.Callers(2, pcs[:])
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, healthFuncPrefix) || strings.HasSuffix(frame.File, "_test.go") {
+			return filepath.Base(frame.File) + ":" + strconv.Itoa(frame.Line)
+		}
+		if !more {
+			return ""
+		}
+	}
+}
+
+func (s *WriterSink) writeLineEnding(b *bytes.Buffer) {
+	ending := s.LineEnding
+	if ending == "" {
+		ending = "\n"
+	}
+	if s.MaxLineLen > 0 {
+		truncateLine(b, s.MaxLineLen-len(ending))
+	}
+	b.WriteString(ending)
+}
+
+const lineEllipsis = "…"
+
+// truncateLine cuts the line in b down to at most maxLen bytes, ellipsis included, without splitting a UTF-8 rune.
+func truncateLine(b *bytes.Buffer, maxLen int) {
+	if b.Len() <= maxLen {
+		return
+	}
+	line := b.Bytes()
+	cut := maxLen - len(lineEllipsis)
+	if cut < 0 {
+		cut = 0
+	}
+	for cut > 0 && !utf8.RuneStart(line[cut]) {
+		cut--
+	}
+	b.Truncate(cut)
+	b.WriteString(lineEllipsis)
+}
+
+// writeLineStart writes the timestamp, the trace field, and Prefix.
+func (s *WriterSink) writeLineStart(b *bytes.Buffer, kvs map[string]string) {
+	s.writeTimestamp(b)
+	if trace, ok := s.traceID(kvs); ok {
+		s.writeField(b, "trace", s.escapedName(trace))
+	}
+	if s.Prefix != "" {
+		if b.Len() > 0 {
+			b.WriteString(s.fieldSeparator())
+		}
+		b.WriteString(s.Prefix)
+	}
+}
+
+// traceID returns the value of TraceKey in kvs (or StaticKvs), if TraceKey is set and there is one.
+func (s *WriterSink) traceID(kvs map[string]string) (string, bool) {
+	if s.TraceKey == "" {
+		return "", false
+	}
+	if v, ok := kvs[s.TraceKey]; ok {
+		return v, true
+	}
+	v, ok := s.StaticKvs[s.TraceKey]
+	return v, ok
+}
+
+func (s *WriterSink) writeTimestamp(b *bytes.Buffer) {
+	if s.NoTimestamp {
+		return
+	}
+	if s.Format == Logfmt {
+		b.WriteString("ts=")
+		writeLogfmtValue(b, s.timestamp())
+		return
+	}
+	// Format into a stack array rather than through timestamp() so the common case doesn't allocate.
+	var arr [64]byte
 	b.WriteRune('[')
-	b.WriteString(timestamp())
-	b.WriteString("]: job:")
-	b.WriteString(job)
-	b.WriteString(" status:")
-	b.WriteString(status.String())
-	b.WriteString(" time:")
-	writeNanoseconds(&b, nanos)
-	writeMapConsistently(&b, kvs)
-	b.WriteRune('\n')
-	s.Writer.Write(b.Bytes())
+	b.Write(s.appendTimestamp(arr[:0]))
+	b.WriteString("]:")
+}
+
+// writeField writes a top-level field like " job:myjob" (or " job=myjob" for Logfmt), preceded by FieldSeparator.
+func (s *WriterSink) writeField(b *bytes.Buffer, key string, value string) {
+	s.writeColoredField(b, key, value, "")
+}
+
+// writeColoredField is like writeField, but wraps the value in the ANSI color code if Color is set.
+// The first field on a line (eg, job when there's no timestamp) has no leading separator.
+func (s *WriterSink) writeColoredField(b *bytes.Buffer, key string, value string, color string) {
+	if b.Len() > 0 {
+		b.WriteString(s.fieldSeparator())
+	}
+	b.WriteString(key)
+	if s.Format == Logfmt {
+		b.WriteRune('=')
+	} else {
+		b.WriteRune(':')
+	}
+
+	colored := s.Color && color != ""
+	if colored {
+		b.WriteString(color)
+	}
+	if s.Format == Logfmt {
+		writeLogfmtValue(b, value)
+	} else {
+		b.WriteString(value)
+	}
+	if colored {
+		b.WriteString(ansiReset)
+	}
 }
 
-func timestamp() string {
-	return time.Now().UTC().Format(time.RFC3339Nano)
+// How many stack frames UnwrapErrors puts in the "stack" kv.
+const unwrapErrorsStackFrames = 3
+
+// writeErrorChain writes the err_chain and err_type fields for inputErr, and returns kvs with a "stack" kv added if inputErr has a stack trace.
+func (s *WriterSink) writeErrorChain(b *bytes.Buffer, inputErr error, kvs map[string]string) map[string]string {
+	var types []string
+	var innermost error
+	var trace *stack.Trace
+	for err := inputErr; err != nil; err = errors.Unwrap(err) {
+		innermost = err
+		switch err := err.(type) {
+		case *UnmutedError:
+			if trace == nil {
+				trace = err.Stack
+			}
+		case *MutedError:
+		default:
+			types = append(types, fmt.Sprintf("%T", err))
+		}
+	}
+	s.writeField(b, "err_chain", strings.Join(types, ">"))
+	s.writeField(b, "err_type", fmt.Sprintf("%T", innermost))
+
+	if trace == nil {
+		return kvs
+	}
+
+	frames := trace.Frames()
+	if len(frames) > unwrapErrorsStackFrames {
+		frames = frames[:unwrapErrorsStackFrames]
+	}
+	stackStrs := make([]string, 0, len(frames))
+	for _, f := range frames {
+		stackStrs = append(stackStrs, fmt.Sprintf("%s:%d %s", filepath.Base(f.File), f.LineNumber, f.Name))
+	}
+
+	return withKv(kvs, "stack", strings.Join(stackStrs, ", "))
 }
 
-func writeMapConsistently(b *bytes.Buffer, kvs map[string]string) {
-	if kvs == nil {
+// nanosKvs returns kvs with a "nanos" kv added if NanosKv is set.
+func (s *WriterSink) nanosKvs(nanos int64, kvs map[string]string) map[string]string {
+	if !s.NanosKv {
+		return kvs
+	}
+	return withKv(kvs, "nanos", strconv.FormatInt(nanos, 10))
+}
+
+// withKv returns a copy of kvs with key set to value. It never modifies kvs.
+func withKv(kvs map[string]string, key, value string) map[string]string {
+	allKvs := make(map[string]string, len(kvs)+1)
+	for k, v := range kvs {
+		allKvs[k] = v
+	}
+	allKvs[key] = value
+	return allKvs
+}
+
+func (s *WriterSink) writeKvs(b *bytes.Buffer, kvs map[string]string) {
+	kvs = s.mergedKvs(kvs)
+	kvs = s.untracedKvs(kvs)
+	kvs = s.unreservedKvs(kvs)
+	kvs = s.includedKvs(kvs)
+	kvs = s.redactedKvs(kvs)
+	kvs = s.truncatedKvs(kvs)
+	if s.KvsRenderer != nil {
+		s.KvsRenderer(b, kvs)
+		return
+	}
+	if s.Format == Logfmt {
+		writeLogfmtKvs(b, kvs, s.PriorityKeys, s.fieldSeparator())
 		return
 	}
-	keys := make([]string, 0, len(kvs))
+	writeMapConsistently(b, kvs, s.PriorityKeys, s.fieldSeparator())
+}
+
+func (s *WriterSink) fieldSeparator() string {
+	if s.FieldSeparator == "" {
+		return " "
+	}
+	return s.FieldSeparator
+}
+
+// IsTerminal reports whether w is a terminal, eg os.Stdout when it isn't redirected. It's useful for deciding whether to set Color.
+func IsTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return fi.Mode()&os.ModeCharDevice != 0
+}
+
+// WithHostname adds the hostname to StaticKvs as "hostname".
+func (s *WriterSink) WithHostname() *WriterSink {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "hostname_errored"
+	}
+	return s.withStaticKv("hostname", host)
+}
+
+// WithPID adds the process id to StaticKvs as "pid".
+func (s *WriterSink) WithPID() *WriterSink {
+	return s.withStaticKv("pid", strconv.Itoa(os.Getpid()))
+}
+
+func (s *WriterSink) withStaticKv(key string, value string) *WriterSink {
+	if s.StaticKvs == nil {
+		s.StaticKvs = make(map[string]string)
+	}
+	s.StaticKvs[key] = value
+	return s
+}
+
+// RedactKeysMatching returns a function for WriterSink.RedactKey that matches keys case-insensitively.
+// Keys starting with "*" match as suffixes, eg "*_token" matches "api_token" and "SESSION_TOKEN".
+func RedactKeysMatching(keys ...string) func(key string) bool {
+	exact := make(map[string]bool)
+	var suffixes []string
+	for _, k := range keys {
+		k = strings.ToLower(k)
+		if strings.HasPrefix(k, "*") {
+			suffixes = append(suffixes, k[1:])
+		} else {
+			exact[k] = true
+		}
+	}
+
+	return func(key string) bool {
+		key = strings.ToLower(key)
+		if exact[key] {
+			return true
+		}
+		for _, suffix := range suffixes {
+			if strings.HasSuffix(key, suffix) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// includedKvs returns just the kvs whose keys are in IncludeKeys, or kvs itself if IncludeKeys is empty. kvs isn't modified.
+func (s *WriterSink) includedKvs(kvs map[string]string) map[string]string {
+	if len(s.IncludeKeys) == 0 || len(kvs) == 0 {
+		return kvs
+	}
+
+	included := make(map[string]string, len(s.IncludeKeys))
+	for _, k := range s.IncludeKeys {
+		if v, ok := kvs[k]; ok {
+			included[k] = v
+		}
+	}
+	return included
+}
+
+// untracedKvs returns kvs without TraceKey, which writeLineStart has already written. kvs isn't modified.
+func (s *WriterSink) untracedKvs(kvs map[string]string) map[string]string {
+	if s.TraceKey == "" {
+		return kvs
+	}
+	if _, ok := kvs[s.TraceKey]; !ok {
+		return kvs
+	}
+
+	untraced := make(map[string]string, len(kvs)-1)
+	for k, v := range kvs {
+		if k != s.TraceKey {
+			untraced[k] = v
+		}
+	}
+	return untraced
+}
+
+// unreservedKvs returns kvs with keys that collide with field names renamed or dropped, as ReservedKeyPolicy says. kvs isn't modified.
+func (s *WriterSink) unreservedKvs(kvs map[string]string) map[string]string {
+	if s.ReservedKeyPolicy == ReservedKeysPassThrough {
+		return kvs
+	}
+
+	collides := false
 	for k := range kvs {
-		keys = append(keys, k)
+		if reservedKeys[k] {
+			collides = true
+			break
+		}
+	}
+	if !collides {
+		return kvs
+	}
+
+	allKvs := make(map[string]string, len(kvs))
+	for k, v := range kvs {
+		if !reservedKeys[k] {
+			allKvs[k] = v
+		}
+	}
+	if s.ReservedKeyPolicy == ReservedKeysRename {
+		for k, v := range kvs {
+			if reservedKeys[k] {
+				allKvs["kv_"+k] = v
+			}
+		}
+	}
+	return allKvs
+}
+
+const redactedValue = "[REDACTED]"
+
+// redactedKvs returns kvs with the values of keys matching RedactKey replaced. kvs isn't modified.
+func (s *WriterSink) redactedKvs(kvs map[string]string) map[string]string {
+	if s.RedactKey == nil {
+		return kvs
+	}
+
+	var allKvs map[string]string
+	for k := range kvs {
+		if !s.RedactKey(k) {
+			continue
+		}
+		if allKvs == nil {
+			allKvs = make(map[string]string, len(kvs))
+			for k, v := range kvs {
+				allKvs[k] = v
+			}
+		}
+		allKvs[k] = redactedValue
+	}
+
+	if allKvs == nil {
+		return kvs
+	}
+	return allKvs
+}
+
+// truncatedKvs returns kvs with values longer than MaxValueLen truncated. kvs isn't modified.
+func (s *WriterSink) truncatedKvs(kvs map[string]string) map[string]string {
+	if s.MaxValueLen <= 0 {
+		return kvs
+	}
+
+	var allKvs map[string]string
+	for k, v := range kvs {
+		if len(v) <= s.MaxValueLen {
+			continue
+		}
+		if allKvs == nil {
+			allKvs = make(map[string]string, len(kvs))
+			for k, v := range kvs {
+				allKvs[k] = v
+			}
+		}
+		allKvs[k] = truncateString(v, s.MaxValueLen)
+	}
+
+	if allKvs == nil {
+		return kvs
+	}
+	return allKvs
+}
+
+// truncateString cuts v down to at most maxLen bytes, without splitting a UTF-8 rune,
+// and appends an ellipsis and v's original length.
+func truncateString(v string, maxLen int) string {
+	cut := maxLen
+	for cut > 0 && !utf8.RuneStart(v[cut]) {
+		cut--
+	}
+	return fmt.Sprintf("%s…(%d bytes)", v[:cut], len(v))
+}
+
+// mergedKvs returns kvs with Version and StaticKvs mixed in. It never modifies either map.
+func (s *WriterSink) mergedKvs(kvs map[string]string) map[string]string {
+	if len(s.StaticKvs) == 0 && s.Version == "" {
+		return kvs
+	}
+	if len(kvs) == 0 && s.Version == "" {
+		return s.StaticKvs
+	}
+
+	allKvs := make(map[string]string, len(s.StaticKvs)+len(kvs)+1)
+	if s.Version != "" {
+		allKvs["version"] = s.Version
+	}
+	for k, v := range s.StaticKvs {
+		allKvs[k] = v
+	}
+	for k, v := range kvs {
+		allKvs[k] = v
+	}
+	return allKvs
+}
+
+func (s *WriterSink) duration(nanos int64) string {
+	if s.DurationFormatter != nil {
+		return s.DurationFormatter(nanos)
+	}
+	prec := -1
+	if s.DurationPrecision > 0 {
+		prec = s.DurationPrecision
+	}
+	switch s.TimingUnit {
+	case Nanos:
+		return strconv.FormatInt(nanos, 10) + " ns"
+	case Micros:
+		return formatInUnit(nanos, int64(time.Microsecond), "μs", prec)
+	case Millis:
+		return formatInUnit(nanos, int64(time.Millisecond), "ms", prec)
+	case Seconds:
+		return formatInUnit(nanos, int64(time.Second), "s", prec)
 	}
-	sort.Strings(keys)
+	if prec > 0 {
+		return formatNanosecondsPrecision(nanos, prec)
+	}
+	return formatNanoseconds(nanos)
+}
+
+func (s *WriterSink) timestamp() string {
+	return string(s.appendTimestamp(nil))
+}
+
+// appendTimestamp appends the current time, formatted per TimeFormat and Location, to dst.
+func (s *WriterSink) appendTimestamp(dst []byte) []byte {
+	layout := s.TimeFormat
+	if layout == "" {
+		layout = time.RFC3339Nano
+	}
+	loc := s.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	clock := s.Clock
+	if clock == nil {
+		clock = time.Now
+	}
+	return clock().In(loc).AppendFormat(dst, layout)
+}
+
+// writeMapConsistently writes kvs as " kvs:[key:value key:value]" ordered by sortedKeys, with sep in place of the leading space.
+// Values that contain a space, ':', ']', '"', or a non-printable character (eg, a newline) are written
+// Go-quoted (as with strconv.Quote) so that the block stays unambiguous. Other values are written as-is.
+func writeMapConsistently(b *bytes.Buffer, kvs map[string]string, priorityKeys []string, sep string) {
+	if len(kvs) == 0 {
+		return
+	}
+	var arr [maxStackKeys]string
+	keys := appendSortedKeys(arr[:0], kvs, priorityKeys)
 	keysLenMinusOne := len(keys) - 1
 
-	b.WriteString(" kvs:[")
+	b.WriteString(sep)
+	b.WriteString("kvs:[")
 	for i, k := range keys {
 		b.WriteString(k)
 		b.WriteRune(':')
-		b.WriteString(kvs[k])
+		writeBracketedValue(b, kvs[k])
 
 		if i != keysLenMinusOne {
 			b.WriteRune(' ')
@@ -107,13 +1007,145 @@ func writeMapConsistently(b *bytes.Buffer, kvs map[string]string) {
 	b.WriteRune(']')
 }
 
-func writeNanoseconds(b *bytes.Buffer, nanos int64) {
+// maxStackKeys is how many keys the kvs writers can sort without allocating. Maps with more keys are sorted on the heap.
+const maxStackKeys = 8
+
+// sortedKeys returns the keys of kvs: first those in priorityKeys, in that order, and then the rest sorted.
+func sortedKeys(kvs map[string]string, priorityKeys []string) []string {
+	return appendSortedKeys(make([]string, 0, len(kvs)), kvs, priorityKeys)
+}
+
+// appendSortedKeys is like sortedKeys, but appends the keys to keys. Pass a slice of a stack array to avoid allocating.
+func appendSortedKeys(keys []string, kvs map[string]string, priorityKeys []string) []string {
+	if len(kvs) == 1 {
+		for k := range kvs {
+			keys = append(keys, k)
+		}
+		return keys
+	}
+
+	start := len(keys)
+	for _, k := range priorityKeys {
+		if _, ok := kvs[k]; ok {
+			keys = append(keys, k)
+		}
+	}
+	numPriority := len(keys) - start
+
+	for k := range kvs {
+		if numPriority > 0 && isPriorityKey(k, priorityKeys) {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys[start+numPriority:])
+	return keys
+}
+
+func isPriorityKey(k string, priorityKeys []string) bool {
+	for _, pk := range priorityKeys {
+		if k == pk {
+			return true
+		}
+	}
+	return false
+}
+
+// escapedName returns a job or event name that's safe to write as a bracketed field.
+// Names with whitespace, quotes, or non-printable characters are Go-quoted, so that a name like "x event:y" or one with a newline
+// can't forge fields or lines. Logfmt values are already quoted as needed, so names are returned as-is for Logfmt.
+func (s *WriterSink) escapedName(name string) string {
+	if s.Format == Logfmt {
+		return name
+	}
+	for _, r := range name {
+		if unicode.IsSpace(r) || r == '"' || r == utf8.RuneError || !unicode.IsPrint(r) {
+			return strconv.Quote(name)
+		}
+	}
+	return name
+}
+
+func writeBracketedValue(b *bytes.Buffer, v string) {
+	for _, r := range v {
+		if r == ' ' || r == ':' || r == ']' || r == '"' || r == utf8.RuneError || !unicode.IsPrint(r) {
+			b.WriteString(strconv.Quote(v))
+			return
+		}
+	}
+	b.WriteString(v)
+}
+
+// FormatDuration renders nanos as a time.Duration, eg "2m3.5s", "1.204ms", or "32ns".
+// Unlike the default rendering it keeps full precision and uses combined units for long durations. Use it as a WriterSink's DurationFormatter.
+func FormatDuration(nanos int64) string {
+	return time.Duration(nanos).String()
+}
+
+// formatInUnit renders nanos as a decimal number of units (each unitNanos long) followed by suffix, eg "34.56789 ms".
+func formatInUnit(nanos int64, unitNanos int64, suffix string, prec int) string {
+	return strconv.FormatFloat(float64(nanos)/float64(unitNanos), 'f', prec, 64) + " " + suffix
+}
+
+// formatNanosecondsPrecision is formatNanoseconds with prec decimal places, switching units as soon as a duration reaches one of them.
+func formatNanosecondsPrecision(nanos int64, prec int) string {
+	switch {
+	case nanos >= int64(time.Millisecond) || nanos <= -int64(time.Millisecond):
+		return formatInUnit(nanos, int64(time.Millisecond), "ms", prec)
+	case nanos >= int64(time.Microsecond) || nanos <= -int64(time.Microsecond):
+		return formatInUnit(nanos, int64(time.Microsecond), "μs", prec)
+	default:
+		return strconv.FormatInt(nanos, 10) + " ns"
+	}
+}
+
+func formatNanoseconds(nanos int64) string {
 	switch {
 	case nanos > 2000000:
-		fmt.Fprintf(b, "%d ms", nanos/1000000)
+		return fmt.Sprintf("%d ms", nanos/1000000)
 	case nanos > 2000:
-		fmt.Fprintf(b, "%d μs", nanos/1000)
+		return fmt.Sprintf("%d μs", nanos/1000)
 	default:
-		fmt.Fprintf(b, "%d ns", nanos)
+		return fmt.Sprintf("%d ns", nanos)
+	}
+}
+
+// writeLogfmtKvs writes kvs as " key=value" pairs ordered by sortedKeys, with sep in place of the leading spaces. Keys go through logfmtKey.
+func writeLogfmtKvs(b *bytes.Buffer, kvs map[string]string, priorityKeys []string, sep string) {
+	var arr [maxStackKeys]string
+	keys := appendSortedKeys(arr[:0], kvs, priorityKeys)
+
+	for _, k := range keys {
+		b.WriteString(sep)
+		b.WriteString(logfmtKey(k))
+		b.WriteRune('=')
+		writeLogfmtValue(b, kvs[k])
+	}
+}
+
+// logfmtKey replaces characters that can't appear in a logfmt key (spaces, '=', '"', and control characters) with '_'.
+// logfmt has no way to quote keys, so this is applied consistently to every key.
+func logfmtKey(k string) string {
+	return strings.Map(func(r rune) rune {
+		if r <= ' ' || r == '=' || r == '"' || r == utf8.RuneError || !unicode.IsPrint(r) {
+			return '_'
+		}
+		return r
+	}, k)
+}
+
+// writeLogfmtValue writes v as-is, or as a Go-quoted string if it's empty or contains spaces, '=', '"', or non-printable characters.
+func writeLogfmtValue(b *bytes.Buffer, v string) {
+	needsQuote := v == ""
+	for _, r := range v {
+		if r <= ' ' || r == '=' || r == '"' || r == utf8.RuneError || !unicode.IsPrint(r) {
+			needsQuote = true
+			break
+		}
+	}
+	if needsQuote {
+		b.WriteString(strconv.Quote(v))
+	} else {
+		b.WriteString(v)
 	}
 }
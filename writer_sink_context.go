@@ -0,0 +1,56 @@
+package health
+
+import (
+	"context"
+	"fmt"
+)
+
+func (s *WriterSink) EmitEventContext(ctx context.Context, job string, event string, kvs map[string]string) {
+	s.EmitEvent(job, event, s.contextKvs(ctx, kvs))
+}
+
+func (s *WriterSink) EmitEventErrContext(ctx context.Context, job string, event string, inputErr error, kvs map[string]string) {
+	s.EmitEventErr(job, event, inputErr, s.contextKvs(ctx, kvs))
+}
+
+func (s *WriterSink) EmitTimingContext(ctx context.Context, job string, event string, nanos int64, kvs map[string]string) {
+	s.EmitTiming(job, event, nanos, s.contextKvs(ctx, kvs))
+}
+
+func (s *WriterSink) EmitCompleteContext(ctx context.Context, job string, status CompletionStatus, nanos int64, kvs map[string]string) {
+	s.EmitComplete(job, status, nanos, s.contextKvs(ctx, kvs))
+}
+
+func (s *WriterSink) EmitGaugeContext(ctx context.Context, job string, event string, value float64, kvs map[string]string) {
+	s.EmitGauge(job, event, value, s.contextKvs(ctx, kvs))
+}
+
+func (s *WriterSink) EmitCountContext(ctx context.Context, job string, event string, delta int64, kvs map[string]string) {
+	s.EmitCount(job, event, delta, s.contextKvs(ctx, kvs))
+}
+
+// contextKvs returns kvs plus the values in ctx for each of ContextKeys. kvs isn't modified.
+func (s *WriterSink) contextKvs(ctx context.Context, kvs map[string]string) map[string]string {
+	var allKvs map[string]string
+	for ctxKey, kvsKey := range s.ContextKeys {
+		v := ctx.Value(ctxKey)
+		if v == nil {
+			continue
+		}
+		if _, ok := kvs[kvsKey]; ok {
+			continue
+		}
+		if allKvs == nil {
+			allKvs = make(map[string]string, len(kvs)+len(s.ContextKeys))
+			for k, v := range kvs {
+				allKvs[k] = v
+			}
+		}
+		allKvs[kvsKey] = fmt.Sprint(v)
+	}
+
+	if allKvs == nil {
+		return kvs
+	}
+	return allKvs
+}
@@ -0,0 +1,59 @@
+package health
+
+import (
+	"bytes"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type testContextKey string
+
+func TestWriterSinkContext(t *testing.T) {
+	var b bytes.Buffer
+	sink := WriterSink{Writer: &b, ContextKeys: map[interface{}]string{
+		testContextKey("request_id"): "request_id",
+		testContextKey("trace_id"):   "trace_id",
+		testContextKey("user_id"):    "user_id",
+	}}
+
+	ctx := context.WithValue(context.Background(), testContextKey("request_id"), "abc")
+	ctx = context.WithValue(ctx, testContextKey("trace_id"), 123)
+
+	kvs := map[string]string{"wat": "ok", "trace_id": "explicit"}
+	sink.EmitEventContext(ctx, "myjob", "myevent", kvs)
+
+	result := kvsEventRegexp.FindStringSubmatch(b.String())
+	assert.Equal(t, 4, len(result))
+	assert.Equal(t, "request_id:abc trace_id:explicit wat:ok", result[3])
+	assert.Equal(t, map[string]string{"wat": "ok", "trace_id": "explicit"}, kvs)
+
+	b.Reset()
+	sink.EmitTimingContext(ctx, "myjob", "myevent", 34567890, nil)
+	result = kvsTimingRegexp.FindStringSubmatch(b.String())
+	assert.Equal(t, 5, len(result))
+	assert.Equal(t, "request_id:abc trace_id:123", result[4])
+
+	b.Reset()
+	sink.EmitEventErrContext(ctx, "myjob", "myevent", testErr, nil)
+	assert.Contains(t, b.String(), "kvs:[request_id:abc trace_id:123]")
+
+	b.Reset()
+	sink.EmitCompleteContext(ctx, "myjob", Success, 34567890, nil)
+	assert.Contains(t, b.String(), "kvs:[request_id:abc trace_id:123]")
+
+	b.Reset()
+	sink.EmitGaugeContext(ctx, "myjob", "myevent", 1, nil)
+	assert.Contains(t, b.String(), "kvs:[request_id:abc trace_id:123]")
+
+	b.Reset()
+	sink.EmitCountContext(ctx, "myjob", "myevent", 1, nil)
+	assert.Contains(t, b.String(), "kvs:[request_id:abc trace_id:123]")
+
+	// Nothing in the context means nothing is added:
+	b.Reset()
+	sink.EmitEventContext(context.Background(), "myjob", "myevent", nil)
+	result = basicEventRegexp.FindStringSubmatch(b.String())
+	assert.Equal(t, "myevent", result[2])
+	assert.NotContains(t, b.String(), "kvs:")
+}
@@ -1,11 +1,21 @@
 package health
 
 import (
+	"bufio"
 	"bytes"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
 	"regexp"
+	"runtime"
+	"strings"
+	"sync"
 	"testing"
+	"time"
+	"unicode/utf8"
 )
 
 var basicEventRegexp = regexp.MustCompile("\\[[^\\]]+\\]: job:(.+) event:(.+)")
@@ -16,13 +26,18 @@ var basicTimingRegexp = regexp.MustCompile("\\[[^\\]]+\\]: job:(.+) event:(.+) t
 var kvsTimingRegexp = regexp.MustCompile("\\[[^\\]]+\\]: job:(.+) event:(.+) time:(.+) kvs:\\[(.+)\\]")
 var basicCompletionRegexp = regexp.MustCompile("\\[[^\\]]+\\]: job:(.+) status:(.+) time:(.+)")
 var kvsCompletionRegexp = regexp.MustCompile("\\[[^\\]]+\\]: job:(.+) status:(.+) time:(.+) kvs:\\[(.+)\\]")
+var basicGaugeRegexp = regexp.MustCompile("\\[[^\\]]+\\]: job:(.+) event:(.+) gauge:(.+)")
+var kvsGaugeRegexp = regexp.MustCompile("\\[[^\\]]+\\]: job:(.+) event:(.+) gauge:(.+) kvs:\\[(.+)\\]")
+var basicCountRegexp = regexp.MustCompile("\\[[^\\]]+\\]: job:(.+) event:(.+) count:(.+)")
+var kvsCountRegexp = regexp.MustCompile("\\[[^\\]]+\\]: job:(.+) event:(.+) count:(.+) kvs:\\[(.+)\\]")
 
 var testErr = errors.New("my test error")
 
 func BenchmarkWriterSinkEmitEvent(b *testing.B) {
 	var by bytes.Buffer
 	someKvs := map[string]string{"foo": "bar", "qux": "dog"}
-	sink := WriterSink{&by}
+	sink := WriterSink{Writer: &by}
+	b.ReportAllocs()
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
 		by.Reset()
@@ -33,7 +48,8 @@ func BenchmarkWriterSinkEmitEvent(b *testing.B) {
 func BenchmarkWriterSinkEmitEventErr(b *testing.B) {
 	var by bytes.Buffer
 	someKvs := map[string]string{"foo": "bar", "qux": "dog"}
-	sink := WriterSink{&by}
+	sink := WriterSink{Writer: &by}
+	b.ReportAllocs()
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
 		by.Reset()
@@ -44,7 +60,8 @@ func BenchmarkWriterSinkEmitEventErr(b *testing.B) {
 func BenchmarkWriterSinkEmitTiming(b *testing.B) {
 	var by bytes.Buffer
 	someKvs := map[string]string{"foo": "bar", "qux": "dog"}
-	sink := WriterSink{&by}
+	sink := WriterSink{Writer: &by}
+	b.ReportAllocs()
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
 		by.Reset()
@@ -55,7 +72,8 @@ func BenchmarkWriterSinkEmitTiming(b *testing.B) {
 func BenchmarkWriterSinkEmitComplete(b *testing.B) {
 	var by bytes.Buffer
 	someKvs := map[string]string{"foo": "bar", "qux": "dog"}
-	sink := WriterSink{&by}
+	sink := WriterSink{Writer: &by}
+	b.ReportAllocs()
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
 		by.Reset()
@@ -63,9 +81,21 @@ func BenchmarkWriterSinkEmitComplete(b *testing.B) {
 	}
 }
 
+func BenchmarkWriterSinkEmitGauge(b *testing.B) {
+	var by bytes.Buffer
+	someKvs := map[string]string{"foo": "bar", "qux": "dog"}
+	sink := WriterSink{Writer: &by}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		by.Reset()
+		sink.EmitGauge("myjob", "myevent", 3.14, someKvs)
+	}
+}
+
 func TestWriterSinkEmitEventBasic(t *testing.T) {
 	var b bytes.Buffer
-	sink := WriterSink{&b}
+	sink := WriterSink{Writer: &b}
 	sink.EmitEvent("myjob", "myevent", nil)
 
 	str := b.String()
@@ -78,7 +108,7 @@ func TestWriterSinkEmitEventBasic(t *testing.T) {
 
 func TestWriterSinkEmitEventKvs(t *testing.T) {
 	var b bytes.Buffer
-	sink := WriterSink{&b}
+	sink := WriterSink{Writer: &b}
 	sink.EmitEvent("myjob", "myevent", map[string]string{"wat": "ok", "another": "thing"})
 
 	str := b.String()
@@ -90,9 +120,70 @@ func TestWriterSinkEmitEventKvs(t *testing.T) {
 	assert.Equal(t, "another:thing wat:ok", result[3])
 }
 
+func TestWriterSinkEmptyKvs(t *testing.T) {
+	var b bytes.Buffer
+	sink := WriterSink{Writer: &b}
+	empty := map[string]string{}
+
+	sink.EmitEvent("myjob", "myevent", empty)
+	sink.EmitEventErr("myjob", "myevent", testErr, empty)
+	sink.EmitTiming("myjob", "myevent", 34567890, empty)
+	sink.EmitComplete("myjob", Success, 34567890, empty)
+	sink.EmitGauge("myjob", "myevent", 3.14, empty)
+
+	assert.NotContains(t, b.String(), "kvs:")
+	assert.Equal(t, 5, strings.Count(b.String(), "\n"))
+	for _, line := range strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n") {
+		assert.False(t, strings.HasSuffix(line, " "), line)
+	}
+}
+
+func TestWriterSinkEscapesNames(t *testing.T) {
+	var b bytes.Buffer
+	sink := WriterSink{Writer: &b, NoTimestamp: true}
+
+	sink.EmitEvent("evil\n[2016-01-02T15:04:05Z]: job:forged", "x event:forged", nil)
+	sink.EmitEventErr("myjob\r", "myevent", testErr, nil)
+	sink.EmitTiming("my job", "my.event", 34567890, nil)
+	sink.EmitComplete("evil\njob", Success, 34567890, nil)
+	sink.EmitGauge("myjob", "say \"hi\"", 3.14, nil)
+	sink.EmitCount("myjob", "tab\tbed", 1, nil)
+
+	assert.Equal(t, `job:"evil\n[2016-01-02T15:04:05Z]: job:forged" event:"x event:forged"`+"\n"+
+		`job:"myjob\r" event:myevent err:my test error`+"\n"+
+		`job:"my job" event:my.event time:34 ms`+"\n"+
+		`job:"evil\njob" status:success time:34 ms`+"\n"+
+		`job:myjob event:"say \"hi\"" gauge:3.14`+"\n"+
+		`job:myjob event:"tab\tbed" count:1`+"\n", b.String())
+
+	// Plain names, including ones with dots and colons, are left alone.
+	b.Reset()
+	sink.EmitEvent("api:/users", "cache.miss", nil)
+	assert.Equal(t, "job:api:/users event:cache.miss\n", b.String())
+}
+
+func TestWriterSinkEmitEventKvsEscaping(t *testing.T) {
+	var b bytes.Buffer
+	sink := WriterSink{Writer: &b}
+	sink.EmitEvent("myjob", "myevent", map[string]string{
+		"path":    "/tmp/my file",
+		"addr":    "127.0.0.1:80",
+		"bracket": "a]b",
+		"quote":   `say "hi"`,
+		"newline": "a\nb",
+		"plain":   "ok",
+	})
+
+	str := b.String()
+
+	result := kvsEventRegexp.FindStringSubmatch(str)
+	assert.Equal(t, 4, len(result))
+	assert.Equal(t, `addr:"127.0.0.1:80" bracket:"a]b" newline:"a\nb" path:"/tmp/my file" plain:ok quote:"say \"hi\""`, result[3])
+}
+
 func TestWriterSinkEmitEventErrBasic(t *testing.T) {
 	var b bytes.Buffer
-	sink := WriterSink{&b}
+	sink := WriterSink{Writer: &b}
 	sink.EmitEventErr("myjob", "myevent", testErr, nil)
 
 	str := b.String()
@@ -104,9 +195,16 @@ func TestWriterSinkEmitEventErrBasic(t *testing.T) {
 	assert.Equal(t, testErr.Error(), result[3])
 }
 
+func TestWriterSinkEmitEventErrNil(t *testing.T) {
+	var b bytes.Buffer
+	sink := WriterSink{Writer: &b, NoTimestamp: true, UnwrapErrors: true}
+	sink.EmitEventErr("myjob", "myevent", nil, nil)
+	assert.Equal(t, "job:myjob event:myevent err:<nil> err_chain: err_type:<nil>\n", b.String())
+}
+
 func TestWriterSinkEmitEventErrKvs(t *testing.T) {
 	var b bytes.Buffer
-	sink := WriterSink{&b}
+	sink := WriterSink{Writer: &b}
 	sink.EmitEventErr("myjob", "myevent", testErr, map[string]string{"wat": "ok", "another": "thing"})
 
 	str := b.String()
@@ -119,9 +217,35 @@ func TestWriterSinkEmitEventErrKvs(t *testing.T) {
 	assert.Equal(t, "another:thing wat:ok", result[4])
 }
 
+func TestWriterSinkEmitEventErrUnwrap(t *testing.T) {
+	var b bytes.Buffer
+	sink := WriterSink{Writer: &b, UnwrapErrors: true}
+
+	_, pathErr := os.Open("/does/not/exist")
+	wrapped := fmt.Errorf("loading config: %w", pathErr)
+	sink.EmitEventErr("myjob", "myevent", wrapped, nil)
+
+	str := b.String()
+	assert.Contains(t, str, " err:loading config: open /does/not/exist: no such file or directory err_chain:*fmt.wrapError>*fs.PathError>syscall.Errno err_type:syscall.Errno\n")
+
+	// Errors that went through a Job have a stack, which shows up in kvs:
+	b.Reset()
+	stream := NewStream().AddSink(&sink)
+	stream.EventErrKv("myevent", errors.New("root"), map[string]string{"wat": "ok"})
+
+	str = b.String()
+	assert.Regexp(t, ` err:root err_chain:\*errors\.errorString err_type:\*errors\.errorString kvs:\[stack:"[^",]+:\d+ [^",]+, [^",]+:\d+ [^",]+, [^",]+:\d+ [^",]+" wat:ok\]\n$`, str)
+
+	// Without UnwrapErrors the line is unchanged:
+	b.Reset()
+	sink.UnwrapErrors = false
+	sink.EmitEventErr("myjob", "myevent", wrapped, nil)
+	assert.NotContains(t, b.String(), "err_chain")
+}
+
 func TestWriterSinkEmitTimingBasic(t *testing.T) {
 	var b bytes.Buffer
-	sink := WriterSink{&b}
+	sink := WriterSink{Writer: &b}
 	sink.EmitTiming("myjob", "myevent", 1204000, nil)
 
 	str := b.String()
@@ -135,7 +259,7 @@ func TestWriterSinkEmitTimingBasic(t *testing.T) {
 
 func TestWriterSinkEmitTimingKvs(t *testing.T) {
 	var b bytes.Buffer
-	sink := WriterSink{&b}
+	sink := WriterSink{Writer: &b}
 	sink.EmitTiming("myjob", "myevent", 34567890, map[string]string{"wat": "ok", "another": "thing"})
 
 	str := b.String()
@@ -151,7 +275,7 @@ func TestWriterSinkEmitTimingKvs(t *testing.T) {
 func TestWriterSinkEmitCompleteBasic(t *testing.T) {
 	for kind, kindStr := range completionStatusToString {
 		var b bytes.Buffer
-		sink := WriterSink{&b}
+		sink := WriterSink{Writer: &b}
 		sink.EmitComplete("myjob", kind, 1204000, nil)
 
 		str := b.String()
@@ -167,7 +291,7 @@ func TestWriterSinkEmitCompleteBasic(t *testing.T) {
 func TestWriterSinkEmitCompleteKvs(t *testing.T) {
 	for kind, kindStr := range completionStatusToString {
 		var b bytes.Buffer
-		sink := WriterSink{&b}
+		sink := WriterSink{Writer: &b}
 		sink.EmitComplete("myjob", kind, 34567890, map[string]string{"wat": "ok", "another": "thing"})
 
 		str := b.String()
@@ -180,3 +304,889 @@ func TestWriterSinkEmitCompleteKvs(t *testing.T) {
 		assert.Equal(t, "another:thing wat:ok", result[4])
 	}
 }
+
+func TestWriterSinkEmitCompleteN(t *testing.T) {
+	var b bytes.Buffer
+	sink := WriterSink{Writer: &b, NoTimestamp: true}
+	sink.EmitCompleteN("myjob", Error, 34567890, 3, map[string]string{"wat": "ok"})
+	assert.Equal(t, "job:myjob status:error time:34 ms attempts:3 kvs:[wat:ok]\n", b.String())
+
+	b.Reset()
+	sink.Format = Logfmt
+	sink.EmitCompleteN("myjob", Success, 1204, 1, nil)
+	assert.Equal(t, "job=myjob status=success time=\"1204 ns\" attempts=1\n", b.String())
+}
+
+func TestWriterSinkEmitGaugeBasic(t *testing.T) {
+	var b bytes.Buffer
+	sink := WriterSink{Writer: &b}
+	sink.EmitGauge("myjob", "myevent", 3.14, nil)
+
+	str := b.String()
+
+	result := basicGaugeRegexp.FindStringSubmatch(str)
+	assert.Equal(t, 4, len(result))
+	assert.Equal(t, "myjob", result[1])
+	assert.Equal(t, "myevent", result[2])
+	assert.Equal(t, "3.14", result[3])
+}
+
+func TestWriterSinkEmitGaugeKvs(t *testing.T) {
+	var b bytes.Buffer
+	sink := WriterSink{Writer: &b}
+	sink.EmitGauge("myjob", "myevent", 1024, map[string]string{"wat": "ok", "another": "thing"})
+
+	str := b.String()
+
+	result := kvsGaugeRegexp.FindStringSubmatch(str)
+	assert.Equal(t, 5, len(result))
+	assert.Equal(t, "myjob", result[1])
+	assert.Equal(t, "myevent", result[2])
+	assert.Equal(t, "1024", result[3])
+	assert.Equal(t, "another:thing wat:ok", result[4])
+}
+
+func TestWriterSinkEmitCountBasic(t *testing.T) {
+	var b bytes.Buffer
+	sink := WriterSink{Writer: &b}
+	sink.EmitCount("myjob", "cache.miss", 3, nil)
+
+	str := b.String()
+
+	result := basicCountRegexp.FindStringSubmatch(str)
+	assert.Equal(t, 4, len(result))
+	assert.Equal(t, "myjob", result[1])
+	assert.Equal(t, "cache.miss", result[2])
+	assert.Equal(t, "3", result[3])
+}
+
+func TestWriterSinkEmitCountKvs(t *testing.T) {
+	var b bytes.Buffer
+	sink := WriterSink{Writer: &b}
+	sink.EmitCount("myjob", "cache.miss", -2, map[string]string{"wat": "ok", "another": "thing"})
+
+	str := b.String()
+
+	result := kvsCountRegexp.FindStringSubmatch(str)
+	assert.Equal(t, 5, len(result))
+	assert.Equal(t, "myjob", result[1])
+	assert.Equal(t, "cache.miss", result[2])
+	assert.Equal(t, "-2", result[3])
+	assert.Equal(t, "another:thing wat:ok", result[4])
+}
+
+type erroringWriter struct {
+	err error
+}
+
+func (w erroringWriter) Write(p []byte) (int, error) {
+	return 0, w.err
+}
+
+func TestWriterSinkErrorHandler(t *testing.T) {
+	var gotErrs []error
+	sink := WriterSink{Writer: erroringWriter{testErr}, ErrorHandler: func(err error) {
+		gotErrs = append(gotErrs, err)
+	}}
+	sink.EmitEvent("myjob", "myevent", nil)
+	sink.EmitEventErr("myjob", "myevent", testErr, nil)
+	sink.EmitTiming("myjob", "myevent", 1204000, nil)
+	sink.EmitComplete("myjob", Success, 1204000, nil)
+	sink.EmitGauge("myjob", "myevent", 1, nil)
+
+	assert.Equal(t, []error{testErr, testErr, testErr, testErr, testErr}, gotErrs)
+
+	// Without a handler, write errors are ignored.
+	sink.ErrorHandler = nil
+	sink.EmitEvent("myjob", "myevent", nil)
+}
+
+func TestWriterSinkKvsOrder(t *testing.T) {
+	var b bytes.Buffer
+	sink := WriterSink{Writer: &b, NoTimestamp: true, PriorityKeys: []string{"level"}}
+
+	sink.EmitEvent("myjob", "myevent", map[string]string{"level": "info"})
+	assert.Equal(t, "job:myjob event:myevent kvs:[level:info]\n", b.String())
+
+	// More keys than fit on the stack.
+	kvs := map[string]string{}
+	for _, k := range []string{"j", "b", "i", "level", "c", "h", "d", "g", "e", "f", "a"} {
+		kvs[k] = "1"
+	}
+	b.Reset()
+	sink.EmitEvent("myjob", "myevent", kvs)
+	assert.Equal(t, "job:myjob event:myevent kvs:[level:1 a:1 b:1 c:1 d:1 e:1 f:1 g:1 h:1 i:1 j:1]\n", b.String())
+
+	b.Reset()
+	sink.Format = Logfmt
+	sink.EmitEvent("myjob", "myevent", kvs)
+	assert.Equal(t, "job=myjob event=myevent level=1 a=1 b=1 c=1 d=1 e=1 f=1 g=1 h=1 i=1 j=1\n", b.String())
+}
+
+func TestWriterSinkNameInErrors(t *testing.T) {
+	var b bytes.Buffer
+	var gotErr error
+	sink := WriterSink{Writer: erroringWriter{testErr}, Name: "file", ErrorHandler: func(err error) {
+		gotErr = err
+	}}
+	sink.EmitEvent("myjob", "myevent", nil)
+
+	assert.Equal(t, "file: my test error", gotErr.Error())
+	assert.True(t, errors.Is(gotErr, testErr))
+
+	// The name doesn't show up in output.
+	sink.Writer = &b
+	sink.EmitEvent("myjob", "myevent", nil)
+	assert.NotContains(t, b.String(), "file")
+}
+
+func TestWriterSinkPriorityKeys(t *testing.T) {
+	var b bytes.Buffer
+	sink := WriterSink{Writer: &b, PriorityKeys: []string{"level", "missing", "request_id"}}
+	sink.EmitEvent("myjob", "myevent", map[string]string{"wat": "ok", "another": "thing", "request_id": "abc", "level": "info"})
+
+	result := kvsEventRegexp.FindStringSubmatch(b.String())
+	assert.Equal(t, 4, len(result))
+	assert.Equal(t, "level:info request_id:abc another:thing wat:ok", result[3])
+
+	b.Reset()
+	sink.Format = Logfmt
+	sink.EmitEvent("myjob", "myevent", map[string]string{"wat": "ok", "level": "info"})
+	assert.Contains(t, b.String(), " event=myevent level=info wat=ok\n")
+}
+
+func TestWriterSinkRedactKey(t *testing.T) {
+	var b bytes.Buffer
+	sink := WriterSink{Writer: &b, RedactKey: RedactKeysMatching("Password", "*_token")}
+	kvs := map[string]string{"password": "hunter2", "API_TOKEN": "abc", "token_count": "3", "user": "bob"}
+	sink.EmitEvent("myjob", "myevent", kvs)
+
+	result := kvsEventRegexp.FindStringSubmatch(b.String())
+	assert.Equal(t, 4, len(result))
+	// [REDACTED] has a "]" in it, so it is quoted like any other such value.
+	assert.Equal(t, `API_TOKEN:"[REDACTED]" password:"[REDACTED]" token_count:3 user:bob`, result[3])
+
+	// The caller's map is untouched:
+	assert.Equal(t, "hunter2", kvs["password"])
+	assert.Equal(t, "abc", kvs["API_TOKEN"])
+}
+
+func TestWriterSinkIncludeKeys(t *testing.T) {
+	var b bytes.Buffer
+	sink := WriterSink{Writer: &b, IncludeKeys: []string{"user", "path", "missing"}, StaticKvs: map[string]string{"hostname": "web1"}}
+	sink.EmitEvent("myjob", "myevent", map[string]string{"user": "bob", "path": "/x", "trace": "abc", "internal": "1"})
+
+	result := kvsEventRegexp.FindStringSubmatch(b.String())
+	assert.Equal(t, 4, len(result))
+	assert.Equal(t, "path:/x user:bob", result[3])
+
+	// With none of the keys, there's no kvs block.
+	b.Reset()
+	sink.EmitEvent("myjob", "myevent", map[string]string{"trace": "abc"})
+	assert.Regexp(t, basicEventRegexp, b.String())
+	assert.NotContains(t, b.String(), "kvs:")
+}
+
+func TestRedactKeysMatching(t *testing.T) {
+	redact := RedactKeysMatching("secret", "*_TOKEN")
+	assert.True(t, redact("secret"))
+	assert.True(t, redact("SECRET"))
+	assert.True(t, redact("session_token"))
+	assert.False(t, redact("secrets"))
+	assert.False(t, redact("tokens"))
+	assert.False(t, redact("token"))
+}
+
+func TestWriterSinkMaxValueLen(t *testing.T) {
+	var b bytes.Buffer
+	sink := WriterSink{Writer: &b, MaxValueLen: 5}
+	kvs := map[string]string{"body": strings.Repeat("x", 4096), "short": "abcde", "a_really_long_key": "ok", "utf8": "abcdé"}
+	sink.EmitEvent("myjob", "myevent", kvs)
+
+	result := kvsEventRegexp.FindStringSubmatch(b.String())
+	assert.Equal(t, 4, len(result))
+	assert.Equal(t, `a_really_long_key:ok body:"xxxxx…(4096 bytes)" short:abcde utf8:"abcd…(6 bytes)"`, result[3])
+	assert.Equal(t, 4096, len(kvs["body"]))
+}
+
+func TestWriterSinkMaxLineLen(t *testing.T) {
+	var b bytes.Buffer
+	sink := WriterSink{Writer: &b, NoTimestamp: true, MaxLineLen: 64}
+
+	sink.EmitEvent("myjob", "myevent", map[string]string{"body": "short"})
+	assert.Equal(t, "job:myjob event:myevent kvs:[body:short]\n", b.String())
+
+	// Each é is two bytes, and the cut lands in the middle of one.
+	for _, ending := range []string{"", "\r\n"} {
+		b.Reset()
+		sink.LineEnding = ending
+		sink.EmitEvent("myjob", "myevent", map[string]string{"body": strings.Repeat("é", 100)})
+
+		line := b.String()
+		assert.True(t, len(line) <= 64, "line is %d bytes", len(line))
+		assert.True(t, utf8.ValidString(line))
+		if ending == "" {
+			ending = "\n"
+		}
+		assert.True(t, strings.HasSuffix(line, "é…"+ending), line)
+		assert.True(t, strings.HasPrefix(line, "job:myjob event:myevent kvs:[body:é"), line)
+	}
+}
+
+func TestWriterSinkStaticKvs(t *testing.T) {
+	var b bytes.Buffer
+	sink := WriterSink{Writer: &b, StaticKvs: map[string]string{"zone": "us-east", "wat": "static"}}
+
+	sink.EmitEvent("myjob", "myevent", nil)
+	result := kvsEventRegexp.FindStringSubmatch(b.String())
+	assert.Equal(t, 4, len(result))
+	assert.Equal(t, "wat:static zone:us-east", result[3])
+
+	b.Reset()
+	kvs := map[string]string{"wat": "ok", "another": "thing"}
+	sink.EmitTiming("myjob", "myevent", 34567890, kvs)
+	result = kvsTimingRegexp.FindStringSubmatch(b.String())
+	assert.Equal(t, 5, len(result))
+	assert.Equal(t, "another:thing wat:ok zone:us-east", result[4])
+
+	// Neither map is modified:
+	assert.Equal(t, map[string]string{"wat": "ok", "another": "thing"}, kvs)
+	assert.Equal(t, map[string]string{"zone": "us-east", "wat": "static"}, sink.StaticKvs)
+}
+
+func TestWriterSinkFieldSeparator(t *testing.T) {
+	var b bytes.Buffer
+	sink := WriterSink{Writer: &b, TimeFormat: "2006", FieldSeparator: "\t", Prefix: "myapp"}
+	sink.Clock = func() time.Time { return time.Date(2016, 1, 2, 15, 4, 5, 0, time.UTC) }
+
+	sink.EmitEvent("myjob", "myevent", map[string]string{"wat": "ok", "another": "thing"})
+	sink.EmitEventErr("myjob", "myevent", testErr, nil)
+	sink.EmitTiming("myjob", "myevent", 1204, nil)
+	sink.EmitComplete("myjob", Success, 1204, nil)
+	sink.EmitGauge("myjob", "myevent", 3.5, nil)
+	sink.EmitCount("myjob", "myevent", 2, nil)
+	assert.Equal(t, "[2016]:\tmyapp\tjob:myjob\tevent:myevent\tkvs:[another:thing wat:ok]\n"+
+		"[2016]:\tmyapp\tjob:myjob\tevent:myevent\terr:my test error\n"+
+		"[2016]:\tmyapp\tjob:myjob\tevent:myevent\ttime:1204 ns\n"+
+		"[2016]:\tmyapp\tjob:myjob\tstatus:success\ttime:1204 ns\n"+
+		"[2016]:\tmyapp\tjob:myjob\tevent:myevent\tgauge:3.5\n"+
+		"[2016]:\tmyapp\tjob:myjob\tevent:myevent\tcount:2\n", b.String())
+
+	b.Reset()
+	sink.Format = Logfmt
+	sink.Prefix = ""
+	sink.EmitEvent("myjob", "myevent", map[string]string{"wat": "ok", "another": "thing"})
+	assert.Equal(t, "ts=2016\tjob=myjob\tevent=myevent\tanother=thing\twat=ok\n", b.String())
+}
+
+func TestWriterSinkKvsRenderer(t *testing.T) {
+	var b bytes.Buffer
+	sink := WriterSink{Writer: &b, NoTimestamp: true, StaticKvs: map[string]string{"host": "a"}, RedactKey: RedactKeysMatching("token")}
+	sink.KvsRenderer = func(b *bytes.Buffer, kvs map[string]string) {
+		if len(kvs) == 0 {
+			return
+		}
+		j, _ := json.Marshal(kvs)
+		b.WriteString(" kvs=")
+		b.Write(j)
+	}
+
+	sink.EmitEvent("myjob", "myevent", map[string]string{"token": "secret"})
+	sink.EmitComplete("myjob", Success, 1204, nil)
+	assert.Equal(t, "job:myjob event:myevent kvs={\"host\":\"a\",\"token\":\"[REDACTED]\"}\n"+
+		"job:myjob status:success time:1204 ns kvs={\"host\":\"a\"}\n", b.String())
+
+	// It's used for Logfmt too, and is called even when there are no kvs.
+	b.Reset()
+	calls := 0
+	sink = WriterSink{Writer: &b, NoTimestamp: true, Format: Logfmt}
+	sink.KvsRenderer = func(b *bytes.Buffer, kvs map[string]string) { calls++ }
+	sink.EmitEvent("myjob", "myevent", nil)
+	sink.EmitEvent("myjob", "myevent", map[string]string{"a": "b"})
+	assert.Equal(t, "job=myjob event=myevent\njob=myjob event=myevent\n", b.String())
+	assert.Equal(t, 2, calls)
+}
+
+func TestWriterSinkEmitGaugeUnit(t *testing.T) {
+	var b bytes.Buffer
+	sink := WriterSink{Writer: &b, NoTimestamp: true}
+
+	sink.EmitGaugeUnit("myjob", "heap", 512, "bytes", nil)
+	sink.EmitGaugeUnit("myjob", "cpu", 87.5, "%", map[string]string{"wat": "ok"})
+	sink.EmitGaugeUnit("myjob", "conns", 12, "", nil)
+	sink.EmitGaugeUnit("myjob", "rate", 3, " req/s", nil)
+	assert.Equal(t, "job:myjob event:heap gauge:512bytes\n"+
+		"job:myjob event:cpu gauge:87.5% kvs:[wat:ok]\n"+
+		"job:myjob event:conns gauge:12\n"+
+		"job:myjob event:rate gauge:\"3 req/s\"\n", b.String())
+
+	b.Reset()
+	sink.Format = Logfmt
+	sink.EmitGaugeUnit("myjob", "heap", 512, "bytes", nil)
+	sink.EmitGaugeUnit("myjob", "rate", 3, " req/s", nil)
+	assert.Equal(t, "job=myjob event=heap gauge=512bytes\njob=myjob event=rate gauge=\"3 req/s\"\n", b.String())
+}
+
+func TestWriterSinkEmitEventKV(t *testing.T) {
+	var b bytes.Buffer
+	sink := WriterSink{Writer: &b, NoTimestamp: true}
+
+	sink.EmitEventKV("myjob", "myevent", map[string]interface{}{"count": 3, "ok": true, "ratio": 0.25, "name": "bob smith"})
+	sink.EmitEventKV("myjob", "myevent", nil)
+	assert.Equal(t, "job:myjob event:myevent kvs:[count:3 name:\"bob smith\" ok:true ratio:0.25]\n"+
+		"job:myjob event:myevent\n", b.String())
+}
+
+func TestWriterSinkReservedKeyPolicy(t *testing.T) {
+	var b bytes.Buffer
+	sink := WriterSink{Writer: &b, NoTimestamp: true}
+	kvs := map[string]string{"job": "other", "time": "noon", "wat": "ok"}
+
+	sink.EmitEvent("myjob", "myevent", kvs)
+	assert.Equal(t, "job:myjob event:myevent kvs:[job:other time:noon wat:ok]\n", b.String())
+
+	b.Reset()
+	sink.ReservedKeyPolicy = ReservedKeysRename
+	sink.EmitEvent("myjob", "myevent", kvs)
+	sink.EmitTiming("myjob", "myevent", 1204, map[string]string{"kv_job": "clobbered", "job": "other"})
+	assert.Equal(t, "job:myjob event:myevent kvs:[kv_job:other kv_time:noon wat:ok]\n"+
+		"job:myjob event:myevent time:1204 ns kvs:[kv_job:other]\n", b.String())
+
+	b.Reset()
+	sink.ReservedKeyPolicy = ReservedKeysDrop
+	sink.Format = Logfmt
+	sink.EmitEvent("myjob", "myevent", kvs)
+	sink.EmitEvent("myjob", "myevent", map[string]string{"job": "other"})
+	assert.Equal(t, "job=myjob event=myevent wat=ok\njob=myjob event=myevent\n", b.String())
+
+	// The caller's map is left alone.
+	assert.Equal(t, map[string]string{"job": "other", "time": "noon", "wat": "ok"}, kvs)
+}
+
+// closingBuffer is a buffered io.WriteCloser that records what happened to it.
+type closingBuffer struct {
+	bytes.Buffer
+	pending  []byte
+	flushes  int
+	closes   int
+	closeErr error
+}
+
+func (w *closingBuffer) Write(p []byte) (int, error) {
+	w.pending = append(w.pending, p...)
+	return len(p), nil
+}
+
+func (w *closingBuffer) Flush() error {
+	w.flushes++
+	w.Buffer.Write(w.pending)
+	w.pending = nil
+	return nil
+}
+
+func (w *closingBuffer) Close() error {
+	w.closes++
+	return w.closeErr
+}
+
+func TestWriterSinkClose(t *testing.T) {
+	w := &closingBuffer{}
+	sink := WriterSink{Writer: w, NoTimestamp: true}
+	sink.EmitEvent("myjob", "myevent", nil)
+	assert.Equal(t, "", w.String())
+
+	assert.NoError(t, sink.Close())
+	assert.Equal(t, "job:myjob event:myevent\n", w.String())
+	assert.Equal(t, 1, w.flushes)
+	assert.Equal(t, 1, w.closes)
+
+	// Closing again does nothing, and later emits are dropped.
+	assert.NoError(t, sink.Close())
+	sink.EmitEvent("myjob", "late", nil)
+	assert.Equal(t, 1, w.closes)
+	assert.Nil(t, w.pending)
+
+	// Writers that don't close are fine too.
+	var b bytes.Buffer
+	assert.NoError(t, (&WriterSink{Writer: &b}).Close())
+}
+
+func TestWriterSinkCloseError(t *testing.T) {
+	w := &closingBuffer{closeErr: testErr}
+	sink := WriterSink{Writer: w, Name: "file"}
+	err := sink.Close()
+	assert.EqualError(t, err, "file: my test error")
+	assert.True(t, errors.Is(err, testErr))
+}
+
+func TestWriterSinkCollapseTimestamps(t *testing.T) {
+	var b bytes.Buffer
+	clock := time.Date(2016, 1, 2, 15, 4, 5, 0, time.UTC)
+	sink := WriterSink{Writer: &b, CollapseTimestamps: true, Clock: func() time.Time { return clock }}
+
+	sink.EmitEvent("myjob", "one", nil)
+	sink.EmitEvent("myjob", "two", nil)
+	sink.EmitTiming("myjob", "three", 1204, nil)
+	clock = clock.Add(time.Second)
+	sink.EmitEvent("myjob", "four", nil)
+	sink.EmitEvent("myjob", "five", nil)
+	assert.Equal(t, "[2016-01-02T15:04:05Z]: job:myjob event:one\n"+
+		"[ \"]: job:myjob event:two\n"+
+		"[ \"]: job:myjob event:three time:1204 ns\n"+
+		"[2016-01-02T15:04:06Z]: job:myjob event:four\n"+
+		"[ \"]: job:myjob event:five\n", b.String())
+
+	// It's off by default, and doesn't apply to Logfmt.
+	b.Reset()
+	sink = WriterSink{Writer: &b, TimeFormat: "2006", Clock: func() time.Time { return clock }}
+	sink.EmitEvent("myjob", "one", nil)
+	sink.EmitEvent("myjob", "two", nil)
+	sink.CollapseTimestamps = true
+	sink.Format = Logfmt
+	sink.EmitEvent("myjob", "three", nil)
+	sink.EmitEvent("myjob", "four", nil)
+	assert.Equal(t, "[2016]: job:myjob event:one\n[2016]: job:myjob event:two\n"+
+		"ts=2016 job=myjob event=three\nts=2016 job=myjob event=four\n", b.String())
+}
+
+func TestWriterSinkVersion(t *testing.T) {
+	var b bytes.Buffer
+	sink := WriterSink{Writer: &b, NoTimestamp: true, Version: "1.4.2"}
+
+	sink.EmitEvent("myjob", "myevent", nil)
+	assert.Equal(t, "job:myjob event:myevent kvs:[version:1.4.2]\n", b.String())
+
+	b.Reset()
+	sink.StaticKvs = map[string]string{"zone": "us-east"}
+	sink.EmitEvent("myjob", "myevent", map[string]string{"wat": "ok"})
+	assert.Equal(t, "job:myjob event:myevent kvs:[version:1.4.2 wat:ok zone:us-east]\n", b.String())
+
+	b.Reset()
+	sink.EmitEvent("myjob", "myevent", map[string]string{"version": "override"})
+	assert.Equal(t, "job:myjob event:myevent kvs:[version:override zone:us-east]\n", b.String())
+	assert.Equal(t, map[string]string{"zone": "us-east"}, sink.StaticKvs)
+}
+
+func TestWriterSinkWithHostnameAndPID(t *testing.T) {
+	var b bytes.Buffer
+	sink := (&WriterSink{Writer: &b}).WithHostname().WithPID()
+	sink.EmitEvent("myjob", "myevent", nil)
+
+	host, _ := os.Hostname()
+	result := kvsEventRegexp.FindStringSubmatch(b.String())
+	assert.Equal(t, 4, len(result))
+	assert.Equal(t, fmt.Sprintf("hostname:%s pid:%d", host, os.Getpid()), result[3])
+}
+
+func TestWriterSinkColor(t *testing.T) {
+	var b bytes.Buffer
+	sink := WriterSink{Writer: &b, Color: true}
+
+	sink.EmitEventErr("myjob", "myevent", testErr, nil)
+	result := basicEventErrRegexp.FindStringSubmatch(b.String())
+	assert.Equal(t, 4, len(result))
+	assert.Equal(t, "\x1b[31mmy test error\x1b[0m", result[3])
+
+	b.Reset()
+	sink.EmitComplete("myjob", Success, 1204000, nil)
+	result = basicCompletionRegexp.FindStringSubmatch(b.String())
+	assert.Equal(t, 4, len(result))
+	assert.Equal(t, "\x1b[32msuccess\x1b[0m", result[2])
+
+	b.Reset()
+	sink.EmitComplete("myjob", Panic, 1204000, nil)
+	result = basicCompletionRegexp.FindStringSubmatch(b.String())
+	assert.Equal(t, "\x1b[31mpanic\x1b[0m", result[2])
+
+	// Without Color there are never escape codes:
+	sink.Color = false
+	for kind := range completionStatusToString {
+		b.Reset()
+		sink.EmitComplete("myjob", kind, 1204000, nil)
+		sink.EmitEventErr("myjob", "myevent", testErr, nil)
+		assert.NotContains(t, b.String(), "\x1b[")
+	}
+}
+
+func TestIsTerminal(t *testing.T) {
+	var b bytes.Buffer
+	assert.False(t, IsTerminal(&b))
+
+	f, err := ioutil.TempFile("", "health")
+	assert.NoError(t, err)
+	defer os.Remove(f.Name())
+	defer f.Close()
+	assert.False(t, IsTerminal(f))
+}
+
+func TestWriterSinkFormatDuration(t *testing.T) {
+	var b bytes.Buffer
+	sink := WriterSink{Writer: &b, DurationFormatter: FormatDuration}
+
+	sink.EmitTiming("myjob", "myevent", int64(2*time.Minute+3500*time.Millisecond), nil)
+	result := basicTimingRegexp.FindStringSubmatch(b.String())
+	assert.Equal(t, 4, len(result))
+	assert.Equal(t, "2m3.5s", result[3])
+
+	b.Reset()
+	sink.EmitComplete("myjob", Success, 1204000, nil)
+	result = basicCompletionRegexp.FindStringSubmatch(b.String())
+	assert.Equal(t, 4, len(result))
+	assert.Equal(t, "1.204ms", result[3])
+}
+
+func TestWriterSinkDurationFormatter(t *testing.T) {
+	var b bytes.Buffer
+	sink := WriterSink{Writer: &b, DurationFormatter: func(nanos int64) string {
+		return fmt.Sprintf("%.1f ms", float64(nanos)/float64(time.Millisecond))
+	}}
+
+	sink.EmitTiming("myjob", "myevent", 1504000, nil)
+	result := basicTimingRegexp.FindStringSubmatch(b.String())
+	assert.Equal(t, 4, len(result))
+	assert.Equal(t, "1.5 ms", result[3])
+
+	b.Reset()
+	sink.EmitComplete("myjob", Success, 34567890, nil)
+	result = basicCompletionRegexp.FindStringSubmatch(b.String())
+	assert.Equal(t, 4, len(result))
+	assert.Equal(t, "34.6 ms", result[3])
+}
+
+func TestWriterSinkMaxErrLen(t *testing.T) {
+	var b bytes.Buffer
+	sink := WriterSink{Writer: &b, NoTimestamp: true, MaxErrLen: 40}
+
+	var err error = errors.New("connection refused\n\tat dial tcp 10.0.0.1:5432\n  \n")
+	for i := 0; i < 5; i++ {
+		err = fmt.Errorf("attempt %d failed:\n%w", i, err)
+	}
+	sink.EmitEventErr("myjob", "myevent", err, nil)
+	assert.Equal(t, "job:myjob event:myevent err:attempt 4 failed: attempt 3 failed: atte…(134 bytes)\n", b.String())
+
+	// Without a limit, newlines are still collapsed.
+	b.Reset()
+	sink.MaxErrLen = 0
+	sink.EmitEventErr("myjob", "myevent", errors.New("first line\r\nsecond line"), nil)
+	assert.Equal(t, "job:myjob event:myevent err:first line second line\n", b.String())
+}
+
+func TestWriterSinkNanosKv(t *testing.T) {
+	var b bytes.Buffer
+	sink := WriterSink{Writer: &b, NoTimestamp: true, NanosKv: true}
+
+	sink.EmitTiming("myjob", "myevent", 34567890, map[string]string{"wat": "ok"})
+	assert.Equal(t, "job:myjob event:myevent time:34 ms kvs:[nanos:34567890 wat:ok]\n", b.String())
+
+	b.Reset()
+	sink.EmitComplete("myjob", Success, 1204, map[string]string{"nanos": "caller's"})
+	assert.Equal(t, "job:myjob status:success time:1204 ns kvs:[nanos:1204]\n", b.String())
+
+	// Other kinds don't get it.
+	b.Reset()
+	sink.EmitEvent("myjob", "myevent", nil)
+	assert.Equal(t, "job:myjob event:myevent\n", b.String())
+}
+
+func TestWriterSinkTimingUnit(t *testing.T) {
+	cases := []struct {
+		unit     TimingUnit
+		nanos    int64
+		expected string
+	}{
+		{Auto, 34567890, "34 ms"},
+		{Auto, 1204, "1204 ns"},
+		{Nanos, 34567890, "34567890 ns"},
+		{Micros, 34567890, "34567.89 μs"},
+		{Micros, 1204, "1.204 μs"},
+		{Millis, 34567890, "34.56789 ms"},
+		{Millis, 2000000, "2 ms"},
+		{Millis, 1204, "0.001204 ms"},
+		{Seconds, 34567890, "0.03456789 s"},
+		{Seconds, 123500000000, "123.5 s"},
+	}
+	for _, c := range cases {
+		var b bytes.Buffer
+		sink := WriterSink{Writer: &b, TimingUnit: c.unit}
+
+		sink.EmitTiming("myjob", "myevent", c.nanos, nil)
+		result := basicTimingRegexp.FindStringSubmatch(b.String())
+		assert.Equal(t, 4, len(result))
+		assert.Equal(t, c.expected, result[3])
+
+		b.Reset()
+		sink.EmitComplete("myjob", Success, c.nanos, nil)
+		result = basicCompletionRegexp.FindStringSubmatch(b.String())
+		assert.Equal(t, 4, len(result))
+		assert.Equal(t, c.expected, result[3])
+	}
+
+	// DurationFormatter takes precedence.
+	var b bytes.Buffer
+	sink := WriterSink{Writer: &b, NoTimestamp: true, TimingUnit: Seconds, DurationFormatter: FormatDuration}
+	sink.EmitTiming("myjob", "myevent", 1204000, nil)
+	assert.Equal(t, "job:myjob event:myevent time:1.204ms\n", b.String())
+}
+
+func TestWriterSinkDurationPrecision(t *testing.T) {
+	cases := []struct {
+		unit     TimingUnit
+		prec     int
+		nanos    int64
+		expected string
+	}{
+		{Auto, 3, 1204000, "1.204 ms"},
+		{Auto, 1, 1204000, "1.2 ms"},
+		{Auto, 3, 34567890, "34.568 ms"},
+		{Auto, 3, 3000000000, "3000.000 ms"},
+		{Auto, 2, 1204, "1.20 μs"},
+		{Auto, 2, 999999, "1000.00 μs"},
+		{Auto, 2, 32, "32 ns"},
+		{Auto, 0, 1204000, "1204 μs"},
+		{Millis, 2, 34567890, "34.57 ms"},
+		{Seconds, 3, 34567890, "0.035 s"},
+		{Nanos, 3, 1204, "1204 ns"},
+	}
+	for _, c := range cases {
+		var b bytes.Buffer
+		sink := WriterSink{Writer: &b, TimingUnit: c.unit, DurationPrecision: c.prec}
+
+		sink.EmitTiming("myjob", "myevent", c.nanos, nil)
+		result := basicTimingRegexp.FindStringSubmatch(b.String())
+		assert.Equal(t, 4, len(result))
+		assert.Equal(t, c.expected, result[3])
+
+		b.Reset()
+		sink.EmitComplete("myjob", Success, c.nanos, nil)
+		result = basicCompletionRegexp.FindStringSubmatch(b.String())
+		assert.Equal(t, 4, len(result))
+		assert.Equal(t, c.expected, result[3])
+	}
+}
+
+func TestWriterSinkTimeFormat(t *testing.T) {
+	var b bytes.Buffer
+	sink := WriterSink{Writer: &b, TimeFormat: "2006-01-02", Location: time.FixedZone("test", 5*60*60)}
+	sink.EmitEvent("myjob", "myevent", nil)
+
+	expected := time.Now().In(sink.Location).Format("2006-01-02")
+	assert.Equal(t, "["+expected+"]: job:myjob event:myevent\n", b.String())
+}
+
+func TestWriterSinkClock(t *testing.T) {
+	var b bytes.Buffer
+	sink := WriterSink{Writer: &b, Clock: func() time.Time {
+		return time.Date(2016, 1, 2, 15, 4, 5, 0, time.FixedZone("test", -7*60*60))
+	}}
+
+	sink.EmitEvent("myjob", "myevent", nil)
+	sink.EmitComplete("myjob", Success, 34567890, nil)
+
+	assert.Equal(t, "[2016-01-02T22:04:05Z]: job:myjob event:myevent\n[2016-01-02T22:04:05Z]: job:myjob status:success time:34 ms\n", b.String())
+}
+
+func TestWriterSinkNoTimestamp(t *testing.T) {
+	var b bytes.Buffer
+	sink := WriterSink{Writer: &b, NoTimestamp: true}
+
+	sink.EmitEvent("myjob", "myevent", map[string]string{"foo": "bar"})
+	sink.EmitEventErr("myjob", "myevent", testErr, nil)
+	sink.EmitTiming("myjob", "myevent", 34567890, nil)
+	sink.EmitComplete("myjob", Success, 34567890, nil)
+	sink.EmitGauge("myjob", "myevent", 3.14, nil)
+
+	assert.Equal(t, "job:myjob event:myevent kvs:[foo:bar]\n"+
+		"job:myjob event:myevent err:my test error\n"+
+		"job:myjob event:myevent time:34 ms\n"+
+		"job:myjob status:success time:34 ms\n"+
+		"job:myjob event:myevent gauge:3.14\n", b.String())
+
+	b.Reset()
+	sink.Format = Logfmt
+	sink.EmitEvent("myjob", "myevent", nil)
+	assert.Equal(t, "job=myjob event=myevent\n", b.String())
+}
+
+func TestWriterSinkPrefix(t *testing.T) {
+	var b bytes.Buffer
+	clock := func() time.Time { return time.Date(2016, 1, 2, 15, 4, 5, 0, time.UTC) }
+	sink := WriterSink{Writer: &b, Prefix: "[auth]", Clock: clock}
+
+	sink.EmitEvent("myjob", "myevent", nil)
+	sink.EmitComplete("myjob", Success, 34567890, nil)
+	assert.Equal(t, "[2016-01-02T15:04:05Z]: [auth] job:myjob event:myevent\n[2016-01-02T15:04:05Z]: [auth] job:myjob status:success time:34 ms\n", b.String())
+
+	b.Reset()
+	sink.NoTimestamp = true
+	sink.EmitEventErr("myjob", "myevent", testErr, nil)
+	assert.Equal(t, "[auth] job:myjob event:myevent err:my test error\n", b.String())
+
+	b.Reset()
+	sink.Format = Logfmt
+	sink.EmitGauge("myjob", "myevent", 3.14, nil)
+	assert.Equal(t, "[auth] job=myjob event=myevent gauge=3.14\n", b.String())
+}
+
+func TestWriterSinkTraceKey(t *testing.T) {
+	var b bytes.Buffer
+	clock := func() time.Time { return time.Date(2016, 1, 2, 15, 4, 5, 0, time.UTC) }
+	sink := WriterSink{Writer: &b, TraceKey: "trace_id", Clock: clock}
+
+	kvs := map[string]string{"trace_id": "abc123", "wat": "ok"}
+	sink.EmitEvent("myjob", "myevent", kvs)
+	sink.EmitComplete("myjob", Success, 34567890, map[string]string{"trace_id": "abc123"})
+	assert.Equal(t, "[2016-01-02T15:04:05Z]: trace:abc123 job:myjob event:myevent kvs:[wat:ok]\n[2016-01-02T15:04:05Z]: trace:abc123 job:myjob status:success time:34 ms\n", b.String())
+	assert.Equal(t, "abc123", kvs["trace_id"])
+
+	// Without the key, lines are as usual.
+	b.Reset()
+	sink.EmitEvent("myjob", "myevent", map[string]string{"wat": "ok"})
+	assert.Equal(t, "[2016-01-02T15:04:05Z]: job:myjob event:myevent kvs:[wat:ok]\n", b.String())
+
+	b.Reset()
+	sink.NoTimestamp = true
+	sink.Prefix = "[auth]"
+	sink.EmitEventErr("myjob", "myevent", testErr, map[string]string{"trace_id": "a b"})
+	assert.Equal(t, "trace:\"a b\" [auth] job:myjob event:myevent err:my test error\n", b.String())
+
+	b.Reset()
+	sink.Format = Logfmt
+	sink.EmitGauge("myjob", "myevent", 3.14, map[string]string{"trace_id": "abc123", "wat": "ok"})
+	assert.Equal(t, "trace=abc123 [auth] job=myjob event=myevent gauge=3.14 wat=ok\n", b.String())
+}
+
+func TestWriterSinkCaller(t *testing.T) {
+	var b bytes.Buffer
+	sink := &WriterSink{Writer: &b, Caller: true}
+
+	_, _, line, _ := runtime.Caller(0)
+	sink.EmitEvent("myjob", "myevent", map[string]string{"foo": "bar"})
+	assert.Contains(t, b.String(), fmt.Sprintf(" caller:writer_sink_test.go:%d kvs:[foo:bar]\n", line+1))
+
+	// Through a Job and a MultiSink, it's still the call site here.
+	b.Reset()
+	job := NewStream().AddSink(NewMultiSink(sink)).NewJob("myjob")
+	_, _, line, _ = runtime.Caller(0)
+	job.Timing("myevent", 34567890)
+	assert.Contains(t, b.String(), fmt.Sprintf(" caller:writer_sink_test.go:%d\n", line+1))
+
+	b.Reset()
+	sink.Caller = false
+	sink.EmitEvent("myjob", "myevent", nil)
+	assert.NotContains(t, b.String(), "caller:")
+}
+
+func TestWriterSinkLineEnding(t *testing.T) {
+	var b bytes.Buffer
+	sink := WriterSink{Writer: &b, LineEnding: "\r\n", NoTimestamp: true}
+
+	sink.EmitEvent("myjob", "myevent", nil)
+	sink.EmitEventErr("myjob", "myevent", testErr, nil)
+	sink.EmitTiming("myjob", "myevent", 34567890, nil)
+	sink.EmitComplete("myjob", Success, 34567890, nil)
+	sink.EmitGauge("myjob", "myevent", 3.14, map[string]string{"foo": "bar"})
+
+	assert.Equal(t, "job:myjob event:myevent\r\n"+
+		"job:myjob event:myevent err:my test error\r\n"+
+		"job:myjob event:myevent time:34 ms\r\n"+
+		"job:myjob status:success time:34 ms\r\n"+
+		"job:myjob event:myevent gauge:3.14 kvs:[foo:bar]\r\n", b.String())
+}
+
+func TestWriterSinkLogfmt(t *testing.T) {
+	var b bytes.Buffer
+	sink := WriterSink{Writer: &b, Format: Logfmt, TimeFormat: "2006"}
+	year := time.Now().UTC().Format("2006")
+
+	sink.EmitEvent("myjob", "myevent", map[string]string{"wat": "ok", "another": "a thing", "eq": "a=b", "my key": ""})
+	assert.Equal(t, "ts="+year+` job=myjob event=myevent another="a thing" eq="a=b" my_key="" wat=ok`+"\n", b.String())
+
+	b.Reset()
+	sink.EmitEventErr("myjob", "myevent", testErr, nil)
+	assert.Equal(t, "ts="+year+` job=myjob event=myevent err="my test error"`+"\n", b.String())
+
+	b.Reset()
+	sink.EmitTiming("myjob", "myevent", 34567890, nil)
+	assert.Equal(t, "ts="+year+` job=myjob event=myevent time="34 ms"`+"\n", b.String())
+
+	b.Reset()
+	sink.EmitComplete("myjob", Success, 34567890, map[string]string{"q": `say "hi"`})
+	assert.Equal(t, "ts="+year+` job=myjob status=success time="34 ms" q="say \"hi\""`+"\n", b.String())
+}
+
+// byteAtATimeWriter writes one byte at a time, yielding in between, so that unsynchronized concurrent writes interleave.
+type byteAtATimeWriter struct {
+	buf []byte
+}
+
+func (w *byteAtATimeWriter) Write(p []byte) (int, error) {
+	for _, c := range p {
+		w.buf = append(w.buf, c)
+		runtime.Gosched()
+	}
+	return len(p), nil
+}
+
+func TestWriterSinkConcurrentWrites(t *testing.T) {
+	var w byteAtATimeWriter
+	sink := WriterSink{Writer: &w}
+
+	const goroutines = 50
+	const emitsPerGoroutine = 20
+	var wg sync.WaitGroup
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < emitsPerGoroutine; j++ {
+				sink.EmitEvent("myjob", "myevent", map[string]string{"wat": "ok", "another": "thing"})
+			}
+		}()
+	}
+	wg.Wait()
+
+	lines := strings.Split(strings.TrimSuffix(string(w.buf), "\n"), "\n")
+	assert.Equal(t, goroutines*emitsPerGoroutine, len(lines))
+	lineRegexp := regexp.MustCompile("^\\[[^\\]]+\\]: job:myjob event:myevent kvs:\\[another:thing wat:ok\\]$")
+	for _, line := range lines {
+		if !lineRegexp.MatchString(line) {
+			t.Fatalf("garbled line: %q", line)
+		}
+	}
+}
+
+func TestWriterSinkOnFatal(t *testing.T) {
+	var b bytes.Buffer
+	var lines []string
+	sink := WriterSink{Writer: &b, NoTimestamp: true}
+	sink.OnFatal = func() { lines = append(lines, b.String()) }
+
+	sink.EmitEvent("myjob", "myevent", map[string]string{"level": "error"})
+	sink.EmitEventErr("myjob", "myevent", testErr, nil)
+	assert.Equal(t, 0, len(lines))
+
+	sink.EmitEvent("myjob", "dying", map[string]string{"level": "fatal"})
+	assert.Equal(t, 1, len(lines))
+	assert.True(t, strings.HasSuffix(lines[0], "job:myjob event:dying kvs:[level:fatal]\n"), "OnFatal was called before the line was written")
+
+	sink.EmitComplete("myjob", Panic, 1, nil)
+	sink.EmitComplete("myjob", Panic, 1, map[string]string{"level": "error"})
+	assert.Equal(t, 2, len(lines))
+}
+
+func TestWriterSinkExitOnFatal(t *testing.T) {
+	var codes []int
+	osExit = func(code int) { codes = append(codes, code) }
+	defer func() { osExit = os.Exit }()
+
+	var b bytes.Buffer
+	w := bufio.NewWriter(&b)
+	sink := WriterSink{Writer: w, NoTimestamp: true, ExitOnFatal: true}
+
+	sink.EmitEvent("myjob", "myevent", nil)
+	assert.Equal(t, 0, len(codes))
+
+	sink.EmitEvent("myjob", "dying", map[string]string{"level": "FATAL"})
+	assert.Equal(t, []int{1}, codes)
+	assert.Equal(t, "job:myjob event:myevent\njob:myjob event:dying kvs:[level:FATAL]\n", b.String())
+}